@@ -18,6 +18,8 @@ package server
 
 import (
 	"bufio"
+	"errors"
+	"io"
 	"net"
 	"os"
 	"strconv"
@@ -25,6 +27,9 @@ import (
 	"time"
 )
 
+// errConnectionClosed is reported when the server cleanly closes the connection.
+var errConnectionClosed = errors.New("connection closed by server")
+
 //
 // lineReader implements standard input line reader.
 type lineReader struct {
@@ -152,6 +157,9 @@ func (this *cli) connect() bool {
 		this.outputError(err)
 		return false
 	}
+	if err = setNoDelay(conn, config.NET_NO_DELAY); err != nil {
+		logWarn("failed to set no delay on server connection", err.Error())
+	}
 	this.conn = conn
 	return true
 }
@@ -189,6 +197,9 @@ func (this *cli) readMessages() {
 LOOP:
 	for {
 		_, bytes, err := reader.readMessage()
+		if err == io.EOF {
+			err = errConnectionClosed
+		}
 		if err != nil {
 			this.outputError(err)
 			break LOOP
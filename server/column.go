@@ -16,6 +16,13 @@
 
 package server
 
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 type columnType int8
 
 // column types
@@ -26,14 +33,72 @@ const (
 	columnTypeTag                      // tag column
 )
 
+// column data types
+type columnDataType int8
+
+const (
+	columnDataTypeString columnDataType = iota // any value, default
+	columnDataTypeInt                          // integer value
+	columnDataTypeFloat                        // floating point value
+	columnDataTypeBool                         // true or false
+	columnDataTypeBlob                         // base64 encoded bytes
+)
+
+// String converts columnDataType value to its create table keyword.
+func (this columnDataType) String() string {
+	switch this {
+	case columnDataTypeInt:
+		return "int"
+	case columnDataTypeFloat:
+		return "float"
+	case columnDataTypeBool:
+		return "bool"
+	case columnDataTypeBlob:
+		return "blob"
+	}
+	return "string"
+}
+
+// Returns data type by its create table keyword.
+func columnDataTypeFromString(name string) (columnDataType, bool) {
+	switch name {
+	case "string":
+		return columnDataTypeString, true
+	case "int":
+		return columnDataTypeInt, true
+	case "float":
+		return columnDataTypeFloat, true
+	case "bool":
+		return columnDataTypeBool, true
+	case "blob":
+		return columnDataTypeBlob, true
+	}
+	return columnDataTypeString, false
+}
+
 // column
 type column struct {
-	name    string
-	ordinal int
-	typ     columnType
+	name     string
+	ordinal  int
+	typ      columnType
+	dataType columnDataType
+	// data type was declared by create table
+	declared bool
 	//
 	tagmap   tagMap
 	tagIndex int
+	// values are split into multiple tags when true
+	multiValue bool
+	// sorted index for range predicates, nil if not defined
+	ranges *rangeIndex
+	// operands of computed select column, nil for table columns
+	concat []concatOperand
+}
+
+// concatOperand is either a column or a constant string of computed select column.
+type concatOperand struct {
+	col *column // nil for constant and for column that does not exist
+	val string
 }
 
 // column factory
@@ -50,6 +115,23 @@ func newColumn(name string, ordinal int) *column {
 	return col
 }
 
+// Returns column value of the record, computed column joins values of its operands
+// with empty values in place of missing ones.
+func (this *column) valueOf(rec *record) string {
+	if this.concat == nil {
+		return rec.getValue(this.ordinal)
+	}
+	var buf []byte
+	for _, operand := range this.concat {
+		if operand.col != nil {
+			buf = append(buf, rec.getValue(operand.col.ordinal)...)
+		} else {
+			buf = append(buf, operand.val...)
+		}
+	}
+	return string(buf)
+}
+
 func (this *column) isKey() bool {
 	return this.typ == columnTypeKey
 }
@@ -62,6 +144,26 @@ func (this *column) isIndexed() bool {
 	return this.typ != columnTypeNormal
 }
 
+// Determines if value is compatible with column data type.
+// Empty value means no value and is always valid.
+func (this *column) isValidValue(val string) bool {
+	if val == "" {
+		return true
+	}
+	var err error
+	switch this.dataType {
+	case columnDataTypeInt:
+		_, err = strconv.ParseInt(val, 10, 64)
+	case columnDataTypeFloat:
+		_, err = strconv.ParseFloat(val, 64)
+	case columnDataTypeBool:
+		_, err = strconv.ParseBool(val)
+	case columnDataTypeBlob:
+		_, err = base64.StdEncoding.DecodeString(val)
+	}
+	return err == nil
+}
+
 // Makes column to be tags container.
 func (this *column) makeTags(tagIndex int) {
 	this.typ = columnTypeTag
@@ -69,6 +171,31 @@ func (this *column) makeTags(tagIndex int) {
 	this.tagIndex = tagIndex
 }
 
+// Returns unique tag values of multi value tag column.
+// Values are separated by comma or white space, empty value is a single tag.
+func (this *column) tagValues(val string) []string {
+	fields := strings.FieldsFunc(val, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		return []string{""}
+	}
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		unique := true
+		for _, prev := range values {
+			if prev == field {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
 // Determines if value is present for a given key
 func (this *column) keyContainsValue(key string) bool {
 	return this.tagmap.containsTag(key)
@@ -37,6 +37,7 @@ type configuration struct {
 	CHAN_RESPONSE_SENDER_BUFFER_SIZE          int
 	CHAN_TABLE_REQUESTS_BUFFER_SIZE           int
 	CHAN_DATA_SERVICE_REQUESTS_BUFFER_SIZE    int
+	CHAN_CONNECTION_REQUESTS_BUFFER_SIZE      int
 	PARSER_SQL_INSERT_REQUEST_COLUMN_CAPACITY int
 	PARSER_SQL_UPDATE_REQUEST_COLUMN_CAPACITY int
 	PARSER_SQL_SELECT_REQUEST_COLUMN_CAPACITY int
@@ -48,6 +49,23 @@ type configuration struct {
 	WAIT_MILLISECOND_CLI_SHUTDOWN             time.Duration
 	DATA_BATCH_SIZE                           int
 	NET_READWRITE_BUFFER_SIZE                 int
+	NET_COMPRESSION_THRESHOLD                 int
+	NET_MAX_MESSAGE_SIZE                      int
+	WAIT_MILLISECOND_IDLE_CONNECTION          time.Duration
+	SUBSCRIPTION_QUEUE_SIZE                   int
+	SUBSCRIPTION_OVERFLOW_POLICY              string
+	WAIT_MILLISECOND_IDEMPOTENCY_WINDOW       time.Duration
+	SELECT_MAX_ROWS                           int
+	WHERE_STRICT_COLUMNS                      bool
+	SNAPSHOT_DIR                              string
+	TABLE_MAX_COLUMNS                         int
+	SERVER_MAX_TABLES                         int
+	NET_RATE_LIMIT                            int
+	WAIT_MILLISECOND_TTL_SWEEP                time.Duration
+	WAIT_MILLISECOND_DRAIN                    time.Duration
+	WAIT_MILLISECOND_QUERY_TIMEOUT            time.Duration
+	NET_NO_DELAY                              bool
+	TABLE_EVENT_BUFFER_SIZE                   int
 
 	// command
 	COMMAND string
@@ -76,6 +94,7 @@ func defaultConfig() configuration {
 		CHAN_RESPONSE_SENDER_BUFFER_SIZE:          10000,
 		CHAN_TABLE_REQUESTS_BUFFER_SIZE:           1000,
 		CHAN_DATA_SERVICE_REQUESTS_BUFFER_SIZE:    1000,
+		CHAN_CONNECTION_REQUESTS_BUFFER_SIZE:      1000,
 		PARSER_SQL_INSERT_REQUEST_COLUMN_CAPACITY: 10,
 		PARSER_SQL_UPDATE_REQUEST_COLUMN_CAPACITY: 10,
 		PARSER_SQL_SELECT_REQUEST_COLUMN_CAPACITY: 10,
@@ -87,6 +106,23 @@ func defaultConfig() configuration {
 		WAIT_MILLISECOND_CLI_SHUTDOWN:             1000,
 		DATA_BATCH_SIZE:                           100,
 		NET_READWRITE_BUFFER_SIZE:                 2048,
+		NET_COMPRESSION_THRESHOLD:                 1024,
+		NET_MAX_MESSAGE_SIZE:                      64 * 1024 * 1024,
+		WAIT_MILLISECOND_IDLE_CONNECTION:          0,
+		SUBSCRIPTION_QUEUE_SIZE:                   0,
+		SUBSCRIPTION_OVERFLOW_POLICY:              overflowPolicyDrop,
+		WAIT_MILLISECOND_IDEMPOTENCY_WINDOW:       60000,
+		SELECT_MAX_ROWS:                           0,
+		WHERE_STRICT_COLUMNS:                      true,
+		SNAPSHOT_DIR:                              "",
+		TABLE_MAX_COLUMNS:                         0,
+		SERVER_MAX_TABLES:                         0,
+		NET_RATE_LIMIT:                            0,
+		WAIT_MILLISECOND_TTL_SWEEP:                1000,
+		WAIT_MILLISECOND_DRAIN:                    0,
+		WAIT_MILLISECOND_QUERY_TIMEOUT:            0,
+		NET_NO_DELAY:                              true,
+		TABLE_EVENT_BUFFER_SIZE:                   0,
 
 		// command
 		COMMAND: "start",
@@ -99,6 +135,12 @@ func defaultConfig() configuration {
 
 var config = defaultConfig()
 
+// subscription overflow policies
+const (
+	overflowPolicyDrop     = "drop"     // subscription is dropped
+	overflowPolicyCoalesce = "coalesce" // events are skipped until subscriber catches up
+)
+
 var validCommands = map[string] string {
 	"start": "",
 	"cli":   "",
@@ -150,6 +192,34 @@ func (this *configuration) processCommandLine(args []string) bool {
 	this.flags.StringVar(&logLevel, "loglevel", "info,warn,error", `logging level "debug,info,warn,error"`)
 	this.flags.StringVar(&this.IP, "ip", config.IP, "ip address")
 	this.flags.UintVar(&this.PORT, "port", config.PORT, "port number")
+	var idleTimeout uint
+	this.flags.UintVar(&idleTimeout, "idletimeout", uint(config.WAIT_MILLISECOND_IDLE_CONNECTION/1000), "seconds before idle client connection is probed and then dropped, 0 disables")
+	var maxMessageSize uint
+	this.flags.UintVar(&maxMessageSize, "maxmessagesize", uint(config.NET_MAX_MESSAGE_SIZE), "maximum size of a network message in bytes, 0 disables the limit")
+	var subscriptionQueueSize uint
+	this.flags.UintVar(&subscriptionQueueSize, "subscriptionqueuesize", uint(config.SUBSCRIPTION_QUEUE_SIZE), "maximum number of events waiting to be sent per subscription, 0 disables the limit")
+	var maxRows uint
+	this.flags.UintVar(&maxRows, "maxrows", uint(config.SELECT_MAX_ROWS), "maximum number of rows returned by select, 0 disables the limit")
+	this.flags.BoolVar(&this.WHERE_STRICT_COLUMNS, "strictcolumns", config.WHERE_STRICT_COLUMNS, "reject where filters on unknown columns, when false such columns are treated as empty")
+	this.flags.BoolVar(&this.NET_NO_DELAY, "nodelay", config.NET_NO_DELAY, "send small messages immediately for lower latency, when false they are coalesced by Nagle's algorithm for higher throughput")
+	this.flags.StringVar(&this.SNAPSHOT_DIR, "snapshotdir", config.SNAPSHOT_DIR, "directory of table snapshot files written by save and read by load, save and load are disabled when empty")
+	var maxColumns uint
+	this.flags.UintVar(&maxColumns, "maxcolumns", uint(config.TABLE_MAX_COLUMNS), "maximum number of columns per table including id, 0 disables the limit")
+	var maxTables uint
+	this.flags.UintVar(&maxTables, "maxtables", uint(config.SERVER_MAX_TABLES), "maximum number of tables, 0 disables the limit")
+	var rateLimit uint
+	this.flags.UintVar(&rateLimit, "ratelimit", uint(config.NET_RATE_LIMIT), "maximum number of commands per second per client connection, 0 disables the limit")
+	var ttlSweep uint
+	this.flags.UintVar(&ttlSweep, "ttlsweep", uint(config.WAIT_MILLISECOND_TTL_SWEEP), "milliseconds between sweeps of records inserted with ttl, 0 disables the sweeper and expired records are removed on the next request")
+	var drainPeriod uint
+	this.flags.UintVar(&drainPeriod, "drainperiod", uint(config.WAIT_MILLISECOND_DRAIN/1000), "seconds the server keeps serving reads after stop was requested while rejecting new connections and mutations, 0 stops immediately")
+	var queryTimeout uint
+	this.flags.UintVar(&queryTimeout, "querytimeout", uint(config.WAIT_MILLISECOND_QUERY_TIMEOUT), "milliseconds after which scan of records by where filter is aborted with query timeout error, 0 disables")
+	var eventBufferSize uint
+	this.flags.UintVar(&eventBufferSize, "eventbuffer", uint(config.TABLE_EVENT_BUFFER_SIZE), "number of most recent record changes kept per table for subscribers resuming with since, 0 disables")
+	var idempotencyWindow uint
+	this.flags.UintVar(&idempotencyWindow, "idempotencywindow", uint(config.WAIT_MILLISECOND_IDEMPOTENCY_WINDOW/1000), "seconds results of idempotent requests are remembered for retries, 0 disables")
+	this.flags.StringVar(&this.SUBSCRIPTION_OVERFLOW_POLICY, "overflowpolicy", config.SUBSCRIPTION_OVERFLOW_POLICY, `subscription overflow policy "drop" or "coalesce"`)
 
 	// set command
 	if len(args) > 0 {
@@ -174,6 +244,46 @@ func (this *configuration) processCommandLine(args []string) bool {
 		return false
 	}
 
+	// set idle timeout
+	this.WAIT_MILLISECOND_IDLE_CONNECTION = time.Duration(idleTimeout) * 1000
+
+	// set max rows
+	this.SELECT_MAX_ROWS = int(maxRows)
+
+	// set max columns and tables
+	this.TABLE_MAX_COLUMNS = int(maxColumns)
+	this.SERVER_MAX_TABLES = int(maxTables)
+
+	// set rate limit
+	this.NET_RATE_LIMIT = int(rateLimit)
+
+	// set ttl sweep interval
+	this.WAIT_MILLISECOND_TTL_SWEEP = time.Duration(ttlSweep)
+
+	// set drain period
+	this.WAIT_MILLISECOND_DRAIN = time.Duration(drainPeriod) * 1000
+
+	// set query timeout
+	this.WAIT_MILLISECOND_QUERY_TIMEOUT = time.Duration(queryTimeout)
+
+	// set event buffer size
+	this.TABLE_EVENT_BUFFER_SIZE = int(eventBufferSize)
+
+	// set idempotency window
+	this.WAIT_MILLISECOND_IDEMPOTENCY_WINDOW = time.Duration(idempotencyWindow) * 1000
+
+	// set max message size
+	this.NET_MAX_MESSAGE_SIZE = int(maxMessageSize)
+
+	// set subscription queue size and overflow policy
+	this.SUBSCRIPTION_QUEUE_SIZE = int(subscriptionQueueSize)
+	switch this.SUBSCRIPTION_OVERFLOW_POLICY {
+	case overflowPolicyDrop, overflowPolicyCoalesce:
+	default:
+		fmt.Println("invalid --overflowpolicy \"" + this.SUBSCRIPTION_OVERFLOW_POLICY + "\"\n" + this.flags.Lookup("overflowpolicy").Usage)
+		return false
+	}
+
 	// set logLevel
 	if !this.setLogLevel(logLevel) {
 		fmt.Println("invalid --loglevel \"" + logLevel + "\"\n" + this.flags.Lookup("loglevel").Usage)
@@ -115,6 +115,132 @@ func TestConfigNetwork(t *testing.T) {
 	ASSERT_TRUE(t, int(c.PORT) == port, "port")
 }
 
+func TestConfigIdleTimeout(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_IDLE_CONNECTION == 0, "idle timeout disabled by default")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--idletimeout", "30"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_IDLE_CONNECTION == 30000, "idle timeout")
+}
+
+func TestConfigMaxMessageSize(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.NET_MAX_MESSAGE_SIZE == 64*1024*1024, "default max message size")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--maxmessagesize", "1024"}), "processCommandLine")
+	ASSERT_TRUE(t, c.NET_MAX_MESSAGE_SIZE == 1024, "max message size")
+}
+
+func TestConfigSubscriptionOverflow(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.SUBSCRIPTION_QUEUE_SIZE == 0, "default subscription queue size")
+	ASSERT_TRUE(t, c.SUBSCRIPTION_OVERFLOW_POLICY == overflowPolicyDrop, "default overflow policy")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--subscriptionqueuesize", "100", "--overflowpolicy", "coalesce"}), "processCommandLine")
+	ASSERT_TRUE(t, c.SUBSCRIPTION_QUEUE_SIZE == 100, "subscription queue size")
+	ASSERT_TRUE(t, c.SUBSCRIPTION_OVERFLOW_POLICY == overflowPolicyCoalesce, "overflow policy")
+	//
+	c = defaultConfig()
+	ASSERT_FALSE(t, c.processCommandLine([]string{"--overflowpolicy", "block"}), "invalid overflow policy")
+}
+
+func TestConfigMaxRows(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.SELECT_MAX_ROWS == 0, "default max rows")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--maxrows", "500"}), "processCommandLine")
+	ASSERT_TRUE(t, c.SELECT_MAX_ROWS == 500, "max rows")
+}
+
+func TestConfigStrictColumns(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WHERE_STRICT_COLUMNS, "default strict columns")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--strictcolumns=false"}), "processCommandLine")
+	ASSERT_FALSE(t, c.WHERE_STRICT_COLUMNS, "lenient columns")
+}
+
+func TestConfigMaxColumnsAndTables(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.TABLE_MAX_COLUMNS == 0 && c.SERVER_MAX_TABLES == 0, "default limits")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--maxcolumns", "20", "--maxtables", "5"}), "processCommandLine")
+	ASSERT_TRUE(t, c.TABLE_MAX_COLUMNS == 20, "max columns")
+	ASSERT_TRUE(t, c.SERVER_MAX_TABLES == 5, "max tables")
+}
+
+func TestConfigRateLimit(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.NET_RATE_LIMIT == 0, "default rate limit")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--ratelimit", "500"}), "processCommandLine")
+	ASSERT_TRUE(t, c.NET_RATE_LIMIT == 500, "rate limit")
+}
+
+func TestConfigTtlSweep(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_TTL_SWEEP == 1000, "default ttl sweep interval")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--ttlsweep", "250"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_TTL_SWEEP == 250, "ttl sweep interval")
+}
+
+func TestConfigDrainPeriod(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_DRAIN == 0, "drain disabled by default")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--drainperiod", "5"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_DRAIN == 5000, "drain period")
+}
+
+func TestConfigQueryTimeout(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_QUERY_TIMEOUT == 0, "query timeout disabled by default")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--querytimeout", "1500"}), "processCommandLine")
+	ASSERT_TRUE(t, c.WAIT_MILLISECOND_QUERY_TIMEOUT == 1500, "query timeout")
+}
+
+func TestConfigNoDelay(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.NET_NO_DELAY, "no delay by default")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--nodelay=false"}), "processCommandLine")
+	ASSERT_TRUE(t, !c.NET_NO_DELAY, "delay")
+}
+
+func TestConfigEventBuffer(t *testing.T) {
+	c := defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"start"}), "processCommandLine")
+	ASSERT_TRUE(t, c.TABLE_EVENT_BUFFER_SIZE == 0, "event buffer disabled by default")
+	//
+	c = defaultConfig()
+	ASSERT_TRUE(t, c.processCommandLine([]string{"--eventbuffer", "500"}), "processCommandLine")
+	ASSERT_TRUE(t, c.TABLE_EVENT_BUFFER_SIZE == 500, "event buffer size")
+}
+
 func TestConfigInvalid(t *testing.T) {
 	args := []string{"--option1"}
 	c := defaultConfig()
@@ -123,6 +123,20 @@ func (this *Controller) readInput() {
 	debug("controller done readInput")
 }
 
+// drain lets in-flight requests finish for the configured period before the server stops,
+// meanwhile new connections and mutations are rejected.
+func (this *Controller) drain() {
+	if config.WAIT_MILLISECOND_DRAIN == 0 {
+		this.quit.Quit(0)
+		return
+	}
+	info("draining")
+	this.network.drain()
+	time.AfterFunc(time.Millisecond*config.WAIT_MILLISECOND_DRAIN, func() {
+		this.quit.Quit(0)
+	})
+}
+
 // onCommandRequest processes request from a connected client, sending respond back to the client.
 func (this *Controller) onCommandRequest(item *requestItem) {
 	switch item.req.(type) {
@@ -136,7 +150,7 @@ func (this *Controller) onCommandRequest(item *requestItem) {
 		item.sender.send(res)
 	case *cmdStopRequest:
 		logInfo("client connection:", item.sender.connectionId, "requested to stop the server")
-		this.quit.Quit(0)
+		this.drain()
 	case *mysqlConnectRequest:
 		logInfo("client connection:", item.sender.connectionId, "requested mysql connect")
 		if item.req.isStreaming() {
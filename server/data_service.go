@@ -16,6 +16,14 @@
 
 package server
 
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
 // requestItem is a container for client request and sender used to send back responses
 type requestItem struct {
 	header *netHeader
@@ -37,6 +45,8 @@ type dataService struct {
 	requests chan *requestItem
 	quit     *Quitter
 	tables   map[string]*table
+	patterns []*sqlSubscribeRequest // subscriptions to table patterns
+	schema   *schemaSubscriptions
 }
 
 // newDataService returns new dataService.
@@ -45,6 +55,7 @@ func newDataService(quit *Quitter) *dataService {
 		requests: make(chan *requestItem, config.CHAN_DATA_SERVICE_REQUESTS_BUFFER_SIZE),
 		quit:     quit,
 		tables:   make(map[string]*table),
+		schema:   new(schemaSubscriptions),
 	}
 }
 
@@ -56,6 +67,16 @@ func (this *dataService) acceptRequest(item *requestItem) {
 	}
 }
 
+// isQuitting returns true when the server is shutting down and no longer accepts requests.
+func (this *dataService) isQuitting() bool {
+	select {
+	case <-this.quit.GetChan():
+		return true
+	default:
+		return false
+	}
+}
+
 // run is an event loop function that recieves sql requests from connected clients and forwards them for further processing.
 func (this *dataService) run() {
 	this.quit.Join()
@@ -67,7 +88,7 @@ func (this *dataService) run() {
 				debug("data service exited due to quit notification")
 				return
 			}
-			this.onSqlRequest(item)
+			this.onRequest(item)
 		case <-this.quit.GetChan():
 			debug("data service exited due to quit notification")
 			return
@@ -75,18 +96,159 @@ func (this *dataService) run() {
 	}
 }
 
+// onRequest dispatches the request to the appropriate handler.
+func (this *dataService) onRequest(item *requestItem) {
+	switch item.req.(type) {
+	case *cmdStatusTablesRequest:
+		this.onStatusTables(item)
+	case *cmdShowTablesRequest:
+		this.onShowTables(item)
+	case *sqlUnsubscribeAllRequest:
+		this.onUnsubscribeAll(item)
+	case *sqlSubscribeSchemaRequest:
+		this.onSubscribeSchema(item)
+	default:
+		this.onSqlRequest(item)
+	}
+}
+
+// onStatusTables sends back statistics of every table ordered by table name.
+// Statistics are sampled without waiting on table event loops.
+func (this *dataService) onStatusTables(item *requestItem) {
+	logInfo("client connection:", item.sender.connectionId, "requested tables status")
+	if item.req.isStreaming() {
+		return
+	}
+	res := newCmdStatusTablesResponse()
+	for _, name := range this.tableNames() {
+		res.addTable(name, this.tables[name].stats.load())
+	}
+	res.requestId = item.getRequestId()
+	item.sender.send(res)
+}
+
+// onShowTables sends back names of all tables ordered by table name.
+func (this *dataService) onShowTables(item *requestItem) {
+	logInfo("client connection:", item.sender.connectionId, "requested tables list")
+	if item.req.isStreaming() {
+		return
+	}
+	res := newCmdShowTablesResponse(this.tableNames())
+	res.requestId = item.getRequestId()
+	item.sender.send(res)
+}
+
+// unsubscribeTally collects pubsubids removed by unsubscribe all.
+// Whoever reports last sends back the response.
+type unsubscribeTally struct {
+	mutex     sync.Mutex
+	item      *requestItem
+	remaining int
+	pubsubids map[uint64]bool
+}
+
+func newUnsubscribeTally(item *requestItem, remaining int) *unsubscribeTally {
+	return &unsubscribeTally{
+		item:      item,
+		remaining: remaining,
+		pubsubids: make(map[uint64]bool),
+	}
+}
+
+// done records removed subscriptions, subscriptions fanned out by table pattern
+// share pubsubid and are counted once.
+func (this *unsubscribeTally) done(pubsubids []uint64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, pubsubid := range pubsubids {
+		this.pubsubids[pubsubid] = true
+	}
+	this.remaining--
+	if this.remaining > 0 || this.item.req.isStreaming() {
+		return
+	}
+	res := &sqlUnsubscribeResponse{unsubscribed: len(this.pubsubids)}
+	res.requestId = this.item.getRequestId()
+	this.item.sender.send(res)
+}
+
+// onUnsubscribeAll removes table pattern subscriptions of the connection
+// and forwards the request to every table to remove the rest.
+func (this *dataService) onUnsubscribeAll(item *requestItem) {
+	tally := newUnsubscribeTally(item, len(this.tables)+1)
+	var pubsubids []uint64
+	patterns := this.patterns[:0]
+	for _, sub := range this.patterns {
+		if sub.sender == item.sender {
+			pubsubids = append(pubsubids, sub.pubsubid)
+			continue
+		}
+		patterns = append(patterns, sub)
+	}
+	this.patterns = patterns
+	pubsubids = append(pubsubids, this.schema.remove(item.sender)...)
+	for _, tbl := range this.tables {
+		req := &sqlUnsubscribeAllRequest{tally: tally}
+		req.setStreaming()
+		tbl.requests <- &requestItem{
+			req:    req,
+			sender: item.sender,
+		}
+	}
+	tally.done(pubsubids)
+}
+
+// tableNames returns sorted names of all tables.
+func (this *dataService) tableNames() []string {
+	names := make([]string, 0, len(this.tables))
+	for name, _ := range this.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// canAutoCreate determines if the table can be created for the request.
+// Sends back error response when the request requires existing table.
+func (this *dataService) canAutoCreate(item *requestItem) bool {
+	switch item.req.(type) {
+	case *sqlTruncateRequest, *sqlDescribeRequest, *sqlSaveRequest:
+		if !item.req.isStreaming() {
+			res := newErrorResponseWithCode(errorCodeTableNotFound, "table "+item.req.getTableName()+" does not exist")
+			res.requestId = item.getRequestId()
+			item.sender.send(res)
+		}
+		return false
+	}
+	if config.SERVER_MAX_TABLES > 0 && len(this.tables) >= config.SERVER_MAX_TABLES {
+		this.reply(item, newErrorResponseWithCode(errorCodeLimitExceeded, "can not create table "+item.req.getTableName()+", maximum number of tables "+strconv.Itoa(config.SERVER_MAX_TABLES)+" reached"))
+		return false
+	}
+	return true
+}
+
 // onSqlRequest forwards sql request to the appropriate table.
 func (this *dataService) onSqlRequest(item *requestItem) {
 	tableName := item.req.getTableName()
+	if isTablePattern(tableName) {
+		this.onTablePattern(item)
+		return
+	}
 	tbl := this.tables[tableName]
+	if tbl == nil && !this.canAutoCreate(item) {
+		return
+	}
 	if tbl == nil {
 		// auto create table and go run table event loop
 		tbl = newTable(tableName)
 		this.tables[tableName] = tbl
 		tbl.quit = this.quit
+		tbl.dataSrv = this
 		tbl.requests = make(chan *requestItem, config.CHAN_TABLE_REQUESTS_BUFFER_SIZE)
 		logInfo("table", tableName, "was created; connection:", item.sender.connectionId)
 		go tbl.run()
+		this.schema.publish("create", tableName, "")
+		this.subscribePatterns(tbl)
 	}
 	switch item.req.(type) {
 	case *mysqlSubscribeRequest:
@@ -99,3 +261,213 @@ func (this *dataService) onSqlRequest(item *requestItem) {
 	// forward sql request to the table
 	tbl.requests <- item
 }
+
+// TABLE PATTERN
+
+// Returns true when table name is a pattern.
+func isTablePattern(name string) bool {
+	return strings.ContainsRune(name, '%')
+}
+
+// Returns true when table name matches the pattern,
+// % matches any sequence of characters and _ matches any single character.
+func matchTablePattern(pattern string, name string) bool {
+	return matchTablePatternRunes([]rune(pattern), []rune(name))
+}
+
+func matchTablePatternRunes(pattern []rune, name []rune) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	switch pattern[0] {
+	case '%':
+		for idx := 0; idx <= len(name); idx++ {
+			if matchTablePatternRunes(pattern[1:], name[idx:]) {
+				return true
+			}
+		}
+		return false
+	case '_':
+		return len(name) > 0 && matchTablePatternRunes(pattern[1:], name[1:])
+	}
+	return len(name) > 0 && pattern[0] == name[0] && matchTablePatternRunes(pattern[1:], name[1:])
+}
+
+// onTablePattern processes requests that target tables matching the pattern.
+func (this *dataService) onTablePattern(item *requestItem) {
+	switch item.req.(type) {
+	case *sqlSubscribeRequest:
+		this.subscribePattern(item)
+	case *sqlUnsubscribeRequest:
+		this.unsubscribePattern(item)
+	default:
+		this.reply(item, newErrorResponse("table pattern "+item.req.getTableName()+" is only supported by subscribe and unsubscribe"))
+	}
+}
+
+// reply sends response back to the client unless the request is streaming.
+func (this *dataService) reply(item *requestItem, res response) {
+	if item.req.isStreaming() {
+		return
+	}
+	res.setRequestId(item.getRequestId())
+	item.sender.send(res)
+}
+
+// subscribePattern subscribes to every existing table matching the pattern
+// and remembers the subscription for tables created later.
+// Fanned out subscriptions share single pubsubid.
+func (this *dataService) subscribePattern(item *requestItem) {
+	req := item.req.(*sqlSubscribeRequest)
+	// sequence numbers are per table
+	if req.since != nil {
+		this.reply(item, newErrorResponse("since is not supported by table pattern subscription"))
+		return
+	}
+	req.sender = item.sender
+	req.pattern = req.table
+	req.pubsubid = atomic.AddUint64(&subid, 1)
+	this.patterns = append(this.patterns, req)
+	this.reply(item, &sqlSubscribeResponse{pubsubid: req.pubsubid})
+	for _, name := range this.tableNames() {
+		if matchTablePattern(req.pattern, name) {
+			this.fanOut(req, this.tables[name])
+		}
+	}
+}
+
+// subscribePatterns subscribes newly created table to matching table patterns.
+func (this *dataService) subscribePatterns(tbl *table) {
+	patterns := this.patterns[:0]
+	for _, req := range this.patterns {
+		// connection is gone
+		if req.sender.quit.Done() {
+			continue
+		}
+		patterns = append(patterns, req)
+		if matchTablePattern(req.pattern, tbl.name) {
+			this.fanOut(req, tbl)
+		}
+	}
+	this.patterns = patterns
+}
+
+// fanOut forwards copy of table pattern subscription to the table.
+// Table does not respond to fanned out subscription, only publishes its events.
+func (this *dataService) fanOut(pattern *sqlSubscribeRequest, tbl *table) {
+	req := *pattern
+	req.table = tbl.name
+	req.setStreaming()
+	tbl.requests <- &requestItem{
+		req:    &req,
+		sender: pattern.sender,
+	}
+}
+
+// unsubscribePattern removes table pattern subscriptions of the connection
+// and tears down subscriptions fanned out to matching tables.
+func (this *dataService) unsubscribePattern(item *requestItem) {
+	req := item.req.(*sqlUnsubscribeRequest)
+	if len(req.filter.col) > 0 && req.filter.col != "pubsubid" {
+		this.reply(item, newErrorResponseWithCode(errorCodeInvalidFilter, "Invalid filter expected pubsubid but got "+req.filter.col))
+		return
+	}
+	var pubsubid uint64
+	if len(req.filter.val) > 0 {
+		var err error
+		pubsubid, err = strconv.ParseUint(req.filter.val, 10, 64)
+		if err != nil {
+			this.reply(item, newErrorResponse("Failed to unsubscribe, pubsubid "+req.filter.val+" is not valid"))
+			return
+		}
+	}
+	res := new(sqlUnsubscribeResponse)
+	patterns := this.patterns[:0]
+	for _, sub := range this.patterns {
+		if sub.sender != item.sender || sub.pattern != req.table || pubsubid != 0 && sub.pubsubid != pubsubid {
+			patterns = append(patterns, sub)
+			continue
+		}
+		res.unsubscribed++
+		for _, name := range this.tableNames() {
+			if matchTablePattern(sub.pattern, name) {
+				unsubscribe := new(sqlUnsubscribeRequest)
+				unsubscribe.table = name
+				unsubscribe.filter.addFilter("pubsubid", strconv.FormatUint(sub.pubsubid, 10))
+				unsubscribe.setStreaming()
+				this.tables[name].requests <- &requestItem{
+					req:    unsubscribe,
+					sender: item.sender,
+				}
+			}
+		}
+	}
+	this.patterns = patterns
+	this.reply(item, res)
+}
+
+// SCHEMA SUBSCRIPTIONS
+
+// onSubscribeSchema subscribes the connection to schema changes of all tables.
+func (this *dataService) onSubscribeSchema(item *requestItem) {
+	pubsubid := this.schema.add(item.sender)
+	this.reply(item, &sqlSubscribeResponse{pubsubid: pubsubid})
+}
+
+// schemaSubscription is a subscription to schema changes.
+type schemaSubscription struct {
+	pubsubid uint64
+	sender   *responseSender
+}
+
+// schemaSubscriptions are subscriptions to schema changes of all tables.
+// Tables publish added columns from their own event loops, hence access is synchronized.
+type schemaSubscriptions struct {
+	mutex sync.Mutex
+	subs  []*schemaSubscription
+}
+
+// add subscribes the connection and returns pubsubid of the subscription.
+func (this *schemaSubscriptions) add(sender *responseSender) uint64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	sub := &schemaSubscription{
+		pubsubid: atomic.AddUint64(&subid, 1),
+		sender:   sender,
+	}
+	this.subs = append(this.subs, sub)
+	return sub.pubsubid
+}
+
+// remove unsubscribes the connection and returns pubsubids of removed subscriptions.
+func (this *schemaSubscriptions) remove(sender *responseSender) []uint64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var pubsubids []uint64
+	subs := this.subs[:0]
+	for _, sub := range this.subs {
+		if sub.sender == sender {
+			pubsubids = append(pubsubids, sub.pubsubid)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	this.subs = subs
+	return pubsubids
+}
+
+// publish sends schema change to every subscriber,
+// subscriptions of connections that are gone are removed.
+func (this *schemaSubscriptions) publish(action string, table string, column string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	subs := this.subs[:0]
+	for _, sub := range this.subs {
+		if sub.sender.quit.Done() {
+			continue
+		}
+		subs = append(subs, sub)
+		sub.sender.send(newSchemaChangeResponse(sub.pubsubid, action, table, column))
+	}
+	this.subs = subs
+}
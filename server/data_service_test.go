@@ -18,6 +18,8 @@ package server
 
 import "testing"
 import "time"
+import "strings"
+import "strconv"
 
 func TestDataServiceRunAndStop(t *testing.T) {
 	quit := NewQuitter()
@@ -69,6 +71,7 @@ func TestDataService(t *testing.T) {
 	res = sender.testRecv()
 	validateSqlSubscribeResponse(t, res)
 	res = sender.testRecv() // action add
+	res = sender.testRecv() // action complete
 	// update
 	dataSrv.acceptRequest(sqlHelper(" update stocks set bid = 140 where ticker = IBM ", sender))
 	res = sender.testRecv() // first is action update
@@ -83,5 +86,539 @@ func TestDataService(t *testing.T) {
 	dataSrv.acceptRequest(sqlHelper(" unsubscribe from stocks where pubsubid = 1 ", sender))
 	res = sender.testRecv() // first is action delete
 	validateSqlUnsubscribe(t, res, 1)
+	// select into
+	dataSrv.acceptRequest(sqlHelper("insert into stocks (ticker, bid, sector) values (MSFT, 12, TECH) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper("insert into stocks (ticker, bid, sector) values (JPM, 14, FIN) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" select ticker, bid from stocks into snapshot where sector = TECH ", sender))
+	res = sender.testRecv()
+	validateSqlInsertResponse(t, res)
+	if x := res.(*sqlActionDataResponse); x.rows != 1 || x.action != "into" {
+		t.Errorf("select into error: expected 1 row but got %d", x.rows)
+	}
+	dataSrv.acceptRequest(sqlHelper(" select * from snapshot ", sender))
+	res = sender.testRecv()
+	validateSqlSelect(t, res, 1, 3)
+	// zero rows still create destination table
+	dataSrv.acceptRequest(sqlHelper(" select * from stocks into empty where sector = NONE ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" select * from empty ", sender))
+	res = sender.testRecv()
+	validateSqlSelect(t, res, 0, 5)
+	// truncate
+	dataSrv.acceptRequest(sqlHelper(" truncate table stocks ", sender))
+	res = sender.testRecv()
+	validateSqlDelete(t, res, 2)
+	// truncate does not create missing tables
+	dataSrv.acceptRequest(sqlHelper(" truncate table bonds ", sender))
+	res = sender.testRecv()
+	validateErrorResponse(t, res)
+	// neither does describe
+	dataSrv.acceptRequest(sqlHelper(" describe bonds ", sender))
+	res = sender.testRecv()
+	validateErrorResponse(t, res)
+	// status tables returns one row per table ordered by name
+	dataSrv.acceptRequest(sqlHelper(" status tables ", sender))
+	res = sender.testRecv()
+	if x, ok := res.(*cmdStatusTablesResponse); !ok || len(x.records) != 3 || len(x.columns) != 5 {
+		t.Errorf("status tables error: expected 3 rows and 5 columns but got %T", res)
+	} else {
+		validateResponseJSON(t, res)
+		for idx, name := range []string{"empty", "snapshot", "stocks"} {
+			if x.records[idx].getValue(0) != name {
+				t.Errorf("status tables error: expected table %s but got %s", name, x.records[idx].getValue(0))
+			}
+		}
+		if x.records[1].getValue(1) != "1" {
+			t.Errorf("status tables error: expected 1 record in snapshot but got %s", x.records[1].getValue(1))
+		}
+	}
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func TestMatchTablePattern(t *testing.T) {
+	for _, x := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"user_%", "user_123", true},
+		{"user_%", "user_", true},
+		{"user_%", "user", false},
+		{"%_log", "audit_log", true},
+		{"%_log", "log", false},
+		{"t%s%", "tables", true},
+		{"user_1", "user_1", true},
+		{"user_1", "userX1", true},
+		{"%", "stocks", true},
+	} {
+		if matchTablePattern(x.pattern, x.name) != x.match {
+			t.Errorf("table pattern error: expected %s matches %s to be %t", x.pattern, x.name, x.match)
+		}
+	}
+}
+
+func TestDataServiceSubscribeTablePattern(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	sender := newResponseSenderStub(1)
+	subscriber := newResponseSenderStub(2)
+	dataSrv.acceptRequest(sqlHelper(" insert into user_1 (name) values (john) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" insert into orders (name) values (john) ", sender))
+	sender.testRecv()
+	// subscribe to existing table
+	dataSrv.acceptRequest(sqlHelper(" subscribe * from user_% ", subscriber))
+	res := subscriber.testRecv()
+	validateSqlSubscribeResponse(t, res)
+	pubsubid := res.(*sqlSubscribeResponse).pubsubid
+	if x, ok := subscriber.testRecv().(*sqlActionAddResponse); !ok || x.pubsubid != pubsubid || x.table != "user_1" || len(x.records) != 1 {
+		t.Errorf("table pattern error: expected add from user_1")
+	}
+	if x, ok := subscriber.testRecv().(*sqlActionCompleteResponse); !ok || x.table != "user_1" {
+		t.Errorf("table pattern error: expected complete from user_1")
+	}
+	// newly created table
+	dataSrv.acceptRequest(sqlHelper(" insert into user_2 (name) values (jane) ", sender))
+	sender.testRecv()
+	if x, ok := subscriber.testRecv().(*sqlActionCompleteResponse); !ok || x.table != "user_2" {
+		t.Errorf("table pattern error: expected complete from user_2")
+	}
+	res = subscriber.testRecv()
+	if x, ok := res.(*sqlActionInsertResponse); !ok || x.pubsubid != pubsubid || x.table != "user_2" {
+		t.Errorf("table pattern error: expected insert from user_2")
+	} else {
+		validateResponseJSON(t, res)
+	}
+	dataSrv.acceptRequest(sqlHelper(" insert into orders (name) values (jane) ", sender))
+	sender.testRecv()
+	if res := subscriber.tryRecv(); res != nil {
+		t.Errorf("table pattern error: unexpected %T from not matching table", res)
+	}
+	// only subscribe and unsubscribe accept table pattern
+	dataSrv.acceptRequest(sqlHelper(" select * from user_% ", sender))
+	validateErrorResponse(t, sender.testRecv())
+	// sequence numbers are per table
+	dataSrv.acceptRequest(sqlHelper(" subscribe * from user_% since 1 ", sender))
+	validateErrorResponse(t, sender.testRecv())
+	// unsubscribe tears down all fanned out subscriptions
+	dataSrv.acceptRequest(sqlHelper(" unsubscribe from user_% ", subscriber))
+	validateSqlUnsubscribe(t, subscriber.testRecv(), 1)
+	dataSrv.acceptRequest(sqlHelper(" insert into user_1 (name) values (jack) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" insert into user_3 (name) values (jack) ", sender))
+	sender.testRecv()
+	if res := subscriber.tryRecv(); res != nil {
+		t.Errorf("table pattern error: unexpected %T after unsubscribe", res)
+	}
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func validateShowTables(t *testing.T, res response, names []string) {
+	x, ok := res.(*cmdShowTablesResponse)
+	if !ok {
+		t.Errorf("show tables error: invalid response type expected cmdShowTablesResponse but got %T", res)
+		return
+	}
+	if len(x.records) != len(names) || len(x.columns) != 1 || x.columns[0].name != "table" {
+		t.Errorf("show tables error: expected %d rows but got %d", len(names), len(x.records))
+		return
+	}
+	for idx, name := range names {
+		if x.records[idx].getValue(0) != name {
+			t.Errorf("show tables error: expected table %s but got %s", name, x.records[idx].getValue(0))
+		}
+	}
+	validateResponseJSON(t, res)
+}
+
+func TestRequestRouterPing(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	router.route(sqlHelper(" ping ", sender))
+	validatePing(t, sender.testRecv(), true)
+	// draining
+	quit.Quit(0)
+	router.route(sqlHelper(" ping ", sender))
+	validatePing(t, sender.testRecv(), false)
+}
+
+func TestRequestRouterSyntaxError(t *testing.T) {
+	dataSrv := newDataService(NewQuitter())
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	router.route(sqlHelper(" selec * from stocks ", sender))
+	res, ok := sender.testRecv().(*errorResponse)
+	if !ok || res.code != errorCodeSyntax {
+		t.Errorf("router error: expected syntax error code")
+	}
+}
+
+func TestRequestRouterTransaction(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	router.route(sqlHelper(" commit ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeTransaction)
+	router.route(sqlHelper(" begin ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" begin ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeTransaction)
+	// statements are queued
+	router.route(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" insert into bonds (ticker) values (T10) ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeTransaction)
+	router.route(sqlHelper(" select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 0, 1)
+	// rollback discards queued statements
+	router.route(sqlHelper(" rollback ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" rollback ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeTransaction)
+	// commit applies queued statements
+	router.route(sqlHelper(" begin ", sender))
+	sender.testRecv()
+	router.route(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	sender.testRecv()
+	router.route(sqlHelper(" insert into stocks (ticker) values (MSFT) ", sender))
+	sender.testRecv()
+	router.route(sqlHelper(" commit ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 2, 2)
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func scriptHelper(sql string, sender *responseSender) *requestItem {
+	return &requestItem{
+		req:    parseMessage(sql, newTokens()),
+		sender: sender,
+	}
+}
+
+func validateScriptError(t *testing.T, res response, code errorCode, statement string) {
+	validateErrorCode(t, res, code)
+	if errres, ok := res.(*errorResponse); ok && !strings.HasPrefix(errres.msg, "statement "+statement+": ") {
+		t.Errorf("script error: expected error of statement %s but got %s", statement, errres.msg)
+	}
+}
+
+func TestRequestRouterScript(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	// statements are executed in order and response of the last one is returned
+	router.route(scriptHelper(" insert into stocks (ticker) values (IBM); insert into stocks (ticker) values ('M;SFT') ; select * from stocks; ", sender))
+	validateSqlSelect(t, sender.testRecv(), 2, 2)
+	validateNoResponse(t, sender)
+	router.route(sqlHelper(" key stocks ticker ", sender))
+	validateOkResponse(t, sender.testRecv())
+	// execution stops at the first failed statement, changes of previous statements are kept
+	router.route(scriptHelper(" insert into stocks (ticker) values (ORCL); insert into stocks (ticker) values (IBM); insert into stocks (ticker) values (JPM) ", sender))
+	validateScriptError(t, sender.testRecv(), errorCodeDuplicateKey, "2")
+	router.route(sqlHelper(" select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 3, 2)
+	// statements enclosed in begin and commit are all or nothing
+	router.route(scriptHelper(" begin; insert into stocks (ticker) values (JPM); insert into stocks (ticker) values (IBM); commit ", sender))
+	validateScriptError(t, sender.testRecv(), errorCodeDuplicateKey, "4")
+	router.route(sqlHelper(" select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 3, 2)
+	router.route(scriptHelper(" begin; insert into stocks (ticker) values (JPM); commit; select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 4, 2)
+	// transaction begun by failed script is discarded
+	router.route(scriptHelper(" begin; insert into stocks (ticker) values (AAPL); insert into bonds (ticker) values (T10) ", sender))
+	validateScriptError(t, sender.testRecv(), errorCodeTransaction, "3")
+	router.route(sqlHelper(" commit ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeTransaction)
+	// script is rejected as a whole when any statement is not valid
+	router.route(scriptHelper(" insert into stocks (ticker) values (AAPL); selec * from stocks ", sender))
+	validateScriptError(t, sender.testRecv(), errorCodeSyntax, "2")
+	router.route(scriptHelper(" insert into stocks (ticker) values (AAPL); subscribe * from stocks ", sender))
+	validateScriptError(t, sender.testRecv(), errorCodeSyntax, "2")
+	router.route(sqlHelper(" select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 4, 2)
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func TestRequestRouterSelectIntoOrder(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	for _, ticker := range []string{"IBM", "MSFT", "ORCL"} {
+		router.route(sqlHelper(" insert into stocks (ticker) values ("+ticker+") ", sender))
+		sender.testRecv()
+	}
+	// request that follows select into sees the copied records
+	for i := 0; i < 20; i++ {
+		table := "snapshot" + strconv.Itoa(i)
+		router.route(sqlHelper(" select * from stocks into "+table+" ", sender))
+		router.route(sqlHelper(" select * from "+table+" ", sender))
+		validateSqlInsertResponse(t, sender.testRecv())
+		validateSqlSelect(t, sender.testRecv(), 3, 2)
+	}
+	// streaming select into is awaited without response
+	router.route(sqlHelper(" stream select * from stocks into streamed ", sender))
+	router.route(sqlHelper(" select * from streamed ", sender))
+	validateSqlSelect(t, sender.testRecv(), 3, 2)
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func TestRequestRouterScriptShutdown(t *testing.T) {
+	quit := NewQuitter()
+	// data service is not running, statements are never answered
+	dataSrv := newDataService(quit)
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	done := make(chan bool)
+	go func() {
+		router.route(scriptHelper(" insert into stocks (ticker) values (IBM); select * from stocks ", sender))
+		done <- true
+	}()
+	quit.Quit(0)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("script error: script kept waiting after server shutdown")
+	}
+}
+
+func TestRequestRouterSession(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	router := newRequestRouter(dataSrv)
+	sender := newResponseSenderStub(1)
+	other := newResponseSenderStub(2)
+	for _, ticker := range []string{"IBM", "MSFT", "ORCL"} {
+		router.route(sqlHelper(" insert into stocks (ticker) values ("+ticker+") ", sender))
+		sender.testRecv()
+	}
+	// unknown settings and invalid values are rejected
+	router.route(sqlHelper(" set session timezone = UTC ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeGeneric)
+	router.route(sqlHelper(" set session limit = -1 ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeInvalidValue)
+	router.route(sqlHelper(" set session timeout = soon ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeInvalidValue)
+	// query timeout
+	router.route(sqlHelper(" set session timeout = 60000 ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" select * from stocks where not ticker = IBM ", sender))
+	validateSqlSelect(t, sender.testRecv(), 2, 2)
+	// default limit
+	router.route(sqlHelper(" set session limit = 2 ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" select * from stocks ", sender))
+	validateSqlSelect(t, sender.testRecv(), 2, 2)
+	router.route(sqlHelper(" select * from stocks limit 3 ", sender))
+	validateSqlSelect(t, sender.testRecv(), 3, 2)
+	// strict columns
+	router.route(sqlHelper(" set session strict = 0 ", sender))
+	validateOkResponse(t, sender.testRecv())
+	router.route(sqlHelper(" select * from stocks where sector = '' ", sender))
+	validateSqlSelect(t, sender.testRecv(), 2, 2)
+	// other connections are not affected
+	router.route(sqlHelper(" select * from stocks ", other))
+	validateSqlSelect(t, other.testRecv(), 3, 2)
+	router.route(sqlHelper(" select * from stocks where sector = '' ", other))
+	validateErrorCode(t, other.testRecv(), errorCodeInvalidColumn)
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func validatePing(t *testing.T, res response, accepting bool) {
+	x, ok := res.(*cmdPingResponse)
+	if !ok {
+		t.Errorf("ping error: invalid response type expected cmdPingResponse but got %T", res)
+		return
+	}
+	if x.accepting != accepting {
+		t.Errorf("ping error: expected accepting %t", accepting)
+	}
+	validateResponseJSON(t, res)
+}
+
+func TestDataServiceUnsubscribeAll(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	sender := newResponseSenderStub(1)
+	subscriber := newResponseSenderStub(2)
+	// no subscriptions and no tables
+	dataSrv.acceptRequest(sqlHelper(" unsubscribe all ", subscriber))
+	validateSqlUnsubscribe(t, subscriber.testRecv(), 0)
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" insert into bonds (ticker) values (T10) ", sender))
+	sender.testRecv()
+	for _, sql := range []string{" subscribe * from stocks ", " subscribe * from bonds "} {
+		dataSrv.acceptRequest(sqlHelper(sql, subscriber))
+		validateSqlSubscribeResponse(t, subscriber.testRecv())
+		subscriber.testRecv() // add
+		subscriber.testRecv() // complete
+		dataSrv.acceptRequest(sqlHelper(sql, sender))
+		sender.testRecv()
+		sender.testRecv()
+		sender.testRecv()
+	}
+	// table pattern subscription fanned out to both tables counts once
+	dataSrv.acceptRequest(sqlHelper(" subscribe * from % ", subscriber))
+	validateSqlSubscribeResponse(t, subscriber.testRecv())
+	for i := 0; i < 4; i++ {
+		subscriber.testRecv() // add and complete from every table
+	}
+	dataSrv.acceptRequest(sqlHelper(" unsubscribe all ", subscriber))
+	validateSqlUnsubscribe(t, subscriber.testRecv(), 3)
+	// no more events for the connection, other connections are not affected
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (MSFT) ", sender))
+	if _, ok := sender.testRecv().(*sqlActionInsertResponse); !ok {
+		t.Errorf("unsubscribe all error: expected insert event for other connection")
+	}
+	validateSqlInsertResponse(t, sender.testRecv())
+	dataSrv.acceptRequest(sqlHelper(" insert into user_1 (name) values (john) ", sender))
+	sender.testRecv()
+	if res := subscriber.tryRecv(); res != nil {
+		t.Errorf("unsubscribe all error: unexpected %T after unsubscribe all", res)
+	}
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func validateSchemaChange(t *testing.T, res response, pubsubid uint64, action string, table string, column string) {
+	x, ok := res.(*schemaChangeResponse)
+	if !ok {
+		t.Errorf("schema subscription error: expected schemaChangeResponse but got %T", res)
+		return
+	}
+	if x.pubsubid != pubsubid || x.action != action || x.table != table || x.column != column {
+		t.Errorf("schema subscription error: expected %s %s %s but got %s %s %s", action, table, column, x.action, x.table, x.column)
+	}
+}
+
+func TestDataServiceSubscribeSchema(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	sender := newResponseSenderStub(1)
+	subscriber := newResponseSenderStub(2)
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" subscribe schema ", subscriber))
+	res := subscriber.testRecv()
+	validateSqlSubscribeResponse(t, res)
+	pubsubid := res.(*sqlSubscribeResponse).pubsubid
+	// created table and added columns
+	dataSrv.acceptRequest(sqlHelper(" create table bonds (ticker, yield float) ", sender))
+	validateOkResponse(t, sender.testRecv())
+	validateSchemaChange(t, subscriber.testRecv(), pubsubid, "create", "bonds", "")
+	validateSchemaChange(t, subscriber.testRecv(), pubsubid, "alter", "bonds", "ticker")
+	res = subscriber.testRecv()
+	validateSchemaChange(t, res, pubsubid, "alter", "bonds", "yield")
+	validateResponseJSON(t, res)
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker, bid) values (MSFT, 12) ", sender))
+	sender.testRecv()
+	validateSchemaChange(t, subscriber.testRecv(), pubsubid, "alter", "stocks", "bid")
+	// records without new columns and failed requests do not change schema
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker, bid) values (ORCL, 13) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" insert into bonds (ticker, yield) values (T10, high) ", sender))
+	validateErrorResponse(t, sender.testRecv())
+	dataSrv.acceptRequest(sqlHelper(" select * from stocks ", sender))
+	sender.testRecv()
+	if res := subscriber.tryRecv(); res != nil {
+		t.Errorf("schema subscription error: unexpected %T without schema change", res)
+	}
+	// unsubscribe all removes schema subscription
+	dataSrv.acceptRequest(sqlHelper(" unsubscribe all ", subscriber))
+	validateSqlUnsubscribe(t, subscriber.testRecv(), 1)
+	dataSrv.acceptRequest(sqlHelper(" insert into orders (name) values (john) ", sender))
+	sender.testRecv()
+	if res := subscriber.tryRecv(); res != nil {
+		t.Errorf("schema subscription error: unexpected %T after unsubscribe all", res)
+	}
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func TestDataServiceTableLimit(t *testing.T) {
+	defer func(max int) { config.SERVER_MAX_TABLES = max }(config.SERVER_MAX_TABLES)
+	config.SERVER_MAX_TABLES = 1
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	sender := newResponseSenderStub(1)
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	validateSqlInsertResponse(t, sender.testRecv())
+	dataSrv.acceptRequest(sqlHelper(" insert into bonds (ticker) values (T10) ", sender))
+	validateErrorCode(t, sender.testRecv(), errorCodeLimitExceeded)
+	// existing table is unaffected
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (MSFT) ", sender))
+	validateSqlInsertResponse(t, sender.testRecv())
+	dataSrv.acceptRequest(sqlHelper(" show tables ", sender))
+	validateShowTables(t, sender.testRecv(), []string{"stocks"})
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func TestDataServiceSelectIntoBackPressure(t *testing.T) {
+	defer func(tableSize int, dataSize int) {
+		config.CHAN_TABLE_REQUESTS_BUFFER_SIZE = tableSize
+		config.CHAN_DATA_SERVICE_REQUESTS_BUFFER_SIZE = dataSize
+	}(config.CHAN_TABLE_REQUESTS_BUFFER_SIZE, config.CHAN_DATA_SERVICE_REQUESTS_BUFFER_SIZE)
+	config.CHAN_TABLE_REQUESTS_BUFFER_SIZE = 1
+	config.CHAN_DATA_SERVICE_REQUESTS_BUFFER_SIZE = 1
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	sender := newResponseSenderStub(1)
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" insert into bonds (ticker) values (T10) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" tag stocks ticker ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" tag bonds ticker ", sender))
+	sender.testRecv()
+	// tables copy into each other while the data service is saturated
+	const count = 100
+	go func() {
+		for i := 0; i < count; i++ {
+			dataSrv.acceptRequest(sqlHelper(" select * from stocks into bonds where ticker = IBM ", sender))
+			dataSrv.acceptRequest(sqlHelper(" select * from bonds into stocks where ticker = T10 ", sender))
+		}
+	}()
+	for i := 0; i < 2*count; i++ {
+		select {
+		case res := <-sender.sender:
+			validateSqlInsertResponse(t, dequeued(res))
+		case <-time.After(3 * time.Second):
+			t.Fatalf("select into error: deadlock after %d responses", i)
+		}
+	}
+	quit.Quit(time.Millisecond * 1000)
+}
+
+func TestDataServiceShowTables(t *testing.T) {
+	quit := NewQuitter()
+	dataSrv := newDataService(quit)
+	go dataSrv.run()
+	sender := newResponseSenderStub(1)
+	// empty server
+	dataSrv.acceptRequest(sqlHelper(" show tables ", sender))
+	validateShowTables(t, sender.testRecv(), []string{})
+	//
+	dataSrv.acceptRequest(sqlHelper(" insert into stocks (ticker) values (IBM) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" insert into bonds (ticker) values (T10) ", sender))
+	sender.testRecv()
+	dataSrv.acceptRequest(sqlHelper(" show tables ", sender))
+	validateShowTables(t, sender.testRecv(), []string{"bonds", "stocks"})
 	quit.Quit(time.Millisecond * 1000)
 }
@@ -0,0 +1,118 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+// Record change actions remembered by event log.
+const (
+	eventActionInsert = "insert"
+	eventActionUpdate = "update"
+	eventActionDelete = "delete"
+)
+
+// tableEvent is a change of table record replayed to subscribers
+// that resume subscription since the sequence number they have seen.
+type tableEvent struct {
+	seq    uint64
+	action string
+	cols   []*column // updated columns
+	before *record   // copy of record values before update or delete
+	after  *record   // copy of record values after insert or update
+}
+
+// eventLog is a bounded buffer of the most recent table events,
+// the oldest events are evicted first.
+// Sequence numbers of logged events are consecutive.
+type eventLog struct {
+	events []*tableEvent
+	first  int // position of the oldest event
+	count  int
+}
+
+// eventLog factory, size of 0 disables the log
+func newEventLog(size int) *eventLog {
+	return &eventLog{
+		events: make([]*tableEvent, size),
+	}
+}
+
+// Returns true when the log remembers events.
+func (this *eventLog) enabled() bool {
+	return len(this.events) > 0
+}
+
+// Adds the event evicting the oldest one when the log is full.
+func (this *eventLog) add(ev *tableEvent) {
+	size := len(this.events)
+	if size == 0 {
+		return
+	}
+	if this.count < size {
+		this.events[(this.first+this.count)%size] = ev
+		this.count++
+		return
+	}
+	this.events[this.first] = ev
+	this.first = (this.first + 1) % size
+}
+
+// Returns events that follow the sequence number up to the last one.
+// Returns false when some of them were evicted or never logged,
+// in which case subscriber has to resync.
+func (this *eventLog) since(seq uint64, last uint64) ([]*tableEvent, bool) {
+	if seq == last {
+		return nil, true
+	}
+	if seq > last || this.count == 0 || this.events[this.first].seq > seq+1 {
+		return nil, false
+	}
+	events := make([]*tableEvent, 0, this.count)
+	for idx := 0; idx < this.count; idx++ {
+		ev := this.events[(this.first+idx)%len(this.events)]
+		if ev.seq > seq {
+			events = append(events, ev)
+		}
+	}
+	return events, true
+}
+
+// Forgets events that follow the sequence number.
+func (this *eventLog) discardAfter(seq uint64) {
+	for this.count > 0 {
+		idx := (this.first + this.count - 1) % len(this.events)
+		if this.events[idx].seq <= seq {
+			return
+		}
+		this.events[idx] = nil
+		this.count--
+	}
+}
+
+// Forgets all events.
+func (this *eventLog) clear() {
+	for idx := range this.events {
+		this.events[idx] = nil
+	}
+	this.first = 0
+	this.count = 0
+}
+
+// Returns copy of record values that is not affected by later changes of the record.
+func copyRecordValues(rec *record) *record {
+	values := make([]string, len(rec.values))
+	copy(values, rec.values)
+	return &record{values: values}
+}
@@ -0,0 +1,88 @@
+/* Copyright (C) 2014 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"testing"
+)
+
+func validateEventSeqs(t *testing.T, events []*tableEvent, ok bool, expected ...uint64) {
+	if !ok {
+		t.Errorf("event log error: expected events %v but they were evicted", expected)
+		return
+	}
+	if len(events) != len(expected) {
+		t.Errorf("event log error: expected %d events but got %d", len(expected), len(events))
+		return
+	}
+	for idx, ev := range events {
+		if ev.seq != expected[idx] {
+			t.Errorf("event log error: expected seq %d but got %d", expected[idx], ev.seq)
+		}
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	log := newEventLog(3)
+	// nothing to replay
+	events, ok := log.since(0, 0)
+	validateEventSeqs(t, events, ok)
+	for seq := uint64(1); seq <= 2; seq++ {
+		log.add(&tableEvent{seq: seq})
+	}
+	events, ok = log.since(0, 2)
+	validateEventSeqs(t, events, ok, 1, 2)
+	events, ok = log.since(1, 2)
+	validateEventSeqs(t, events, ok, 2)
+	// oldest event is evicted
+	for seq := uint64(3); seq <= 4; seq++ {
+		log.add(&tableEvent{seq: seq})
+	}
+	if _, ok = log.since(0, 4); ok {
+		t.Errorf("event log error: expected evicted event")
+	}
+	events, ok = log.since(1, 4)
+	validateEventSeqs(t, events, ok, 2, 3, 4)
+	// sequence number that was not reached
+	if _, ok = log.since(5, 4); ok {
+		t.Errorf("event log error: expected resync for future seq")
+	}
+	// rolled back events
+	log.discardAfter(2)
+	events, ok = log.since(1, 2)
+	validateEventSeqs(t, events, ok, 2)
+	log.add(&tableEvent{seq: 3})
+	events, ok = log.since(1, 3)
+	validateEventSeqs(t, events, ok, 2, 3)
+	log.clear()
+	if _, ok = log.since(2, 3); ok {
+		t.Errorf("event log error: expected resync after clear")
+	}
+}
+
+func TestEventLogDisabled(t *testing.T) {
+	log := newEventLog(0)
+	log.add(&tableEvent{seq: 1})
+	if log.enabled() {
+		t.Errorf("event log error: expected disabled log")
+	}
+	if _, ok := log.since(0, 1); ok {
+		t.Errorf("event log error: expected resync when log is disabled")
+	}
+	events, ok := log.since(1, 1)
+	validateEventSeqs(t, events, ok)
+}
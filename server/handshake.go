@@ -0,0 +1,103 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Handshake is an ordinary message sent by the client: handshake command followed by
+// space separated protocol version and names of capabilities the client supports,
+// for example "handshake 1 compression probe idempotency". The server replies with
+// handshakeResponse carrying its version, negotiated protocol version, capabilities
+// both sides support and id the server assigned to the connection, which is included
+// in server log lines. Servers that do not know the handshake command answer it right
+// away with syntax error and the client keeps using the legacy protocol on the same connection.
+// Probes and idempotent requests are only accepted from clients that negotiated them.
+const (
+	serverVersion    = "1.0"
+	protocolVersion  = 1
+	handshakeCommand = "handshake"
+)
+
+// protocol capabilities
+const (
+	capabilityCompression = "compression"
+	capabilityProbe       = "probe"
+	capabilityIdempotency = "idempotency"
+)
+
+var serverCapabilities = []string{capabilityCompression, capabilityProbe, capabilityIdempotency}
+
+// handshake is the protocol negotiated with the client.
+type handshake struct {
+	protocol     int
+	capabilities []string
+}
+
+// Returns protocol version and capabilities that follow the handshake command,
+// ok is false when the message is not a handshake.
+func handshakeArguments(message []byte) (args []byte, ok bool) {
+	trimmed := bytes.TrimLeftFunc(message, unicode.IsSpace)
+	if len(trimmed) < len(handshakeCommand) || !bytes.EqualFold(trimmed[:len(handshakeCommand)], []byte(handshakeCommand)) {
+		return nil, false
+	}
+	args = trimmed[len(handshakeCommand):]
+	if len(args) > 0 && !unicode.IsSpace(rune(args[0])) {
+		return nil, false
+	}
+	return args, true
+}
+
+// Negotiates protocol from protocol version and capabilities sent by the client.
+func negotiateHandshake(message []byte) (*handshake, error) {
+	fields := strings.Fields(string(message))
+	if len(fields) == 0 {
+		return nil, errors.New("handshake requires protocol version")
+	}
+	version, err := strconv.Atoi(fields[0])
+	if err != nil || version < 1 {
+		return nil, errors.New("invalid protocol version " + fields[0])
+	}
+	if version > protocolVersion {
+		version = protocolVersion
+	}
+	ret := &handshake{protocol: version}
+	for _, capability := range serverCapabilities {
+		for _, requested := range fields[1:] {
+			if requested == capability {
+				ret.capabilities = append(ret.capabilities, capability)
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// Returns true when both sides support the capability.
+func (this *handshake) supports(capability string) bool {
+	for _, c := range this.capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"testing"
+)
+
+func TestNegotiateHandshake(t *testing.T) {
+	negotiated, err := negotiateHandshake([]byte("1 compression idempotency"))
+	if err != nil || negotiated.protocol != 1 || len(negotiated.capabilities) != 2 {
+		t.Fatal("unexpected handshake", negotiated, err)
+	}
+	if !negotiated.supports(capabilityCompression) || negotiated.supports(capabilityProbe) {
+		t.Error("expected only requested capabilities to be negotiated")
+	}
+	// newer client gets server protocol, unknown capabilities are ignored
+	negotiated, err = negotiateHandshake([]byte("7 probe multiplexing"))
+	if err != nil || negotiated.protocol != protocolVersion || len(negotiated.capabilities) != 1 {
+		t.Error("unexpected handshake", negotiated, err)
+	}
+	//
+	if _, err = negotiateHandshake([]byte("")); err == nil {
+		t.Error("expected missing protocol version error")
+	}
+	if _, err = negotiateHandshake([]byte("compression")); err == nil {
+		t.Error("expected invalid protocol version error")
+	}
+	if _, err = negotiateHandshake([]byte("0")); err == nil {
+		t.Error("expected invalid protocol version error")
+	}
+}
+
+func TestHandshakeArguments(t *testing.T) {
+	for message, expected := range map[string]string{
+		"handshake 1 compression": " 1 compression",
+		" HANDSHAKE 1":            " 1",
+		"handshake":               "",
+	} {
+		if args, ok := handshakeArguments([]byte(message)); !ok || string(args) != expected {
+			t.Errorf("handshake error: expected %q but got %q for %q", expected, args, message)
+		}
+	}
+	for _, message := range []string{"", "handshakes 1", "select * from handshake", "ping"} {
+		if _, ok := handshakeArguments([]byte(message)); ok {
+			t.Errorf("handshake error: %q is not a handshake", message)
+		}
+	}
+	// servers that do not know the command reject it as syntax error
+	if _, ok := parseMessage("handshake 1 compression probe idempotency", newTokens()).(*errorRequest); !ok {
+		t.Error("handshake error: expected handshake command to be rejected by the parser")
+	}
+}
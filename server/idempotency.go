@@ -0,0 +1,159 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// idempotentResult is the result of request that was sent with idempotency key.
+type idempotentResult struct {
+	key       string
+	requestId uint32
+	messages  [][]byte // network ready messages of the original response
+	retries   []uint32 // retries waiting for the original response
+	expires   time.Time
+}
+
+// idempotencyCache remembers results of idempotent requests of a client connection
+// so that retries with the same key are answered without executing them again.
+// It is accessed by both connection reader and writer.
+type idempotencyCache struct {
+	mutex    sync.Mutex
+	window   time.Duration
+	keys     map[string]*idempotentResult
+	pending  map[uint32]*idempotentResult // by request id of the original request
+	complete []*idempotentResult          // in order of expiration
+}
+
+// idempotencyCache factory, window of 0 disables deduplication
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		window:  window,
+		keys:    make(map[string]*idempotentResult),
+		pending: make(map[uint32]*idempotentResult),
+	}
+}
+
+// begin registers idempotent request.
+// Returns true when the request is a retry and must not be executed,
+// along with the original result when it is already available.
+func (this *idempotencyCache) begin(key string, requestId uint32, now time.Time) (response, bool) {
+	if this.window == 0 {
+		return nil, false
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.expire(now)
+	if result, contains := this.keys[key]; contains {
+		if result.expires.IsZero() {
+			result.retries = append(result.retries, requestId)
+			return nil, true
+		}
+		return newIdempotentReplayResponse(requestId, result.messages), true
+	}
+	if _, contains := this.pending[requestId]; contains || requestId == 0 {
+		// response can not be told apart by request id, the request is not tracked
+		return nil, false
+	}
+	result := &idempotentResult{
+		key:       key,
+		requestId: requestId,
+	}
+	this.keys[key] = result
+	this.pending[requestId] = result
+	return nil, false
+}
+
+// record remembers network ready message when it belongs to the original response
+// of idempotent request.
+// Returns responses for retries that were waiting for the original response to complete.
+func (this *idempotencyCache) record(message []byte, more bool, now time.Time) []response {
+	if this.window == 0 {
+		return nil
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if len(this.pending) == 0 {
+		return nil
+	}
+	var header netHeader
+	header.readFrom(message)
+	result, contains := this.pending[header.RequestId]
+	if !contains {
+		return nil
+	}
+	copied := make([]byte, len(message))
+	copy(copied, message)
+	result.messages = append(result.messages, copied)
+	if more {
+		return nil
+	}
+	delete(this.pending, result.requestId)
+	result.expires = now.Add(this.window)
+	this.complete = append(this.complete, result)
+	var replays []response
+	for _, requestId := range result.retries {
+		replays = append(replays, newIdempotentReplayResponse(requestId, result.messages))
+	}
+	result.retries = nil
+	return replays
+}
+
+// expire forgets results that are older than the window.
+func (this *idempotencyCache) expire(now time.Time) {
+	expired := 0
+	for _, result := range this.complete {
+		if now.Before(result.expires) {
+			break
+		}
+		delete(this.keys, result.key)
+		expired++
+	}
+	if expired > 0 {
+		this.complete = this.complete[expired:]
+	}
+}
+
+// idempotentReplayResponse resends the original response of idempotent request to its retry.
+type idempotentReplayResponse struct {
+	requestIdResponse
+	messages [][]byte
+	index    int
+}
+
+func newIdempotentReplayResponse(requestId uint32, messages [][]byte) *idempotentReplayResponse {
+	res := &idempotentReplayResponse{messages: messages}
+	res.requestId = requestId
+	return res
+}
+
+func (this *idempotentReplayResponse) getResponsStatus() responseStatusType {
+	return responseStatusOk
+}
+
+func (this *idempotentReplayResponse) toNetworkReadyJSON() ([]byte, bool) {
+	message := make([]byte, len(this.messages[this.index]))
+	copy(message, this.messages[this.index])
+	var header netHeader
+	header.readFrom(message)
+	header.RequestId = this.requestId
+	header.writeTo(message)
+	this.index++
+	return message, this.index < len(this.messages)
+}
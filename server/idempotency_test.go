@@ -0,0 +1,77 @@
+/* Copyright (C) 2014 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func validateReplay(t *testing.T, res response, requestId uint32, expected []byte) {
+	replay, ok := res.(*idempotentReplayResponse)
+	if !ok {
+		t.Fatal("expected idempotentReplayResponse")
+	}
+	msg, more := replay.toNetworkReadyJSON()
+	var header netHeader
+	header.readFrom(msg)
+	if more || header.RequestId != requestId || string(msg[_HEADER_SIZE:]) != string(expected[_HEADER_SIZE:]) {
+		t.Error("unexpected replay", header.RequestId, string(msg[_HEADER_SIZE:]))
+	}
+}
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Now()
+	cache := newIdempotencyCache(time.Second)
+	original := newOkResponse("insert")
+	original.setRequestId(1)
+	msg, more := original.toNetworkReadyJSON()
+	// first request is executed
+	if res, retry := cache.begin("key1", 1, now); res != nil || retry {
+		t.Error("expected first request to be executed")
+	}
+	// retry before the original response is written waits for it
+	if res, retry := cache.begin("key1", 2, now); res != nil || !retry {
+		t.Error("expected retry to wait for the original response")
+	}
+	// unrelated responses are not recorded
+	probe, _ := newProbeResponse().toNetworkReadyJSON()
+	if replays := cache.record(probe, false, now); replays != nil {
+		t.Error("expected no replays")
+	}
+	replays := cache.record(msg, more, now)
+	if len(replays) != 1 {
+		t.Fatal("expected 1 replay but got", len(replays))
+	}
+	validateReplay(t, replays[0], 2, msg)
+	// retry after the original response is answered immediately
+	res, retry := cache.begin("key1", 3, now.Add(time.Millisecond*500))
+	if !retry {
+		t.Error("expected retry")
+	}
+	validateReplay(t, res, 3, msg)
+	// key expires after the window
+	if res, retry := cache.begin("key1", 4, now.Add(time.Second)); res != nil || retry {
+		t.Error("expected expired key to be executed")
+	}
+	// disabled cache executes every request
+	cache = newIdempotencyCache(0)
+	cache.begin("key1", 1, now)
+	if res, retry := cache.begin("key1", 2, now); res != nil || retry {
+		t.Error("expected disabled cache to execute retry")
+	}
+}
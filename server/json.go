@@ -139,7 +139,13 @@ func (this *JSONBuilder) getNetworkBytes(requestId uint32) []byte {
 	var header netHeader
 	header.MessageSize = uint32(len(bytes)) - uint32(_HEADER_SIZE)
 	header.RequestId = requestId
-	header.writeTo(bytes)
+	if err := header.writeTo(bytes); err != nil {
+		// message that does not fit the header would corrupt the stream
+		logWarn("failed to send response:", err.Error())
+		res := newErrorResponseWithCode(errorCodeLimitExceeded, "response is too large")
+		res.requestId = requestId
+		bytes, _ = res.toNetworkReadyJSON()
+	}
 	return bytes
 }
 
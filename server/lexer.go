@@ -17,7 +17,11 @@
 package server
 
 import (
+	"encoding/base64"
+	encodinghex "encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -26,46 +30,92 @@ import (
 type tokenType uint8
 
 const (
-	tokenTypeError                   tokenType = iota // error occurred
-	tokenTypeEOF                                      // last token
-	tokenTypeCmdStatus                                // status
-	tokenTypeCmdStop                                  // stop
-	tokenTypeCmdClose                                 // close
-	tokenTypeSqlTable                                 // table name
-	tokenTypeSqlColumn                                // column name
-	tokenTypeSqlInsert                                // insert
-	tokenTypeSqlInto                                  // into
-	tokenTypeSqlUpdate                                // update
-	tokenTypeSqlSet                                   // set
-	tokenTypeSqlDelete                                // delete
-	tokenTypeSqlFrom                                  // from
-	tokenTypeSqlSelect                                // select
-	tokenTypeSqlSubscribe                             // subscribe
-	tokenTypeSqlUnsubscribe                           // unsubscribe
-	tokenTypeSqlSkip                                  // skip
-	tokenTypeSqlWhere                                 // where
-	tokenTypeSqlValues                                // values
-	tokenTypeSqlStar                                  // *
-	tokenTypeSqlEqual                                 // =
-	tokenTypeSqlLeftParenthesis                       // (
-	tokenTypeSqlRightParenthesis                      // )
-	tokenTypeSqlComma                                 // ,
-	tokenTypeSqlValue                                 // 'some string' string or continuous sequence of chars delimited by WHITE SPACE | ' | , | ( | )
-	tokenTypeSqlValueWithSingleQuote                  // '' becomes ' inside the string, parser will need to replace the string
-	tokenTypeSqlKey                                   // key
-	tokenTypeSqlTag                                   // tag
-	tokenTypeSqlStream                                // stream
-	tokenTypeSqlPush                                  // push
-	tokenTypeSqlPop                                   // pop
-	tokenTypeSqlPeek                                  // peek
-	tokenTypeSqlBack                                  // back
-	tokenTypeSqlFront                                 // front
-	tokenTypeSqlReturning                             // returning
-	tokenTypeSqlTopic                                 // topic
-	tokenTypeCmdMysql                                 // mysql
-	tokenTypeCmdConnect                               // connect
-	tokenTypeCmdDisconnect                            // disconnect
-	tokenTypeCmdTables                                // tables
+	tokenTypeError               tokenType = iota // error occurred
+	tokenTypeEOF                                  // last token
+	tokenTypeCmdStatus                            // status
+	tokenTypeCmdStop                              // stop
+	tokenTypeCmdClose                             // close
+	tokenTypeSqlTable                             // table name
+	tokenTypeSqlColumn                            // column name
+	tokenTypeSqlInsert                            // insert
+	tokenTypeSqlInto                              // into
+	tokenTypeSqlUpdate                            // update
+	tokenTypeSqlSet                               // set
+	tokenTypeSqlDelete                            // delete
+	tokenTypeSqlFrom                              // from
+	tokenTypeSqlSelect                            // select
+	tokenTypeSqlSubscribe                         // subscribe
+	tokenTypeSqlUnsubscribe                       // unsubscribe
+	tokenTypeSqlSkip                              // skip
+	tokenTypeSqlWhere                             // where
+	tokenTypeSqlValues                            // values
+	tokenTypeSqlStar                              // *
+	tokenTypeSqlEqual                             // =
+	tokenTypeSqlLeftParenthesis                   // (
+	tokenTypeSqlRightParenthesis                  // )
+	tokenTypeSqlComma                             // ,
+	tokenTypeSqlValue                             // 'some string' string or continuous sequence of chars delimited by WHITE SPACE | ' | , | ( | )
+	tokenTypeSqlKey                               // key
+	tokenTypeSqlTag                               // tag
+	tokenTypeSqlStream                            // stream
+	tokenTypeSqlPush                              // push
+	tokenTypeSqlPop                               // pop
+	tokenTypeSqlPeek                              // peek
+	tokenTypeSqlBack                              // back
+	tokenTypeSqlFront                             // front
+	tokenTypeSqlReturning                         // returning
+	tokenTypeSqlTopic                             // topic
+	tokenTypeCmdMysql                             // mysql
+	tokenTypeCmdConnect                           // connect
+	tokenTypeCmdDisconnect                        // disconnect
+	tokenTypeCmdTables                            // tables
+	tokenTypeSqlLimit                             // limit
+	tokenTypeSqlOffset                            // offset
+	tokenTypeSqlTruncate                          // truncate
+	tokenTypeSqlDistinct                          // distinct
+	tokenTypeSqlBetween                           // between
+	tokenTypeSqlAnd                               // and
+	tokenTypeSqlRange                             // range
+	tokenTypeSqlCount                             // count(*)
+	tokenTypeSqlGroup                             // group
+	tokenTypeSqlBy                                // by
+	tokenTypeSqlUpsert                            // upsert
+	tokenTypeSqlCreate                            // create
+	tokenTypeSqlColumnType                        // int float string bool
+	tokenTypeSqlNot                               // not
+	tokenTypeCmdShow                              // show
+	tokenTypeSqlAs                                // as
+	tokenTypeCmdPing                              // ping
+	tokenTypeSqlMulti                             // multi
+	tokenTypeSqlOrder                             // order
+	tokenTypeSqlOrderDirection                    // asc desc
+	tokenTypeCmdBegin                             // begin
+	tokenTypeCmdCommit                            // commit
+	tokenTypeCmdRollback                          // rollback
+	tokenTypeSqlArithmetic                        // + -
+	tokenTypeSqlColumnTable                       // table qualifier of column name
+	tokenTypeSqlCopy                              // copy
+	tokenTypeSqlOr                                // or
+	tokenTypeSqlHaving                            // having
+	tokenTypeSqlDelta                             // delta
+	tokenTypeSqlGreater                           // >
+	tokenTypeSqlDescribe                          // describe
+	tokenTypeSqlSave                              // save
+	tokenTypeSqlLoad                              // load
+	tokenTypeSqlTo                                // to
+	tokenTypeSqlReplace                           // replace
+	tokenTypeSqlConcat                            // ||
+	tokenTypeCmdSet                               // set
+	tokenTypeCmdSession                           // session
+	tokenTypeSqlBool                              // true or false
+	tokenTypeSqlAll                               // all
+	tokenTypeSqlWith                              // with
+	tokenTypeSqlTtl                               // ttl
+	tokenTypeSqlCollate                           // collate
+	tokenTypeSqlIn                                // in
+	tokenTypeSqlIf                                // if
+	tokenTypeSqlExists                            // exists
+	tokenTypeSqlSince                             // since
 )
 
 // String converts tokenType value to a string.
@@ -121,8 +171,6 @@ func (typ tokenType) String() string {
 		return "tokenTypeSqlComma"
 	case tokenTypeSqlValue:
 		return "tokenTypeSqlValue"
-	case tokenTypeSqlValueWithSingleQuote:
-		return "tokenTypeSqlValueWithSingleQuote"
 	case tokenTypeSqlKey:
 		return "tokenTypeSqlKey"
 	case tokenTypeSqlTag:
@@ -149,6 +197,100 @@ func (typ tokenType) String() string {
 		return "tokenTypeCmdDisconnect"
 	case tokenTypeCmdTables:
 		return "tokenTypeCmdTables"
+	case tokenTypeSqlLimit:
+		return "tokenTypeSqlLimit"
+	case tokenTypeSqlOffset:
+		return "tokenTypeSqlOffset"
+	case tokenTypeSqlTruncate:
+		return "tokenTypeSqlTruncate"
+	case tokenTypeSqlDistinct:
+		return "tokenTypeSqlDistinct"
+	case tokenTypeSqlBetween:
+		return "tokenTypeSqlBetween"
+	case tokenTypeSqlAnd:
+		return "tokenTypeSqlAnd"
+	case tokenTypeSqlRange:
+		return "tokenTypeSqlRange"
+	case tokenTypeSqlCount:
+		return "tokenTypeSqlCount"
+	case tokenTypeSqlGroup:
+		return "tokenTypeSqlGroup"
+	case tokenTypeSqlBy:
+		return "tokenTypeSqlBy"
+	case tokenTypeSqlUpsert:
+		return "tokenTypeSqlUpsert"
+	case tokenTypeSqlCreate:
+		return "tokenTypeSqlCreate"
+	case tokenTypeSqlColumnType:
+		return "tokenTypeSqlColumnType"
+	case tokenTypeSqlNot:
+		return "tokenTypeSqlNot"
+	case tokenTypeCmdShow:
+		return "tokenTypeCmdShow"
+	case tokenTypeSqlAs:
+		return "tokenTypeSqlAs"
+	case tokenTypeCmdPing:
+		return "tokenTypeCmdPing"
+	case tokenTypeSqlMulti:
+		return "tokenTypeSqlMulti"
+	case tokenTypeSqlOrder:
+		return "tokenTypeSqlOrder"
+	case tokenTypeSqlOrderDirection:
+		return "tokenTypeSqlOrderDirection"
+	case tokenTypeCmdBegin:
+		return "tokenTypeCmdBegin"
+	case tokenTypeCmdCommit:
+		return "tokenTypeCmdCommit"
+	case tokenTypeCmdRollback:
+		return "tokenTypeCmdRollback"
+	case tokenTypeSqlArithmetic:
+		return "tokenTypeSqlArithmetic"
+	case tokenTypeSqlColumnTable:
+		return "tokenTypeSqlColumnTable"
+	case tokenTypeSqlCopy:
+		return "tokenTypeSqlCopy"
+	case tokenTypeSqlOr:
+		return "tokenTypeSqlOr"
+	case tokenTypeSqlHaving:
+		return "tokenTypeSqlHaving"
+	case tokenTypeSqlDelta:
+		return "tokenTypeSqlDelta"
+	case tokenTypeSqlGreater:
+		return "tokenTypeSqlGreater"
+	case tokenTypeSqlDescribe:
+		return "tokenTypeSqlDescribe"
+	case tokenTypeSqlSave:
+		return "tokenTypeSqlSave"
+	case tokenTypeSqlLoad:
+		return "tokenTypeSqlLoad"
+	case tokenTypeSqlTo:
+		return "tokenTypeSqlTo"
+	case tokenTypeSqlReplace:
+		return "tokenTypeSqlReplace"
+	case tokenTypeSqlConcat:
+		return "tokenTypeSqlConcat"
+	case tokenTypeCmdSet:
+		return "tokenTypeCmdSet"
+	case tokenTypeCmdSession:
+		return "tokenTypeCmdSession"
+	case tokenTypeSqlBool:
+		return "tokenTypeSqlBool"
+	case tokenTypeSqlAll:
+		return "tokenTypeSqlAll"
+	case tokenTypeSqlWith:
+		return "tokenTypeSqlWith"
+	case tokenTypeSqlTtl:
+		return "tokenTypeSqlTtl"
+	case tokenTypeSqlCollate:
+		return "tokenTypeSqlCollate"
+	case tokenTypeSqlIn:
+		return "tokenTypeSqlIn"
+	case tokenTypeSqlIf:
+		return "tokenTypeSqlIf"
+	case tokenTypeSqlExists:
+		return "tokenTypeSqlExists"
+	case tokenTypeSqlSince:
+		return "tokenTypeSqlSince"
 	}
 	return "not implemented"
 }
@@ -231,9 +373,12 @@ type stateFn func(*lexer) stateFn
 
 // Emits an error token and terminates the scan
 // by passing back a nil pointer that will be the next state
-// terminating lexer.run function
+// terminating lexer.run function.
+// The message is prefixed with the byte offset of the offending item
+// in the form: syntax error at position 14: unexpected 'x'
 func (this *lexer) errorToken(format string, args ...interface{}) stateFn {
-	this.err = fmt.Sprintf(format, args...)
+	this.err = fmt.Sprintf("syntax error at position %d: ", this.start) + fmt.Sprintf(format, args...)
+	this.ignore()
 	this.tokens.Consume(&token{tokenTypeError, this.err})
 	return nil
 }
@@ -255,6 +400,11 @@ func (this *lexer) current() string {
 	return str
 }
 
+// Returns the current item without consuming it.
+func (this *lexer) span() string {
+	return this.input[this.start:this.pos]
+}
+
 // Returns the next rune in the input.
 func (this *lexer) next() (rune int32) {
 	if this.pos >= len(this.input) {
@@ -266,6 +416,12 @@ func (this *lexer) next() (rune int32) {
 	return rune
 }
 
+// Returns the next rune in the input converted to lower case.
+// Used to match keywords case insensitively.
+func (this *lexer) nextLower() int32 {
+	return unicode.ToLower(this.next())
+}
+
 // Returns whether end was reached in the input.
 func (this *lexer) end() bool {
 	if this.pos >= len(this.input) {
@@ -291,6 +447,11 @@ func (this *lexer) peek() int32 {
 	return rune
 }
 
+// Returns but does not consume the next rune in the input converted to lower case.
+func (this *lexer) peekLower() int32 {
+	return unicode.ToLower(this.peek())
+}
+
 // Determines if rune is valid unicode space character or 0.
 func isWhiteSpace(rune int32) bool {
 	return (unicode.IsSpace(rune) || rune == 0)
@@ -302,6 +463,7 @@ func (this *lexer) scanTillWhiteSpace() {
 	for rune := this.next(); !isWhiteSpace(rune); rune = this.next() {
 		// void
 	}
+	this.backup()
 }
 
 // Skips white space characters in the input.
@@ -321,7 +483,7 @@ func (this *lexer) match(str string, skip int) bool {
 			skip--
 			continue
 		}
-		if rune != this.next() {
+		if rune != this.nextLower() {
 			done = false
 		}
 	}
@@ -339,7 +501,7 @@ func (this *lexer) tryMatch(val string) bool {
 	i := 0
 	for _, rune := range val {
 		i++
-		if rune != this.next() {
+		if rune != this.nextLower() {
 			for ; i > 0; i-- {
 				this.backup()
 			}
@@ -356,22 +518,62 @@ func (this *lexer) lexMatch(typ tokenType, value string, skip int, fn stateFn) s
 		this.emit(typ)
 		return fn
 	}
-	return this.errorToken("Unexpected token:" + this.current())
+	return this.errorToken("unexpected '%s'", this.span())
 }
 
 // lexSqlIndentifier scans input for valid sql identifier emitting the token on success
 // and returning passed state function.
+// Column name can be qualified with table name as in table.column,
+// the qualifier is emitted as separate token preceding the column.
 func (this *lexer) lexSqlIdentifier(typ tokenType, fn stateFn) stateFn {
 	this.skipWhiteSpaces()
+	if !this.scanSqlIdentifier() {
+		return this.errorToken("identifier must begin with a letter but got '%s'", this.span())
+	}
+	if typ == tokenTypeSqlColumn && this.peek() == '.' {
+		this.emit(tokenTypeSqlColumnTable)
+		this.next()
+		this.ignore()
+		if !this.scanSqlIdentifier() {
+			return this.errorToken("identifier must begin with a letter but got '%s'", this.span())
+		}
+	}
+	this.emit(typ)
+	return fn
+}
+
+// scanSqlIdentifier advances the input past valid sql identifier.
+// Returns false if the identifier does not begin with a letter.
+func (this *lexer) scanSqlIdentifier() bool {
 	// first rune has to be valid unicode letter
 	if !unicode.IsLetter(this.next()) {
-		return this.errorToken("identifier must begin with a letter " + this.current())
+		return false
 	}
-	for rune := this.next(); unicode.IsLetter(rune) || unicode.IsDigit(rune); rune = this.next() {
+	for rune := this.next(); isIdentifierRune(rune); rune = this.next() {
 
 	}
 	this.backup()
-	this.emit(typ)
+	return true
+}
+
+// isIdentifierRune returns true for letters, digits and underscore.
+func isIdentifierRune(rune rune) bool {
+	return unicode.IsLetter(rune) || unicode.IsDigit(rune) || rune == '_'
+}
+
+// lexSqlTableName scans input for table name or table pattern
+// where % matches any sequence of characters.
+func (this *lexer) lexSqlTableName(fn stateFn) stateFn {
+	this.skipWhiteSpaces()
+	// first rune has to be valid unicode letter or %
+	if rune := this.next(); !unicode.IsLetter(rune) && rune != '%' {
+		return this.errorToken("identifier must begin with a letter but got '%s'", this.span())
+	}
+	for rune := this.next(); isIdentifierRune(rune) || rune == '%'; rune = this.next() {
+
+	}
+	this.backup()
+	this.emit(tokenTypeSqlTable)
 	return fn
 }
 
@@ -393,52 +595,129 @@ func (this *lexer) lexSqlValue(fn stateFn) stateFn {
 	if this.end() {
 		return this.errorToken("expected value but go eof")
 	}
+	// blob literals
+	pos := this.pos
+	if this.tryMatch("x'") {
+		return this.lexSqlBlobValue(encodinghex.DecodeString, fn)
+	}
+	if this.tryMatch("base64'") {
+		return this.lexSqlBlobValue(base64.StdEncoding.DecodeString, fn)
+	}
+	this.pos = pos
 	rune := this.next()
-	typ := tokenTypeSqlValue
 	// quoted string
 	if rune == '\'' {
+		return this.lexSqlQuotedValue(fn)
+	}
+	// value
+	for rune = this.next(); !isWhiteSpace(rune) && rune != ',' && rune != ')'; rune = this.next() {
+	}
+	this.backup()
+	if base := radixLiteralBase(this.span()); base != 0 {
+		return this.lexSqlRadixValue(base, fn)
+	}
+	// unquoted true and false are boolean literals stored in lower case
+	if val := strings.ToLower(this.span()); val == "true" || val == "false" {
+		this.tokens.Consume(&token{tokenTypeSqlBool, val})
 		this.ignore()
-		for rune = this.next(); ; rune = this.next() {
-			if rune == '\'' {
-				if !this.end() {
-					rune = this.next()
-					// check for '''
-					if rune == '\'' {
-						typ = tokenTypeSqlValueWithSingleQuote
-					} else {
-						// since we read lookahead after single quote that ends the string
-						// for lookahead
-						this.backup()
-						// for single quote which is not part of the value
-						this.backup()
-						this.emit(typ)
-						// now ignore that single quote
-						this.next()
-						this.ignore()
-						//
-						return fn
-					}
-				} else {
-					// at the very end
-					this.backup()
-					this.emit(typ)
-					this.next()
-					return fn
-				}
+		return fn
+	}
+	this.emit(tokenTypeSqlValue)
+	return fn
+}
+
+// Escape sequences recognized inside single quoted strings.
+// Backslash followed by any other rune is kept as is.
+var lexEscapes = map[int32]int32{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'\\': '\\',
+	'\'': '\'',
+}
+
+// lexSqlQuotedValue scans the rest of single quoted string and emits
+// the value token with doubled quotes and backslash escape sequences decoded.
+func (this *lexer) lexSqlQuotedValue(fn stateFn) stateFn {
+	var val []byte
+	for {
+		rune := this.next()
+		switch {
+		case rune == 0 && this.width == 0:
+			return this.errorToken("string was not delimited")
+		case rune == '\'':
+			// '' becomes ' inside the string
+			if this.peek() != '\'' {
+				this.tokens.Consume(&token{tokenTypeSqlValue, string(val)})
+				this.ignore()
+				return fn
 			}
-			if rune == 0 {
-				return this.errorToken("string was not delimited")
+			this.next()
+		case rune == '\\':
+			if esc, ok := lexEscapes[this.next()]; ok {
+				val = utf8.AppendRune(val, esc)
+			} else {
+				this.backup()
+				val = append(val, '\\')
 			}
+			continue
+		}
+		val = append(val, this.input[this.pos-this.width:this.pos]...)
+	}
+}
+
+// Returns 16 for hexadecimal 0x and 2 for binary 0b literals, otherwise 0.
+func radixLiteralBase(val string) int {
+	if len(val) < 2 || val[0] != '0' {
+		return 0
+	}
+	switch val[1] {
+	case 'x', 'X':
+		return 16
+	case 'b', 'B':
+		return 2
+	}
+	return 0
+}
+
+// lexSqlRadixValue validates hexadecimal or binary literal and emits
+// the value token in canonical decimal form so that filters match
+// regardless of the radix used in the statement.
+func (this *lexer) lexSqlRadixValue(base int, fn stateFn) stateFn {
+	val := this.span()
+	num, err := strconv.ParseUint(val[2:], base, 64)
+	if err != nil {
+		return this.errorToken("invalid numeric literal '%s'", val)
+	}
+	this.tokens.Consume(&token{tokenTypeSqlValue, strconv.FormatUint(num, 10)})
+	this.ignore()
+	return fn
+}
+
+// lexSqlBlobValue scans the rest of x'hex' or base64'base64' blob literal and emits
+// the value token with the bytes in canonical base64 encoding, which is how blobs
+// are stored, indexed and returned to the client.
+// Blob literal is a part of the message, hence its size is limited by the maximum
+// message size: hex literal takes two and base64 literal four thirds bytes per blob byte.
+func (this *lexer) lexSqlBlobValue(decode func(string) ([]byte, error), fn stateFn) stateFn {
+	start := this.pos
+	for {
+		rune := this.next()
+		if rune == 0 && this.width == 0 {
+			return this.errorToken("blob literal was not delimited")
 		}
-		// value
-	} else {
-		for rune = this.next(); !isWhiteSpace(rune) && rune != ',' && rune != ')'; rune = this.next() {
+		if rune == '\'' {
+			break
 		}
-		this.backup()
-		this.emit(typ)
-		return fn
 	}
-	return nil
+	literal := this.input[start : this.pos-1]
+	bytes, err := decode(literal)
+	if err != nil {
+		return this.errorToken("invalid blob literal '%s'", literal)
+	}
+	this.tokens.Consume(&token{tokenTypeSqlValue, base64.StdEncoding.EncodeToString(bytes)})
+	this.ignore()
+	return fn
 }
 
 // Tries to match expected value returns next state function depending on the match.
@@ -454,6 +733,14 @@ func (this *lexer) lexTryMatch(typ tokenType, val string, fnMatch stateFn, fnNoM
 // WHERE sql where clause scan state functions.
 
 func lexSqlWhereColumn(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	// optional not prefix, column names such as notes are not keywords
+	pos := this.pos
+	if this.tryMatch("not") && isWhiteSpace(this.peek()) {
+		this.emit(tokenTypeSqlNot)
+		return lexSqlWhereColumn
+	}
+	this.pos = pos
 	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlWhereColumnEqual)
 }
 
@@ -463,12 +750,110 @@ func lexSqlWhereColumnEqual(this *lexer) stateFn {
 		this.emit(tokenTypeSqlEqual)
 		return lexSqlWhereColumnEqualValue
 	}
-	return this.errorToken("expected = ")
+	this.backup()
+	if this.peekLower() == 'b' {
+		return this.lexMatch(tokenTypeSqlBetween, "between", 0, lexSqlWhereBetweenFrom)
+	}
+	pos := this.pos
+	if this.tryMatch("in") && (isWhiteSpace(this.peek()) || this.peek() == '(') {
+		this.emit(tokenTypeSqlIn)
+		return lexSqlWhereInLeftParenthesis
+	}
+	this.pos = pos
+	return this.errorToken("expected =, between or in")
+}
+
+// Scans in (value, ...) list of values.
+func lexSqlWhereInLeftParenthesis(this *lexer) stateFn {
+	return this.lexSqlLeftParenthesis(lexSqlWhereInValue)
+}
+
+func lexSqlWhereInValue(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlWhereInCommaOrRightParenthesis)
+}
+
+func lexSqlWhereInCommaOrRightParenthesis(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.next() {
+	case ',':
+		this.emit(tokenTypeSqlComma)
+		return lexSqlWhereInValue
+	case ')':
+		this.emit(tokenTypeSqlRightParenthesis)
+		return lexSqlWhereAnd
+	}
+	return this.errorToken("expected , or ) ")
+}
+
+func lexSqlWhereBetweenFrom(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlWhereBetweenAnd)
+}
+
+func lexSqlWhereBetweenAnd(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlAnd, "and", 0, lexSqlWhereBetweenTo)
+}
+
+func lexSqlWhereBetweenTo(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlWhereOr)
 }
 
 func lexSqlWhereColumnEqualValue(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	return this.lexSqlValue(lexSqlReturning)
+	return this.lexSqlValue(lexSqlWhereCollate)
+}
+
+// Scans optional collate collation of equality condition.
+func lexSqlWhereCollate(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	pos := this.pos
+	if this.tryMatch("collate") && isWhiteSpace(this.peek()) {
+		this.emit(tokenTypeSqlCollate)
+		return lexSqlWhereCollation
+	}
+	this.pos = pos
+	return lexSqlWhereAnd(this)
+}
+
+func lexSqlWhereCollation(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlValue, lexSqlWhereAnd)
+}
+
+// Scans optional and version = value condition.
+func lexSqlWhereAnd(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	pos := this.pos
+	if this.tryMatch("and") && isWhiteSpace(this.peek()) {
+		this.emit(tokenTypeSqlAnd)
+		return lexSqlWhereVersion
+	}
+	this.pos = pos
+	return lexSqlWhereOr(this)
+}
+
+// Scans optional or followed by another condition.
+func lexSqlWhereOr(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	pos := this.pos
+	if this.tryMatch("or") && isWhiteSpace(this.peek()) {
+		this.emit(tokenTypeSqlOr)
+		return lexSqlWhereColumn
+	}
+	this.pos = pos
+	return lexSqlClause(this)
+}
+
+func lexSqlWhereVersion(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlWhereVersionEqual)
+}
+
+func lexSqlWhereVersionEqual(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() == '=' {
+		this.emit(tokenTypeSqlEqual)
+		return lexSqlClauseValue
+	}
+	return this.errorToken("expected = ")
 }
 
 func lexEof(this *lexer) stateFn {
@@ -485,7 +870,7 @@ func lexEof(this *lexer) stateFn {
 
 func lexSqlPushInto(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	switch this.next() {
+	switch this.nextLower() {
 	case 'b':
 		return this.lexMatch(tokenTypeSqlBack, "back", 1, lexSqlInsertInto)
 	case 'f':
@@ -516,7 +901,7 @@ func lexSqlInsertColumn(this *lexer) stateFn {
 
 func lexSqlInsertColumnCommaOrRightParenthesis(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	switch this.next() {
+	switch this.nextLower() {
 	case ',':
 		this.emit(tokenTypeSqlComma)
 		return lexSqlInsertColumn
@@ -542,17 +927,215 @@ func lexSqlInsertVal(this *lexer) stateFn {
 
 func lexSqlInsertValueCommaOrRigthParenthesis(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	switch this.next() {
+	switch this.nextLower() {
 	case ',':
 		this.emit(tokenTypeSqlComma)
 		return lexSqlInsertVal
 	case ')':
 		this.emit(tokenTypeSqlRightParenthesis)
-		return lexSqlReturning
+		return lexSqlInsertTtl
+	}
+	return this.errorToken("expected , or ) ")
+}
+
+// Scans optional ttl seconds clause.
+func lexSqlInsertTtl(this *lexer) stateFn {
+	return this.lexTryMatch(tokenTypeSqlTtl, "ttl", lexSqlInsertTtlValue, lexSqlReturning)
+}
+
+func lexSqlInsertTtlValue(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlReturning)
+}
+
+// COPY sql statement scan state functions.
+// Rows of bulk load follow the stream keyword as (value, ...) tuples.
+
+func lexSqlCopyInto(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlInto, "into", 0, lexSqlCopyTable)
+}
+
+func lexSqlCopyTable(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlTable, lexSqlCopyTableLeftParenthesis)
+}
+
+func lexSqlCopyTableLeftParenthesis(this *lexer) stateFn {
+	return this.lexSqlLeftParenthesis(lexSqlCopyColumn)
+}
+
+func lexSqlCopyColumn(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlCopyColumnCommaOrRightParenthesis)
+}
+
+func lexSqlCopyColumnCommaOrRightParenthesis(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.nextLower() {
+	case ',':
+		this.emit(tokenTypeSqlComma)
+		return lexSqlCopyColumn
+	case ')':
+		this.emit(tokenTypeSqlRightParenthesis)
+		return lexSqlCopyFrom
+	}
+	return this.errorToken("expected , or ) ")
+}
+
+func lexSqlCopyFrom(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlFrom, "from", 0, lexSqlCopyStream)
+}
+
+func lexSqlCopyStream(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlStream, "stream", 0, lexSqlCopyRow)
+}
+
+func lexSqlCopyRow(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.end() {
+		return nil
+	}
+	return this.lexSqlLeftParenthesis(lexSqlCopyVal)
+}
+
+func lexSqlCopyVal(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlCopyValueCommaOrRightParenthesis)
+}
+
+func lexSqlCopyValueCommaOrRightParenthesis(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.nextLower() {
+	case ',':
+		this.emit(tokenTypeSqlComma)
+		return lexSqlCopyVal
+	case ')':
+		this.emit(tokenTypeSqlRightParenthesis)
+		return lexSqlCopyRow
 	}
 	return this.errorToken("expected , or ) ")
 }
 
+// group by limit offset
+
+func lexSqlClause(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.peekLower() {
+	case 'g':
+		return this.lexMatch(tokenTypeSqlGroup, "group", 0, lexSqlGroupBy)
+	case 'h':
+		return this.lexMatch(tokenTypeSqlHaving, "having", 0, lexSqlHavingDelta)
+	case 'l':
+		return this.lexMatch(tokenTypeSqlLimit, "limit", 0, lexSqlClauseValue)
+	case 'o':
+		this.next()
+		if this.peekLower() == 'r' {
+			return this.lexMatch(tokenTypeSqlOrder, "order", 1, lexSqlOrderBy)
+		}
+		return this.lexMatch(tokenTypeSqlOffset, "offset", 1, lexSqlClauseValue)
+	case 's':
+		return this.lexMatch(tokenTypeSqlSince, "since", 0, lexSqlClauseValue)
+	}
+	return lexSqlReturning(this)
+}
+
+// having delta(column) > value [and ...]
+
+func lexSqlHavingDelta(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if !this.tryMatch("delta") {
+		return this.errorToken("expected delta")
+	}
+	this.emit(tokenTypeSqlDelta)
+	return this.lexSqlLeftParenthesis(lexSqlHavingColumn)
+}
+
+func lexSqlHavingColumn(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlHavingRightParenthesis)
+}
+
+func lexSqlHavingRightParenthesis(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() != ')' {
+		return this.errorToken("expected ) ")
+	}
+	this.emit(tokenTypeSqlRightParenthesis)
+	return lexSqlHavingGreater
+}
+
+func lexSqlHavingGreater(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() != '>' {
+		return this.errorToken("expected > ")
+	}
+	this.emit(tokenTypeSqlGreater)
+	return lexSqlHavingValue
+}
+
+func lexSqlHavingValue(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlHavingAnd)
+}
+
+func lexSqlHavingAnd(this *lexer) stateFn {
+	return this.lexTryMatch(tokenTypeSqlAnd, "and", lexSqlHavingDelta, lexSqlClause)
+}
+
+func lexSqlOrderBy(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlBy, "by", 0, lexSqlOrderByColumn)
+}
+
+func lexSqlOrderByColumn(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlOrderByDirection)
+}
+
+// Scans optional asc or desc order direction.
+func lexSqlOrderByDirection(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	for _, direction := range []string{"asc", "desc"} {
+		if this.tryMatch(direction) {
+			if this.end() || isWhiteSpace(this.peek()) || this.peek() == ',' {
+				this.emit(tokenTypeSqlOrderDirection)
+				return lexSqlOrderByCommaOrClause
+			}
+			this.pos = this.start
+		}
+	}
+	return lexSqlOrderByCommaOrClause(this)
+}
+
+func lexSqlOrderByCommaOrClause(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() == ',' {
+		this.emit(tokenTypeSqlComma)
+		return lexSqlOrderByColumn
+	}
+	this.backup()
+	return lexSqlClause(this)
+}
+
+func lexSqlGroupBy(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlBy, "by", 0, lexSqlGroupByColumn)
+}
+
+func lexSqlGroupByColumn(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlGroupByCommaOrClause)
+}
+
+func lexSqlGroupByCommaOrClause(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() == ',' {
+		this.emit(tokenTypeSqlComma)
+		return lexSqlGroupByColumn
+	}
+	this.backup()
+	return lexSqlClause(this)
+}
+
+func lexSqlClauseValue(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlClause)
+}
+
 // returning
 
 func lexSqlReturning(this *lexer) stateFn {
@@ -593,7 +1176,57 @@ func lexSqlReturningCommaOrEnd(this *lexer) stateFn {
 
 func lexSqlSelectColumn(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlSelectColumnCommaOrFrom)
+	if this.tryMatch("count(") {
+		return lexSqlSelectCount
+	}
+	return lexSqlSelectOperand(this)
+}
+
+// Scans column or quoted string operand of select column expression.
+func lexSqlSelectOperand(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.peek() == '\'' {
+		return this.lexSqlValue(lexSqlSelectConcat)
+	}
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlSelectConcat)
+}
+
+// Scans optional || operator that concatenates select column operands.
+func lexSqlSelectConcat(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.tryMatch("||") {
+		this.emit(tokenTypeSqlConcat)
+		return lexSqlSelectOperand
+	}
+	return lexSqlSelectColumnAs(this)
+}
+
+// Scans optional column alias.
+func lexSqlSelectColumnAs(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.tryMatch("as") {
+		if isWhiteSpace(this.peek()) {
+			this.emit(tokenTypeSqlAs)
+			return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlSelectColumnCommaOrFrom)
+		}
+		this.pos = this.start
+	}
+	return lexSqlSelectColumnCommaOrFrom(this)
+}
+
+// Scans remainder of count(*) aggregate.
+func lexSqlSelectCount(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() != '*' {
+		return this.errorToken("expected count(*)")
+	}
+	this.skipWhiteSpaces()
+	if this.next() != ')' {
+		return this.errorToken("expected count(*)")
+	}
+	this.tokens.Consume(&token{tokenTypeSqlCount, sqlCountStar})
+	this.ignore()
+	return lexSqlSelectColumnAs
 }
 
 func lexSqlSelectColumnCommaOrFrom(this *lexer) stateFn {
@@ -610,12 +1243,35 @@ func lexSqlSelectStar(this *lexer) stateFn {
 	this.skipWhiteSpaces()
 	if this.next() == '*' {
 		this.emit(tokenTypeSqlStar)
-		return lexSqlFrom
+		// * can be followed by more columns
+		return lexSqlSelectColumnCommaOrFrom
 	}
 	this.backup()
 	return lexSqlSelectColumn(this)
 }
 
+func lexSqlSelectDistinct(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.tryMatch("distinct") {
+		if isWhiteSpace(this.peek()) {
+			this.emit(tokenTypeSqlDistinct)
+			return lexSqlSelectDistinctColumn
+		}
+		// column name that begins with distinct
+		this.pos = this.start
+	}
+	return lexSqlSelectStar(this)
+}
+
+func lexSqlSelectDistinctColumn(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.peek() == '*' {
+		this.next()
+		return this.errorToken("distinct requires column names but got '%s'", this.span())
+	}
+	return lexSqlSelectColumn(this)
+}
+
 func lexSqlPopFrom(this *lexer) stateFn {
 	this.skipWhiteSpaces()
 	// from
@@ -695,6 +1351,22 @@ func lexSqlColumnEqual(this *lexer) stateFn {
 
 func lexSqlColumnEqualValue(this *lexer) stateFn {
 	this.skipWhiteSpaces()
+	return this.lexSqlValue(lexSqlArithmetic)
+}
+
+// Scans optional + or - operator of col = col + number assignment.
+func lexSqlArithmetic(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.next() {
+	case '+', '-':
+		this.emit(tokenTypeSqlArithmetic)
+		return lexSqlArithmeticValue
+	}
+	this.backup()
+	return lexSqlCommaOrWhere
+}
+
+func lexSqlArithmeticValue(this *lexer) stateFn {
 	return this.lexSqlValue(lexSqlCommaOrWhere)
 }
 
@@ -716,14 +1388,22 @@ func lexSqlFrom(this *lexer) stateFn {
 }
 
 func lexSqlFromTable(this *lexer) stateFn {
+	return this.lexSqlTableName(lexSqlFromInto)
+}
+
+func lexSqlFromInto(this *lexer) stateFn {
+	return this.lexTryMatch(tokenTypeSqlInto, "into", lexSqlFromIntoTable, lexSqlWhere)
+}
+
+func lexSqlFromIntoTable(this *lexer) stateFn {
 	return this.lexSqlIdentifier(tokenTypeSqlTable, lexSqlWhere)
 }
 
 func lexSqlWhere(this *lexer) stateFn {
-	return this.lexTryMatch(tokenTypeSqlWhere, "where", lexSqlWhereColumn, lexSqlReturning)
+	return this.lexTryMatch(tokenTypeSqlWhere, "where", lexSqlWhereColumn, lexSqlClause)
 }
 
-// KEY and TAG sql statement scan state functions.
+// KEY, TAG and RANGE sql statement scan state functions.
 
 func lexSqlKeyTable(this *lexer) stateFn {
 	return this.lexSqlIdentifier(tokenTypeSqlTable, lexSqlKeyColumn)
@@ -733,6 +1413,152 @@ func lexSqlKeyColumn(this *lexer) stateFn {
 	return this.lexSqlIdentifier(tokenTypeSqlColumn, nil)
 }
 
+func lexSqlTagTable(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlTable, lexSqlTagColumn)
+}
+
+func lexSqlTagColumn(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlTagMulti)
+}
+
+// Scans optional multi keyword of multi value tag.
+func lexSqlTagMulti(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.end() {
+		return nil
+	}
+	return this.lexMatch(tokenTypeSqlMulti, "multi", 0, lexEof)
+}
+
+// TRUNCATE sql statement scan state functions.
+
+func lexSqlTruncateTable(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if !this.match("table", 0) {
+		return this.errorToken("expected table keyword but got '%s'", this.span())
+	}
+	this.ignore()
+	return lexSqlTruncateTableName
+}
+
+func lexSqlTruncateTableName(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlTable, lexEof)
+}
+
+// CREATE sql statement scan state functions.
+
+func lexSqlCreateTable(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if !this.match("table", 0) {
+		return this.errorToken("expected table keyword but got '%s'", this.span())
+	}
+	this.ignore()
+	return lexSqlCreateTableIf
+}
+
+// Scans optional if not exists condition.
+func lexSqlCreateTableIf(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	pos := this.pos
+	if this.tryMatch("if") && isWhiteSpace(this.peek()) {
+		this.emit(tokenTypeSqlIf)
+		return lexSqlCreateTableIfNot
+	}
+	this.pos = pos
+	return lexSqlCreateTableName
+}
+
+func lexSqlCreateTableIfNot(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlNot, "not", 0, lexSqlCreateTableIfExists)
+}
+
+func lexSqlCreateTableIfExists(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlExists, "exists", 0, lexSqlCreateTableName)
+}
+
+func lexSqlCreateTableName(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlTable, lexSqlCreateTableLeftParenthesis)
+}
+
+func lexSqlCreateTableLeftParenthesis(this *lexer) stateFn {
+	return this.lexSqlLeftParenthesis(lexSqlCreateColumn)
+}
+
+func lexSqlCreateColumn(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlCreateColumnType)
+}
+
+// Column type is optional, parser validates type names.
+func lexSqlCreateColumnType(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.peek() {
+	case ',', ')':
+		return lexSqlCreateColumnCommaOrRightParenthesis
+	}
+	return this.lexSqlIdentifier(tokenTypeSqlColumnType, lexSqlCreateColumnCommaOrRightParenthesis)
+}
+
+func lexSqlCreateColumnCommaOrRightParenthesis(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	switch this.next() {
+	case ',':
+		this.emit(tokenTypeSqlComma)
+		return lexSqlCreateColumn
+	case ')':
+		this.emit(tokenTypeSqlRightParenthesis)
+		return lexSqlCreateTableWith
+	}
+	return this.errorToken("expected , or ) ")
+}
+
+// Scans optional with id = format table option.
+func lexSqlCreateTableWith(this *lexer) stateFn {
+	return this.lexTryMatch(tokenTypeSqlWith, "with", lexSqlOptionName, lexEof)
+}
+
+// SAVE and LOAD
+
+func lexSqlSnapshotTable(fn stateFn) stateFn {
+	return func(this *lexer) stateFn {
+		this.skipWhiteSpaces()
+		if !this.match("table", 0) {
+			return this.errorToken("expected table keyword but got '%s'", this.span())
+		}
+		this.ignore()
+		return this.lexSqlIdentifier(tokenTypeSqlTable, fn)
+	}
+}
+
+func lexSqlSaveTo(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlTo, "to", 0, lexSqlSaveFile)
+}
+
+func lexSqlSaveFile(this *lexer) stateFn {
+	return this.lexSqlValue(lexEof)
+}
+
+func lexSqlLoadFrom(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeSqlFrom, "from", 0, lexSqlLoadFile)
+}
+
+func lexSqlLoadFile(this *lexer) stateFn {
+	return this.lexSqlValue(lexSqlLoadReplace)
+}
+
+func lexSqlLoadReplace(this *lexer) stateFn {
+	return this.lexTryMatch(tokenTypeSqlReplace, "replace", lexEof, lexEof)
+}
+
+// DESCRIBE
+
+func lexSqlDescribeTable(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlTable, lexEof)
+}
+
 // SUBSCRIBE
 
 func lexSqlSubscribeSkip(this *lexer) stateFn {
@@ -750,46 +1576,100 @@ func lexSqlSubscribe(this *lexer) stateFn {
 }
 
 func lexSqlTopic(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	// single identifier is a topic, otherwise watched columns are followed by from
+	rest := strings.TrimRightFunc(this.input[this.pos:], unicode.IsSpace)
+	if strings.IndexFunc(rest, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) >= 0 {
+		return lexSqlSelectColumn(this)
+	}
 	return this.lexSqlIdentifier(tokenTypeSqlTopic, nil)
 }
 
 // UNSUBSCRIBE
 
 func lexSqlUnsubscribeFrom(this *lexer) stateFn {
-	return lexSqlFrom(this)
+	return this.lexTryMatch(tokenTypeSqlAll, "all", lexEof, lexSqlFrom)
 }
 
 // END SQL
 
+// Scans optional tables argument of status command.
+func lexCmdStatusTables(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.end() {
+		return nil
+	}
+	return this.lexMatch(tokenTypeCmdTables, "tables", 0, lexEof)
+}
+
+// Scans tables argument of show command.
+func lexCmdShowTables(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeCmdTables, "tables", 0, lexEof)
+}
+
 // Helper function to process status stop start commands.
 func lexCommandST(this *lexer) stateFn {
-	switch this.next() {
+	switch this.nextLower() {
 	case 'r':
 		return this.lexMatch(tokenTypeSqlStream, "stream", 3, lexCommand)
 	case 'a':
-		return this.lexMatch(tokenTypeCmdStatus, "status", 3, nil)
+		return this.lexMatch(tokenTypeCmdStatus, "status", 3, lexCmdStatusTables)
 	case 'o':
 		return this.lexMatch(tokenTypeCmdStop, "stop", 3, nil)
 	}
-	return this.errorToken("Invalid command:" + this.current())
+	return this.errorToken("invalid command '%s'", this.span())
 }
 
-// Helper function to process select subscribe status stop start commands.
+// Scans session keyword of set session command.
+func lexCmdSetSession(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	return this.lexMatch(tokenTypeCmdSession, "session", 0, lexSqlOptionName)
+}
+
+// Scans name = value option of set session and create table statements.
+func lexSqlOptionName(this *lexer) stateFn {
+	return this.lexSqlIdentifier(tokenTypeSqlColumn, lexSqlOptionEqual)
+}
+
+func lexSqlOptionEqual(this *lexer) stateFn {
+	this.skipWhiteSpaces()
+	if this.next() != '=' {
+		return this.errorToken("expected =")
+	}
+	this.emit(tokenTypeSqlEqual)
+	return lexSqlOptionValue
+}
+
+func lexSqlOptionValue(this *lexer) stateFn {
+	return this.lexSqlValue(lexEof)
+}
+
+// Helper function to process select subscribe show status stop start set commands.
 func lexCommandS(this *lexer) stateFn {
-	switch this.next() {
+	switch this.nextLower() {
 	case 'e':
-		return this.lexMatch(tokenTypeSqlSelect, "select", 2, lexSqlSelectStar)
+		if this.peekLower() == 't' {
+			return this.lexMatch(tokenTypeCmdSet, "set", 2, lexCmdSetSession)
+		}
+		return this.lexMatch(tokenTypeSqlSelect, "select", 2, lexSqlSelectDistinct)
 	case 'u':
 		return this.lexMatch(tokenTypeSqlSubscribe, "subscribe", 2, lexSqlSubscribe)
 	case 't':
 		return lexCommandST(this)
+	case 'h':
+		return this.lexMatch(tokenTypeCmdShow, "show", 2, lexCmdShowTables)
+	case 'a':
+		return this.lexMatch(tokenTypeSqlSave, "save", 2, lexSqlSnapshotTable(lexSqlSaveTo))
 	}
-	return this.errorToken("Invalid command:" + this.current())
+	return this.errorToken("invalid command '%s'", this.span())
 }
 
-// Helper function to process push, pop, peek commands.
+// Helper function to process push, pop, peek, ping commands.
 func lexCommandP(this *lexer) stateFn {
-	switch this.next() {
+	switch this.nextLower() {
+	case 'i':
+		return this.lexMatch(tokenTypeCmdPing, "ping", 2, lexEof)
 	case 'u':
 		return this.lexMatch(tokenTypeSqlPush, "push", 2, lexSqlPushInto)
 	case 'o':
@@ -797,51 +1677,185 @@ func lexCommandP(this *lexer) stateFn {
 	case 'e':
 		return this.lexMatch(tokenTypeSqlPeek, "peek", 2, lexSqlPeekFrom)
 	}
-	return this.errorToken("Invalid command:" + this.current())
+	return this.errorToken("invalid command '%s'", this.span())
+}
+
+// Helper function to process tag, truncate commands.
+func lexCommandT(this *lexer) stateFn {
+	switch this.nextLower() {
+	case 'a':
+		return this.lexMatch(tokenTypeSqlTag, "tag", 2, lexSqlTagTable)
+	case 'r':
+		return this.lexMatch(tokenTypeSqlTruncate, "truncate", 2, lexSqlTruncateTable)
+	}
+	return this.errorToken("invalid command '%s'", this.span())
 }
 
 // Initial state function.
 func lexCommand(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	switch this.next() {
-	case 'u': // update unsubscribe
-		if this.next() == 'p' {
+	switch this.nextLower() {
+	case 'u': // update upsert unsubscribe
+		if this.nextLower() == 'p' {
+			if this.peekLower() == 's' {
+				return this.lexMatch(tokenTypeSqlUpsert, "upsert", 2, lexSqlInsertInto)
+			}
 			return this.lexMatch(tokenTypeSqlUpdate, "update", 2, lexSqlUpdateTable)
 		}
 		return this.lexMatch(tokenTypeSqlUnsubscribe, "unsubscribe", 2, lexSqlUnsubscribeFrom)
-	case 's': // select subscribe status stop start stream
+	case 's': // select subscribe show status stop start stream save set
 		return lexCommandS(this)
 	case 'i': // insert
 		return this.lexMatch(tokenTypeSqlInsert, "insert", 1, lexSqlInsertInto)
-	case 'd': // delete
-		return this.lexMatch(tokenTypeSqlDelete, "delete", 1, lexSqlFrom)
+	case 'd': // delete describe
+		this.next()
+		if this.peekLower() == 's' {
+			return this.lexMatch(tokenTypeSqlDescribe, "describe", 2, lexSqlDescribeTable)
+		}
+		return this.lexMatch(tokenTypeSqlDelete, "delete", 2, lexSqlFrom)
 	case 'k': // key
 		return this.lexMatch(tokenTypeSqlKey, "key", 1, lexSqlKeyTable)
-	case 't': // tag
-		return this.lexMatch(tokenTypeSqlTag, "tag", 1, lexSqlKeyTable)
-	case 'c': // close
+	case 't': // tag truncate
+		return lexCommandT(this)
+	case 'r': // range rollback
+		if this.peekLower() == 'o' {
+			return this.lexMatch(tokenTypeCmdRollback, "rollback", 1, lexEof)
+		}
+		return this.lexMatch(tokenTypeSqlRange, "range", 1, lexSqlKeyTable)
+	case 'c': // close create commit copy
+		switch this.peekLower() {
+		case 'r':
+			return this.lexMatch(tokenTypeSqlCreate, "create", 1, lexSqlCreateTable)
+		case 'o':
+			this.next()
+			if this.peekLower() == 'p' {
+				return this.lexMatch(tokenTypeSqlCopy, "copy", 2, lexSqlCopyInto)
+			}
+			return this.lexMatch(tokenTypeCmdCommit, "commit", 2, lexEof)
+		}
 		return this.lexMatch(tokenTypeCmdClose, "close", 1, nil)
-	case 'p': // pop, push, peek
+	case 'b': // begin
+		return this.lexMatch(tokenTypeCmdBegin, "begin", 1, lexEof)
+	case 'p': // pop, push, peek, ping
 		return lexCommandP(this)
 	case 'm': // mysql
 		return this.lexMatch(tokenTypeCmdMysql, "mysql", 1, lexCmdMysql)
+	case 'l': // load
+		return this.lexMatch(tokenTypeSqlLoad, "load", 1, lexSqlSnapshotTable(lexSqlLoadFrom))
 	}
-	return this.errorToken("Invalid command:" + this.current())
+	return this.errorToken("invalid command '%s'", this.span())
+}
+
+// Validates that the whole input is UTF-8 encoded before scanning the command.
+// Invalid bytes are reported with their position instead of being scanned as RuneError.
+func lexValidUTF8(this *lexer) stateFn {
+	for pos := 0; pos < len(this.input); {
+		rune, width := utf8.DecodeRuneInString(this.input[pos:])
+		if rune == utf8.RuneError && width == 1 {
+			this.start = pos
+			return this.errorToken("invalid UTF-8 byte 0x%02x", this.input[pos])
+		}
+		pos += width
+	}
+	return lexCommand
 }
 
 // Scans the input by executing state function untithis.
 // the state is nil
 func (this *lexer) run() {
-	for state := lexCommand; state != nil; {
+	for state := lexValidUTF8; state != nil; {
 		state = state(this)
 	}
 	this.emit(tokenTypeEOF)
 }
 
+// Splits the input into statements separated by ; outside of single quoted values.
+// As in lexSqlValue only ' that begins a value starts quoted value,
+// apostrophe inside unquoted value such as O'Brien is a part of the value.
+// Blank statements are skipped.
+func splitStatements(input string) []string {
+	var statements []string
+	quoted := false
+	start := 0
+	for idx := 0; idx < len(input); idx++ {
+		if quoted {
+			switch input[idx] {
+			case '\\':
+				// escaped rune never ends quoted value
+				idx++
+			case '\'':
+				// '' is escaped ' inside quoted value
+				if idx+1 < len(input) && input[idx+1] == '\'' {
+					idx++
+				} else {
+					quoted = false
+				}
+			}
+			continue
+		}
+		if input[idx] == ';' {
+			statements = appendStatement(statements, input[start:idx])
+			start = idx + 1
+			continue
+		}
+		if !beginsValue(input, idx) {
+			continue
+		}
+		if input[idx] == '\'' {
+			quoted = true
+		} else if size := blobPrefixSize(input[idx:]); size > 0 {
+			// blob literal ends at the next '
+			end := strings.IndexByte(input[idx+size:], '\'')
+			if end < 0 {
+				break
+			}
+			idx += size + end
+		}
+	}
+	return appendStatement(statements, input[start:])
+}
+
+// Returns true when the byte at idx can begin a value, that is when it follows
+// the beginning of the input, white space or a rune that precedes values.
+func beginsValue(input string, idx int) bool {
+	if idx == 0 {
+		return true
+	}
+	prev := rune(input[idx-1])
+	return isWhiteSpace(prev) || strings.ContainsRune("(,=>", prev)
+}
+
+// Returns size of x' or base64' prefix of blob literal, otherwise 0.
+func blobPrefixSize(input string) int {
+	for _, prefix := range []string{"x'", "base64'"} {
+		if len(input) >= len(prefix) && strings.EqualFold(input[:len(prefix)], prefix) {
+			return len(prefix)
+		}
+	}
+	return 0
+}
+
+func appendStatement(statements []string, stmt string) []string {
+	if strings.TrimSpace(stmt) == "" {
+		return statements
+	}
+	return append(statements, stmt)
+}
+
+// Removes optional ; terminating the statement.
+// Only single terminator is removed, multiple statements are split by splitStatements.
+func trimStatementTerminator(input string) string {
+	trimmed := strings.TrimRightFunc(input, unicode.IsSpace)
+	if strings.HasSuffix(trimmed, ";") {
+		return trimmed[:len(trimmed)-1]
+	}
+	return input
+}
+
 // Scans the input by running lexer.
 func lex(input string, tokens tokenConsumer) bool {
 	lexer := &lexer{
-		input:  input,
+		input:  trimStatementTerminator(input),
 		tokens: tokens,
 	}
 	lexer.run()
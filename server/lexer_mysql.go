@@ -23,19 +23,19 @@ func lexMysqlConnectAddress(this *lexer) stateFn {
 
 // Helper function to process subscribe, status.
 func lexMysqlCommandS(this *lexer) stateFn {
-	switch this.next() {
+	switch this.nextLower() {
 	case 'u':
 		return this.lexMatch(tokenTypeSqlSubscribe, "subscribe", 2, lexSqlSubscribe)
 	case 't':
 		return this.lexMatch(tokenTypeCmdStatus, "status", 2, nil)
 	}
-	return this.errorToken("Invalid command:" + this.current())
+	return this.errorToken("invalid command '%s'", this.span())
 }
 
 // Helper function to process mysql subscribe unsubscribe connect disconnect status tables commands.
 func lexCmdMysql(this *lexer) stateFn {
 	this.skipWhiteSpaces()
-	switch this.next() {
+	switch this.nextLower() {
 	case 's': // subscribe, status
 		return lexMysqlCommandS(this)
 	case 'u': // unsubscribe
@@ -47,5 +47,5 @@ func lexCmdMysql(this *lexer) stateFn {
 	case 't': // tables
 		return this.lexMatch(tokenTypeCmdTables, "tables", 1, nil)
 	}
-	return this.errorToken("Invalid command:" + this.current())
+	return this.errorToken("invalid command '%s'", this.span())
 }
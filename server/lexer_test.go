@@ -18,6 +18,7 @@ package server
 
 import "testing"
 import "fmt"
+import "reflect"
 
 // ignores consumed tokens useful in benchmark code
 type ignoreTokenConsumer struct {
@@ -74,6 +75,28 @@ func TestStatusCommand(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestShowTablesCommand(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" show tables ", &consumer)
+	expected := []token{
+		{tokenTypeCmdShow, "show"},
+		{tokenTypeCmdTables, "tables"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestStatusTablesCommand(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" status tables ", &consumer)
+	expected := []token{
+		{tokenTypeCmdStatus, "status"},
+		{tokenTypeCmdTables, "tables"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 // STOP
 func TestStopCommand(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
@@ -85,6 +108,48 @@ func TestStopCommand(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+// PING
+func TestPingCommand(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" ping ", &consumer)
+	expected := []token{
+		{tokenTypeCmdPing, "ping"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+// SET SESSION
+func TestSetSessionCommand(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" set session limit = 100 ", &consumer)
+	expected := []token{
+		{tokenTypeCmdSet, "set"},
+		{tokenTypeCmdSession, "session"},
+		{tokenTypeSqlColumn, "limit"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "100"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+// BEGIN COMMIT ROLLBACK
+func TestTransactionCommands(t *testing.T) {
+	for _, tok := range []token{
+		{tokenTypeCmdBegin, "begin"},
+		{tokenTypeCmdCommit, "commit"},
+		{tokenTypeCmdRollback, "rollback"}} {
+		consumer := chanTokenConsumer{channel: make(chan *token)}
+		go lex(" "+tok.val+" ", &consumer)
+		expected := []token{
+			tok,
+			{tokenTypeEOF, ""}}
+
+		validateTokens(t, expected, consumer.channel)
+	}
+}
+
 // CLOSE
 func TestCloseCommand(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
@@ -155,6 +220,29 @@ func TestSqlInsertStatement2(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestSqlInsertTtl(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" insert into cache (key) values (k1) ttl 60 returning id", &consumer)
+	expected := []token{
+		{tokenTypeSqlInsert, "insert"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "cache"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "key"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "values"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "k1"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlTtl, "ttl"},
+		{tokenTypeSqlValue, "60"},
+		{tokenTypeSqlReturning, "returning"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 func TestSqlInsertStatement3(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
 	go lex("insert into stocks (	ticker,bid, ask		 ) values (IBM, '34.43', 465.123) returning *", &consumer)
@@ -184,6 +272,118 @@ func TestSqlInsertStatement3(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestSqlBoolValues(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" insert into users (active, admin, note, flag) values (true, FALSE, 'true', truest)", &consumer)
+	expected := []token{
+		{tokenTypeSqlInsert, "insert"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "users"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "active"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "admin"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "note"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "flag"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "values"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlBool, "true"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlBool, "false"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "true"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "truest"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	where := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from users where active = true", &where)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "users"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "active"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlBool, "true"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, where.channel)
+}
+
+// COPY
+
+func TestSqlCopyStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex("copy into stocks (ticker, bid) from stream\n(IBM, 12)\n(MSFT, '37.5')\n", &consumer)
+	expected := []token{
+		{tokenTypeSqlCopy, "copy"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlStream, "stream"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "12"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "MSFT"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "37.5"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex("commit", &consumer2)
+	expected = []token{
+		{tokenTypeCmdCommit, "commit"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
+
+// UPSERT
+
+func TestSqlUpsertStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" upsert into stocks (ticker, bid) values (IBM, 12) returning *", &consumer)
+	expected := []token{
+		{tokenTypeSqlUpsert, "upsert"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "values"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "12"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlReturning, "returning"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 // DELETE
 func TestSqlDeleteStatement1(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
@@ -295,6 +495,99 @@ func TestSqlSelectStatement3(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestSqlSelectLimitOffset(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where ticker = IBM limit 50 offset 100", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlLimit, "limit"},
+		{tokenTypeSqlValue, "50"},
+		{tokenTypeSqlOffset, "offset"},
+		{tokenTypeSqlValue, "100"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectOrderBy(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from events order by id desc limit 50 offset 10", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "events"},
+		{tokenTypeSqlOrder, "order"},
+		{tokenTypeSqlBy, "by"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeSqlOrderDirection, "desc"},
+		{tokenTypeSqlLimit, "limit"},
+		{tokenTypeSqlValue, "50"},
+		{tokenTypeSqlOffset, "offset"},
+		{tokenTypeSqlValue, "10"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from events order by bid", &consumer2)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "events"},
+		{tokenTypeSqlOrder, "order"},
+		{tokenTypeSqlBy, "by"},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer2.channel)
+	//
+	columns := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from events order by exchange asc,price DESC , ascending limit 5", &columns)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "events"},
+		{tokenTypeSqlOrder, "order"},
+		{tokenTypeSqlBy, "by"},
+		{tokenTypeSqlColumn, "exchange"},
+		{tokenTypeSqlOrderDirection, "asc"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlOrderDirection, "DESC"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "ascending"},
+		{tokenTypeSqlLimit, "limit"},
+		{tokenTypeSqlValue, "5"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, columns.channel)
+}
+
+func TestSqlSelectOffset(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks offset 10", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlOffset, "offset"},
+		{tokenTypeSqlValue, "10"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 // SUBSCRIBE
 func TestSqlSubscribeStatement1(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
@@ -344,6 +637,112 @@ func TestSqlSubscribeStatement3(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestSqlSubscribeColumns(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" subscribe price, qty from stocks where ticker = IBM", &consumer)
+	expected := []token{
+		{tokenTypeSqlSubscribe, "subscribe"},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "qty"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSubscribeHaving(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" subscribe price from ticks where ticker = IBM having delta(price) > 0.5 and delta( qty ) > 10", &consumer)
+	expected := []token{
+		{tokenTypeSqlSubscribe, "subscribe"},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "ticks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlHaving, "having"},
+		{tokenTypeSqlDelta, "delta"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlGreater, ">"},
+		{tokenTypeSqlValue, "0.5"},
+		{tokenTypeSqlAnd, "and"},
+		{tokenTypeSqlDelta, "delta"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "qty"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlGreater, ">"},
+		{tokenTypeSqlValue, "10"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSubscribeSince(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" subscribe * from ticks where ticker = IBM since 42", &consumer)
+	expected := []token{
+		{tokenTypeSqlSubscribe, "subscribe"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "ticks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlSince, "since"},
+		{tokenTypeSqlValue, "42"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	having := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" subscribe * from ticks having delta(price) > 1 since 7", &having)
+	expected = []token{
+		{tokenTypeSqlSubscribe, "subscribe"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "ticks"},
+		{tokenTypeSqlHaving, "having"},
+		{tokenTypeSqlDelta, "delta"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlGreater, ">"},
+		{tokenTypeSqlValue, "1"},
+		{tokenTypeSqlSince, "since"},
+		{tokenTypeSqlValue, "7"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, having.channel)
+}
+
+func TestSqlSubscribeTablePattern(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" subscribe * from user_% where user_type = admin", &consumer)
+	expected := []token{
+		{tokenTypeSqlSubscribe, "subscribe"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "user_%"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "user_type"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "admin"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 func TestSqlSubscribeTopic(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
 	go lex("subscribe topicname", &consumer)
@@ -367,6 +766,17 @@ func TestSqlUnrsubscribeStatement1(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestSqlUnsubscribeAll(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" unsubscribe all ", &consumer)
+	expected := []token{
+		{tokenTypeSqlUnsubscribe, "unsubscribe"},
+		{tokenTypeSqlAll, "all"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 // UPDATE
 func TestSqlUpdateStatement1(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
@@ -456,16 +866,98 @@ func TestSqlUpdateStatement4(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
-// KEY
-func TestSqlKeyStatement(t *testing.T) {
+func TestSqlUpdateVersion(t *testing.T) {
 	consumer := chanTokenConsumer{channel: make(chan *token)}
-	go lex("key stocks ticker", &consumer)
+	go lex(" update stocks set bid = 140.45 where id = 3 and version = 2 returning *", &consumer)
 	expected := []token{
-		{tokenTypeSqlKey, "key"},
+		{tokenTypeSqlUpdate, "update"},
 		{tokenTypeSqlTable, "stocks"},
-		{tokenTypeSqlColumn, "ticker"},
-		{tokenTypeEOF, ""}}
-
+		{tokenTypeSqlSet, "set"},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "140.45"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "3"},
+		{tokenTypeSqlAnd, "and"},
+		{tokenTypeSqlColumn, "version"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "2"},
+		{tokenTypeSqlReturning, "returning"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectQualifiedColumn(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select stocks.ticker, bid from stocks where stocks.ticker = IBM", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlColumnTable, "stocks"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumnTable, "stocks"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select stocks.1 from stocks", &consumer2)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlColumnTable, "stocks"},
+		{tokenTypeError, "syntax error at position 15: identifier must begin with a letter but got '1'"}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
+
+func TestSqlUpdateIncrement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" update stats set hits = hits + 1, misses = misses - 2.5 where id = 3", &consumer)
+	expected := []token{
+		{tokenTypeSqlUpdate, "update"},
+		{tokenTypeSqlTable, "stats"},
+		{tokenTypeSqlSet, "set"},
+		{tokenTypeSqlColumn, "hits"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "hits"},
+		{tokenTypeSqlArithmetic, "+"},
+		{tokenTypeSqlValue, "1"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "misses"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "misses"},
+		{tokenTypeSqlArithmetic, "-"},
+		{tokenTypeSqlValue, "2.5"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "3"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+// KEY
+func TestSqlKeyStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex("key stocks ticker", &consumer)
+	expected := []token{
+		{tokenTypeSqlKey, "key"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeEOF, ""}}
+
 	validateTokens(t, expected, consumer.channel)
 }
 
@@ -482,6 +974,19 @@ func TestSqlTagStatement(t *testing.T) {
 	validateTokens(t, expected, consumer.channel)
 }
 
+func TestSqlTagMultiStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex("tag stocks labels multi", &consumer)
+	expected := []token{
+		{tokenTypeSqlTag, "tag"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlColumn, "labels"},
+		{tokenTypeSqlMulti, "multi"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
 // STREAM
 func TestSqlStream(t *testing.T) {
 	// any operation can be streamed
@@ -770,3 +1275,677 @@ func TestSqlPeekStatement6(t *testing.T) {
 
 	validateTokens(t, expected, consumer.channel)
 }
+
+// CREATE
+
+func TestSqlCreateStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" create table stocks (id int, ticker, bid FLOAT,active bool)", &consumer)
+	expected := []token{
+		{tokenTypeSqlCreate, "create"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeSqlColumnType, "int"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlColumnType, "FLOAT"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "active"},
+		{tokenTypeSqlColumnType, "bool"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	with := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" create table users (name) with id = uuid ", &with)
+	expected = []token{
+		{tokenTypeSqlCreate, "create"},
+		{tokenTypeSqlTable, "users"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "name"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlWith, "with"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "uuid"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, with.channel)
+	//
+	ifNotExists := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" create table IF not Exists ifs (name) ", &ifNotExists)
+	expected = []token{
+		{tokenTypeSqlCreate, "create"},
+		{tokenTypeSqlIf, "IF"},
+		{tokenTypeSqlNot, "not"},
+		{tokenTypeSqlExists, "Exists"},
+		{tokenTypeSqlTable, "ifs"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "name"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, ifNotExists.channel)
+}
+
+// TRUNCATE
+
+func TestSqlTruncateStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" truncate table stocks ", &consumer)
+	expected := []token{
+		{tokenTypeSqlTruncate, "truncate"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlDescribeStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" describe stocks ", &consumer)
+	expected := []token{
+		{tokenTypeSqlDescribe, "describe"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSaveLoadStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" save table stocks to 'stocks.snap' ", &consumer)
+	expected := []token{
+		{tokenTypeSqlSave, "save"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlTo, "to"},
+		{tokenTypeSqlValue, "stocks.snap"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	load := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" load table stocks from stocks.snap replace ", &load)
+	expected = []token{
+		{tokenTypeSqlLoad, "load"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlValue, "stocks.snap"},
+		{tokenTypeSqlReplace, "replace"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, load.channel)
+}
+
+func TestSqlStatementTerminator(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" update stocks set bid = 'a;' where ticker = IBM ; \n", &consumer)
+	expected := []token{
+		{tokenTypeSqlUpdate, "update"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlSet, "set"},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "a;"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSplitStatements(t *testing.T) {
+	for _, x := range []struct {
+		input      string
+		statements []string
+	}{
+		{" select * from stocks ", []string{" select * from stocks "}},
+		{" select * from stocks; ", []string{" select * from stocks"}},
+		{"ping; select * from stocks", []string{"ping", " select * from stocks"}},
+		{"ping;; ;ping", []string{"ping", "ping"}},
+		{"insert into t (a) values ('x;y'); ping", []string{"insert into t (a) values ('x;y')", " ping"}},
+		{"insert into t (a) values ('it''s;'); ping", []string{"insert into t (a) values ('it''s;')", " ping"}},
+		{"insert into t (a) values ('\\';'); ping", []string{"insert into t (a) values ('\\';')", " ping"}},
+		{" ; ", nil},
+		// apostrophe inside unquoted value does not start quoted value
+		{"update t set a = O'Brien where id = 1; select * from t", []string{"update t set a = O'Brien where id = 1", " select * from t"}},
+		{"insert into t (a, b) values (O'Brien, 'x;y');ping", []string{"insert into t (a, b) values (O'Brien, 'x;y')", "ping"}},
+		{"insert into t (a) values (base64'YQ=='); ping", []string{"insert into t (a) values (base64'YQ==')", " ping"}},
+		{"insert into t (a) values (x'0a'); ping", []string{"insert into t (a) values (x'0a')", " ping"}},
+	} {
+		statements := splitStatements(x.input)
+		if !reflect.DeepEqual(statements, x.statements) {
+			t.Errorf("split statements error: expected %q but got %q", x.statements, statements)
+		}
+	}
+}
+
+// ERRORS
+
+func TestSqlErrorPosition(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * form stocks", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeError, "syntax error at position 10: unexpected 'form'"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlErrorInvalidUTF8(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" insert into stocks (ticker) values ('\xffIBM')", &consumer)
+	expected := []token{
+		{tokenTypeError, "syntax error at position 38: invalid UTF-8 byte 0xff"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	// truncated multi byte sequence
+	truncated := chanTokenConsumer{channel: make(chan *token)}
+	go lex("select * from \xe2\x82", &truncated)
+	expected = []token{
+		{tokenTypeError, "syntax error at position 14: invalid UTF-8 byte 0xe2"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, truncated.channel)
+	// valid multi byte runes are accepted
+	valid := chanTokenConsumer{channel: make(chan *token)}
+	go lex("select * from stocks where ticker = '€'", &valid)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "€"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, valid.channel)
+}
+
+func TestSqlErrorPositionInvalidCommand(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex("  xyz from stocks", &consumer)
+	expected := []token{
+		{tokenTypeError, "syntax error at position 2: invalid command 'x'"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectDistinct(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select distinct sector, exchange from stocks", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlDistinct, "distinct"},
+		{tokenTypeSqlColumn, "sector"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "exchange"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectAs(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select price as p, ticker AS symbol, count(*) as total, asset from stocks", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlAs, "as"},
+		{tokenTypeSqlColumn, "p"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlAs, "AS"},
+		{tokenTypeSqlColumn, "symbol"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlCount, "count(*)"},
+		{tokenTypeSqlAs, "as"},
+		{tokenTypeSqlColumn, "total"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "asset"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectConcat(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select ticker || ' @ ' ||exchange as label, price from stocks", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlConcat, "||"},
+		{tokenTypeSqlValue, " @ "},
+		{tokenTypeSqlConcat, "||"},
+		{tokenTypeSqlColumn, "exchange"},
+		{tokenTypeSqlAs, "as"},
+		{tokenTypeSqlColumn, "label"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectStarColumns(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select *, ticker || exchange as label from stocks", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlConcat, "||"},
+		{tokenTypeSqlColumn, "exchange"},
+		{tokenTypeSqlAs, "as"},
+		{tokenTypeSqlColumn, "label"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlRadixValues(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" insert into stocks (flags, mask, name) values (0xFF, 0b1010, '0x10')", &consumer)
+	expected := []token{
+		{tokenTypeSqlInsert, "insert"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "flags"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "mask"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "name"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "values"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "255"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "10"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "0x10"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlBlobValues(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" insert into files (a, b, c, d) values (x'48656C6c6f', BASE64'SGVsbG8=', x'', 'x''00')", &consumer)
+	expected := []token{
+		{tokenTypeSqlInsert, "insert"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "files"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "a"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "b"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "c"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "d"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "values"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "SGVsbG8="},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "SGVsbG8="},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, ""},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "x'00"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from files where a = x'4g'", &consumer2)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "files"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "a"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeError, "syntax error at position 31: invalid blob literal '4g'"}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
+
+func TestSqlQuotedValues(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(` insert into notes (a, b, c, d) values ('it''s', 'line1\nline2\ttab', 'back\\slash \'q\'', 'c:\dir')`, &consumer)
+	expected := []token{
+		{tokenTypeSqlInsert, "insert"},
+		{tokenTypeSqlInto, "into"},
+		{tokenTypeSqlTable, "notes"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "a"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "b"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "c"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "d"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "values"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "it's"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "line1\nline2\ttab"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "back\\slash 'q'"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "c:\\dir"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	// quoted value at the end of input
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where ticker = ''''", &consumer2)
+	expected = []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "'"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
+
+func TestSqlQuotedValuesNotDelimited(t *testing.T) {
+	for _, sql := range []string{
+		` select * from stocks where ticker = 'IBM`,
+		` select * from stocks where ticker = 'IBM''`,
+		` select * from stocks where ticker = 'IBM\'`} {
+		consumer := chanTokenConsumer{channel: make(chan *token)}
+		go lex(sql, &consumer)
+		expected := []token{
+			{tokenTypeSqlSelect, "select"},
+			{tokenTypeSqlStar, "*"},
+			{tokenTypeSqlFrom, "from"},
+			{tokenTypeSqlTable, "stocks"},
+			{tokenTypeSqlWhere, "where"},
+			{tokenTypeSqlColumn, "ticker"},
+			{tokenTypeSqlEqual, "="},
+			{tokenTypeError, "syntax error at position 37: string was not delimited"},
+			{tokenTypeEOF, ""}}
+
+		validateTokens(t, expected, consumer.channel)
+	}
+}
+
+func TestSqlRadixValuesMalformed(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where flags = 0xZZ", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "flags"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeError, "syntax error at position 36: invalid numeric literal '0xZZ'"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" update stocks set mask = 0b102", &consumer2)
+	expected = []token{
+		{tokenTypeSqlUpdate, "update"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlSet, "set"},
+		{tokenTypeSqlColumn, "mask"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeError, "syntax error at position 26: invalid numeric literal '0b102'"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
+
+func TestSqlWhereBetween(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where price between 100 and 200 limit 5", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "price"},
+		{tokenTypeSqlBetween, "between"},
+		{tokenTypeSqlValue, "100"},
+		{tokenTypeSqlAnd, "and"},
+		{tokenTypeSqlValue, "200"},
+		{tokenTypeSqlLimit, "limit"},
+		{tokenTypeSqlValue, "5"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlWhereNot(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where not NOT notes = IBM", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlNot, "not"},
+		{tokenTypeSqlNot, "NOT"},
+		{tokenTypeSqlColumn, "notes"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlWhereCollate(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where name = 'ibm' COLLATE nocase or collated = 1", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "name"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "ibm"},
+		{tokenTypeSqlCollate, "COLLATE"},
+		{tokenTypeSqlValue, "nocase"},
+		{tokenTypeSqlOr, "or"},
+		{tokenTypeSqlColumn, "collated"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "1"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlWhereIn(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where id IN (3, '1',2) or inventory in(4) ", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeSqlIn, "IN"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "3"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "1"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlValue, "2"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlOr, "or"},
+		{tokenTypeSqlColumn, "inventory"},
+		{tokenTypeSqlIn, "in"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "4"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlWhereOr(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select * from stocks where ticker = IBM or bid between 1 and 2 OR not orders = 3 order by id", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlStar, "*"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlWhere, "where"},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlOr, "or"},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeSqlBetween, "between"},
+		{tokenTypeSqlValue, "1"},
+		{tokenTypeSqlAnd, "and"},
+		{tokenTypeSqlValue, "2"},
+		{tokenTypeSqlOr, "OR"},
+		{tokenTypeSqlNot, "not"},
+		{tokenTypeSqlColumn, "orders"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "3"},
+		{tokenTypeSqlOrder, "order"},
+		{tokenTypeSqlBy, "by"},
+		{tokenTypeSqlColumn, "id"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlRangeStatement(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" range stocks bid ", &consumer)
+	expected := []token{
+		{tokenTypeSqlRange, "range"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlColumn, "bid"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlSelectGroupBy(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" select exchange, count( * ) from stocks group by exchange, sector limit 1", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "select"},
+		{tokenTypeSqlColumn, "exchange"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlCount, "count(*)"},
+		{tokenTypeSqlFrom, "from"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlGroup, "group"},
+		{tokenTypeSqlBy, "by"},
+		{tokenTypeSqlColumn, "exchange"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "sector"},
+		{tokenTypeSqlLimit, "limit"},
+		{tokenTypeSqlValue, "1"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+}
+
+func TestSqlCaseInsensitiveKeywords(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" SELECT Ticker, BID FROM Stocks Where Sector = TECH Limit 5", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "SELECT"},
+		{tokenTypeSqlColumn, "Ticker"},
+		{tokenTypeSqlComma, ","},
+		{tokenTypeSqlColumn, "BID"},
+		{tokenTypeSqlFrom, "FROM"},
+		{tokenTypeSqlTable, "Stocks"},
+		{tokenTypeSqlWhere, "Where"},
+		{tokenTypeSqlColumn, "Sector"},
+		{tokenTypeSqlEqual, "="},
+		{tokenTypeSqlValue, "TECH"},
+		{tokenTypeSqlLimit, "Limit"},
+		{tokenTypeSqlValue, "5"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" Insert INTO stocks (ticker) VALUES (IBM)", &consumer2)
+	expected = []token{
+		{tokenTypeSqlInsert, "Insert"},
+		{tokenTypeSqlInto, "INTO"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlColumn, "ticker"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeSqlValues, "VALUES"},
+		{tokenTypeSqlLeftParenthesis, "("},
+		{tokenTypeSqlValue, "IBM"},
+		{tokenTypeSqlRightParenthesis, ")"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
+
+func TestSqlKeywordPrefixIdentifier(t *testing.T) {
+	consumer := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" SELECT selector FROM stocks", &consumer)
+	expected := []token{
+		{tokenTypeSqlSelect, "SELECT"},
+		{tokenTypeSqlColumn, "selector"},
+		{tokenTypeSqlFrom, "FROM"},
+		{tokenTypeSqlTable, "stocks"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer.channel)
+	//
+	consumer2 := chanTokenConsumer{channel: make(chan *token)}
+	go lex(" selector * from stocks", &consumer2)
+	expected = []token{
+		{tokenTypeError, "syntax error at position 1: unexpected 'selector'"},
+		{tokenTypeEOF, ""}}
+
+	validateTokens(t, expected, consumer2.channel)
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 var debugLogger = log.New(os.Stderr, "debug: ", log.LstdFlags)
@@ -58,3 +60,36 @@ func info(v ...interface{}) {
 func errorx(v ...interface{}) {
 	errLogger.Output(2, fmt.Sprintln(v...))
 }
+
+// RequestLog is structured log of a request processed by the server.
+type RequestLog struct {
+	ConnectionId uint64
+	RequestId    uint32
+	Command      string        // first keyword of the request such as select or subscribe
+	Duration     time.Duration // from reading the request to writing its response
+	ErrorCode    string        // empty when the request succeeded
+	Error        string
+}
+
+// Logger receives structured logs of processed requests.
+// It is called by client connections concurrently.
+type Logger interface {
+	LogRequest(entry RequestLog)
+}
+
+var requestLogger Logger
+
+// SetLogger routes logs of processed requests to the logger, nil disables them.
+// It must be called before the server starts accepting connections.
+func SetLogger(logger Logger) {
+	requestLogger = logger
+}
+
+// Returns lower case first keyword of the request message.
+func requestCommand(message []byte) string {
+	command := strings.TrimSpace(string(message))
+	if idx := strings.IndexAny(command, " \t\r\n"); idx >= 0 {
+		command = command[:idx]
+	}
+	return strings.ToLower(command)
+}
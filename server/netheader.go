@@ -14,6 +14,8 @@ package server
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"strconv"
 )
 
 /*
@@ -22,11 +24,32 @@ import (
 --------------------+--------------------
 |      uint32       |      uint32       |
 --------------------+--------------------
+
+The high bits of message size are reserved for header flags.
+
+Message of idempotent request starts with the idempotency key:
+--------------------+--------------------+--------------------
+|    key length     |        key         |      command      |
+--------------------+--------------------+--------------------
+|       uint8       |  key length bytes  |                   |
+--------------------+--------------------+--------------------
 */
 
+const (
+	_HEADER_FLAGS_MASK      uint32 = 0xF0000000
+	_HEADER_FLAG_COMPRESSED uint32 = 0x80000000 // message is deflate compressed
+	_HEADER_FLAG_PROBE      uint32 = 0x40000000 // idle connection probe, carries no message
+	_HEADER_FLAG_IDEMPOTENT uint32 = 0x20000000 // message starts with idempotency key
+)
+
 type netHeader struct {
 	MessageSize uint32
 	RequestId   uint32
+	Compressed  bool
+	Probe       bool
+	Idempotent  bool
+	// set by netHelper when idempotent message is read
+	IdempotencyKey string
 }
 
 var _HEADER_SIZE = 8
@@ -40,15 +63,36 @@ func newNetHeader(messageSize uint32, requestId uint32) *netHeader {
 }
 
 func (this *netHeader) readFrom(bytes []byte) {
-	this.MessageSize = binary.BigEndian.Uint32(bytes)
+	size := binary.BigEndian.Uint32(bytes)
+	this.MessageSize = size &^ _HEADER_FLAGS_MASK
+	this.Compressed = size&_HEADER_FLAG_COMPRESSED != 0
+	this.Probe = size&_HEADER_FLAG_PROBE != 0
+	this.Idempotent = size&_HEADER_FLAG_IDEMPOTENT != 0
 	this.RequestId = binary.BigEndian.Uint32(bytes[4:])
 }
 
-func (this *netHeader) writeTo(bytes []byte) {
-	binary.BigEndian.PutUint32(bytes, this.MessageSize)
+// writeTo writes the header to the bytes.
+// Returns error when message size does not fit the bits below header flags.
+func (this *netHeader) writeTo(bytes []byte) error {
+	if this.MessageSize&_HEADER_FLAGS_MASK != 0 {
+		return errors.New("message size " + strconv.FormatUint(uint64(this.MessageSize), 10) + " exceeds maximum message size of the header")
+	}
+	size := this.MessageSize
+	if this.Compressed {
+		size |= _HEADER_FLAG_COMPRESSED
+	}
+	if this.Probe {
+		size |= _HEADER_FLAG_PROBE
+	}
+	if this.Idempotent {
+		size |= _HEADER_FLAG_IDEMPOTENT
+	}
+	binary.BigEndian.PutUint32(bytes, size)
 	binary.BigEndian.PutUint32(bytes[4:], this.RequestId)
+	return nil
 }
 
+// getBytes returns header of message whose size is known to fit the header.
 func (this *netHeader) getBytes() []byte {
 	bytes := make([]byte, _HEADER_SIZE, _HEADER_SIZE)
 	this.writeTo(bytes)
@@ -12,8 +12,14 @@
 package server
 
 import (
-	"errors"
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,6 +27,23 @@ import (
 type netHelper struct {
 	conn  net.Conn
 	bytes []byte
+	// messages larger than compressThreshold are compressed before they are written,
+	// 0 disables compression
+	compressThreshold int
+	// messages larger than maxMessageSize are rejected before they are read,
+	// 0 disables the limit
+	maxMessageSize int
+}
+
+// Enables or disables Nagle's algorithm on tcp connection.
+// With no delay small messages such as commands and their responses are sent immediately,
+// which lowers latency of request/response workloads. With delay small messages are
+// coalesced into fewer packets, which favors throughput of streaming many small messages.
+func setNoDelay(conn net.Conn, noDelay bool) error {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		return tcp.SetNoDelay(noDelay)
+	}
+	return nil
 }
 
 func newNetHelper(conn net.Conn, bufferSize int) *netHelper {
@@ -32,6 +55,7 @@ func newNetHelper(conn net.Conn, bufferSize int) *netHelper {
 func (this *netHelper) set(conn net.Conn, bufferSize int) {
 	this.conn = conn
 	this.bytes = make([]byte, bufferSize, bufferSize)
+	this.maxMessageSize = config.NET_MAX_MESSAGE_SIZE
 }
 
 func (this *netHelper) close() {
@@ -62,52 +86,201 @@ func (this *netHelper) writeMessage(bytes []byte) error {
 }
 
 func (this *netHelper) writeHeaderAndMessage(requestId uint32, bytes []byte) error {
-	err := this.writeMessage(newNetHeader(uint32(len(bytes)), requestId).getBytes())
+	header := newNetHeader(uint32(len(bytes)), requestId)
+	if this.shouldCompress(len(bytes)) {
+		if compressed, ok := compressMessage(bytes); ok {
+			header.MessageSize = uint32(len(compressed))
+			header.Compressed = true
+			bytes = compressed
+		}
+	}
+	err := this.writeHeader(header)
 	if err != nil {
 		return err
 	}
 	return this.writeMessage(bytes)
 }
 
+// writeHeader writes the header of the message that follows.
+func (this *netHelper) writeHeader(header *netHeader) error {
+	bytes := make([]byte, _HEADER_SIZE)
+	if err := header.writeTo(bytes); err != nil {
+		return err
+	}
+	return this.writeMessage(bytes)
+}
+
+// writeHeaderKeyAndMessage writes message of idempotent request prefixed with the idempotency key.
+func (this *netHelper) writeHeaderKeyAndMessage(requestId uint32, key string, bytes []byte) error {
+	if len(key) == 0 || len(key) > _MAX_IDEMPOTENCY_KEY_SIZE {
+		return idempotencyKeyError()
+	}
+	message := make([]byte, 0, 1+len(key)+len(bytes))
+	message = append(message, byte(len(key)))
+	message = append(message, key...)
+	message = append(message, bytes...)
+	header := newNetHeader(uint32(len(message)), requestId)
+	header.Idempotent = true
+	if this.shouldCompress(len(message)) {
+		if compressed, ok := compressMessage(message); ok {
+			header.MessageSize = uint32(len(compressed))
+			header.Compressed = true
+			message = compressed
+		}
+	}
+	err := this.writeHeader(header)
+	if err != nil {
+		return err
+	}
+	return this.writeMessage(message)
+}
+
+// writeHandshake writes handshake request with protocol version and capabilities.
+func (this *netHelper) writeHandshake(requestId uint32, protocol int, capabilities []string) error {
+	message := strings.Join(append([]string{handshakeCommand, strconv.Itoa(protocol)}, capabilities...), " ")
+	return this.writeHeaderAndMessage(requestId, []byte(message))
+}
+
+// writeNetworkMessage writes message that already has the header in place,
+// compressing it when compression is enabled.
+func (this *netHelper) writeNetworkMessage(bytes []byte) error {
+	if this.shouldCompress(len(bytes) - _HEADER_SIZE) {
+		var header netHeader
+		header.readFrom(bytes)
+		if compressed, ok := compressMessage(bytes[_HEADER_SIZE:]); ok {
+			header.MessageSize = uint32(len(compressed))
+			header.Compressed = true
+			err := this.writeHeader(&header)
+			if err != nil {
+				return err
+			}
+			return this.writeMessage(compressed)
+		}
+	}
+	return this.writeMessage(bytes)
+}
+
+func (this *netHelper) shouldCompress(messageSize int) bool {
+	return this.compressThreshold > 0 && messageSize >= this.compressThreshold
+}
+
+// compressMessage deflates the message.
+// Returns false on compression error.
+func compressMessage(message []byte) ([]byte, bool) {
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriter(&buffer, flate.BestSpeed)
+	if err != nil {
+		return nil, false
+	}
+	if _, err = writer.Write(message); err != nil {
+		return nil, false
+	}
+	if err = writer.Close(); err != nil {
+		return nil, false
+	}
+	return buffer.Bytes(), true
+}
+
+// decompressMessage inflates the message.
+// Returns error when inflated message is larger than maxMessageSize.
+func decompressMessage(message []byte, maxMessageSize int) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(message))
+	defer reader.Close()
+	if maxMessageSize <= 0 {
+		return ioutil.ReadAll(reader)
+	}
+	inflated, err := ioutil.ReadAll(io.LimitReader(reader, int64(maxMessageSize)+1))
+	if err == nil && len(inflated) > maxMessageSize {
+		err = messageSizeError(len(inflated), maxMessageSize)
+	}
+	return inflated, err
+}
+
+const _MAX_IDEMPOTENCY_KEY_SIZE = 255
+
+func idempotencyKeyError() error {
+	return fmt.Errorf("Idempotency key must be between 1 and %d bytes.", _MAX_IDEMPOTENCY_KEY_SIZE)
+}
+
+// splitIdempotencyKey separates idempotency key from the message.
+func splitIdempotencyKey(message []byte) (string, []byte, error) {
+	if len(message) == 0 || message[0] == 0 || len(message) < 1+int(message[0]) {
+		return "", nil, idempotencyKeyError()
+	}
+	size := 1 + int(message[0])
+	return string(message[1:size]), message[size:], nil
+}
+
+func messageSizeError(size int, maxMessageSize int) error {
+	return fmt.Errorf("Message size %d exceeds maximum message size %d.", size, maxMessageSize)
+}
+
+// readMessageTimeout reads next message and returns true when no message started
+// to arrive within the timeout.
+// Timeout only applies while waiting for the first byte of the message,
+// message that started to arrive is read completely however slowly it is sent.
 func (this *netHelper) readMessageTimeout(milliseconds int64) (*netHeader, []byte, error, bool) {
 	this.conn.SetReadDeadline(time.Now().Add(time.Duration(milliseconds) * time.Millisecond))
-	header, bytes, err := this.readMessage()
-	timedout := false
+	_, err := io.ReadFull(this.conn, this.bytes[0:1])
+	this.conn.SetReadDeadline(time.Time{})
 	if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
-		timedout = true
-		err = nil
+		return nil, nil, nil, true
 	}
-	return header, bytes, err, timedout
+	if err != nil {
+		return nil, nil, err, false
+	}
+	header, bytes, err := this.readMessageAfter(1)
+	return header, bytes, err, false
 }
 
+// readMessage reads next message.
+// Uncompressed message is read into the reused buffer and is valid until the next read.
+// Returns io.EOF when the peer cleanly closed the connection between messages
+// and io.ErrUnexpectedEOF when the connection was closed in the middle of a message.
 func (this *netHelper) readMessage() (*netHeader, []byte, error) {
+	return this.readMessageAfter(0)
+}
+
+// readMessageAfter reads the rest of the message
+// whose first read bytes of header are already in the buffer.
+func (this *netHelper) readMessageAfter(read int) (*netHeader, []byte, error) {
 	// header
-	read, err := this.conn.Read(this.bytes[0:_HEADER_SIZE])
-	if err != nil {
-		return nil, nil, err
+	_, err := io.ReadFull(this.conn, this.bytes[read:_HEADER_SIZE])
+	if err == io.EOF && read > 0 {
+		err = io.ErrUnexpectedEOF
 	}
-	if read < _HEADER_SIZE {
-		err = errors.New("Failed to read header.")
+	if err != nil {
 		return nil, nil, err
 	}
 	var header netHeader
 	header.readFrom(this.bytes)
+	if this.maxMessageSize > 0 && int(header.MessageSize) > this.maxMessageSize {
+		return nil, nil, messageSizeError(int(header.MessageSize), this.maxMessageSize)
+	}
 	// prepare buffer
 	if len(this.bytes) < int(header.MessageSize) {
 		this.bytes = make([]byte, header.MessageSize, header.MessageSize)
 	}
 	// message
-	bytes := this.bytes[:header.MessageSize]
-	left := len(bytes)
-	message := bytes
-	read = 0
-	for left > 0 {
-		bytes = bytes[read:]
-		read, err = this.conn.Read(bytes)
+	message := this.bytes[:header.MessageSize]
+	_, err = io.ReadFull(this.conn, message)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Compressed {
+		message, err = decompressMessage(message, this.maxMessageSize)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if header.Idempotent {
+		header.IdempotencyKey, message, err = splitIdempotencyKey(message)
 		if err != nil {
 			return nil, nil, err
 		}
-		left -= read
 	}
 	return &header, message, nil
 }
@@ -54,6 +54,8 @@ type network struct {
 	connections map[uint64]*networkConnection
 	listener    net.Listener
 	context     *networkContext
+	// set once stop was requested, new connections are not accepted
+	draining bool
 }
 
 func (this *network) addConnection(netConn *networkConnection) {
@@ -62,11 +64,15 @@ func (this *network) addConnection(netConn *networkConnection) {
 	}
 	this.mutex.Lock()
 	defer this.mutex.Unlock()
+	if this.draining {
+		netConn.close()
+		return
+	}
 	if this.connections == nil {
 		this.connections = make(map[uint64]*networkConnection)
 	}
 	this.connections[netConn.getConnectionId()] = netConn
-	logInfo("new client connection id:", strconv.FormatUint(netConn.getConnectionId(), 10))
+	logInfo("new client connection id:", strconv.FormatUint(netConn.getConnectionId(), 10), "from", netConn.conn.RemoteAddr().String())
 }
 
 func (this *network) removeConnection(netConn *networkConnection) {
@@ -118,11 +124,14 @@ func (this *network) start(address string) bool {
 		for {
 			conn, err := this.listener.Accept()
 			// stop was called
-			if quit.Done() {
+			if quit.Done() || this.isDraining() {
 				return
 			}
 			if err == nil {
 				connectionId++
+				if err = setNoDelay(conn, config.NET_NO_DELAY); err != nil {
+					logWarn("failed to set no delay on client connection:", connectionId, err.Error())
+				}
 				netConn := newNetworkConnection(conn, this.context, connectionId, this)
 				this.addConnection(netConn)
 				go netConn.run()
@@ -135,6 +144,29 @@ func (this *network) start(address string) bool {
 	return true
 }
 
+// drain stops accepting new connections and notifies connected clients that the server is closing.
+// Connections keep serving reads until the network is stopped.
+func (this *network) drain() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.draining {
+		return
+	}
+	this.draining = true
+	if this.listener != nil {
+		this.listener.Close()
+	}
+	for _, c := range this.connections {
+		c.drain()
+	}
+}
+
+func (this *network) isDraining() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.draining
+}
+
 func (this *network) stop() {
 	if this.listener != nil {
 		this.listener.Close()
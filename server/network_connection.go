@@ -16,7 +16,13 @@
 
 package server
 
-import "net"
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 type networkConnection struct {
 	parent networkConnectionContainer
@@ -25,16 +31,73 @@ type networkConnection struct {
 	router *requestRouter
 	sender *responseSender
 	dbConn *mysqlConnection
+	// set to 1 once the client has negotiated compression in the handshake,
+	// indicating that it is able to read compressed responses
+	compress int32
+	// results of idempotent requests
+	idempotency *idempotencyCache
+	// commands per second allowance, only accessed by the reader
+	limiter *rateLimiter
+	// protocol negotiated with the client, nil for legacy clients,
+	// only accessed by the reader
+	handshake *handshake
+	// requests waiting for response, nil when request logger is not set
+	pending *pendingRequests
+	// set to 1 once the server is draining before shutdown
+	draining int32
+	// requests read by the reader and routed by the worker in the same order
+	requests chan *requestItem
+}
+
+// pendingRequest is a request read by the connection reader.
+type pendingRequest struct {
+	command string
+	start   time.Time
+}
+
+// pendingRequests remembers requests read by the connection reader
+// until the writer writes their first response.
+type pendingRequests struct {
+	mutex    sync.Mutex
+	requests map[uint32]pendingRequest
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{requests: make(map[uint32]pendingRequest)}
+}
+
+func (this *pendingRequests) add(requestId uint32, command string, start time.Time) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.requests[requestId] = pendingRequest{command: command, start: start}
+}
+
+func (this *pendingRequests) remove(requestId uint32) (pendingRequest, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	pending, ok := this.requests[requestId]
+	if ok {
+		delete(this.requests, requestId)
+	}
+	return pending, ok
 }
 
 func newNetworkConnection(conn net.Conn, context *networkContext, connectionId uint64, parent networkConnectionContainer) *networkConnection {
-	return &networkConnection {
-		parent: parent,
-		conn:   conn,
-		quit:   context.quit,
-		router: context.router,
-		sender: newResponseSenderStub(connectionId),
-		dbConn: newMysqlConnection(),
+	var pending *pendingRequests
+	if requestLogger != nil {
+		pending = newPendingRequests()
+	}
+	return &networkConnection{
+		parent:      parent,
+		conn:        conn,
+		quit:        context.quit,
+		router:      context.router,
+		sender:      newResponseSenderStub(connectionId),
+		dbConn:      newMysqlConnection(),
+		idempotency: newIdempotencyCache(config.WAIT_MILLISECOND_IDEMPOTENCY_WINDOW * time.Millisecond),
+		limiter:     newRateLimiter(config.NET_RATE_LIMIT),
+		pending:     pending,
+		requests:    make(chan *requestItem, config.CHAN_CONNECTION_REQUESTS_BUFFER_SIZE),
 	}
 }
 
@@ -59,9 +122,32 @@ func (this *networkConnection) close() {
 	this.sender.quit.Quit(0)
 }
 
+// drain notifies the client that the server is closing,
+// from now on the connection rejects mutations and serves reads only.
+func (this *networkConnection) drain() {
+	if atomic.CompareAndSwapInt32(&this.draining, 0, 1) {
+		this.sender.send(newServerClosingResponse())
+	}
+}
+
+// isRejectedWhileDraining answers mutation requested while the server is draining with an error.
+// Returns true when the request must not be executed.
+func (this *networkConnection) isRejectedWhileDraining(header *netHeader, req request) bool {
+	if atomic.LoadInt32(&this.draining) == 0 || isReadRequest(req) {
+		return false
+	}
+	if !req.isStreaming() {
+		res := newErrorResponseWithCode(errorCodeServerClosing, "server is closing")
+		res.requestId = header.RequestId
+		this.sender.send(res)
+	}
+	return true
+}
+
 func (this *networkConnection) run() {
 	go this.watchForQuit()
 	go this.read()
+	go this.work()
 	defer this.dbConn.disconnect()
 	this.write()
 }
@@ -74,13 +160,116 @@ func (this *networkConnection) Done() bool {
 }
 
 func (this *networkConnection) route(header *netHeader, req request) {
-	item := &requestItem {
+	item := &requestItem{
 		header: header,
 		req:    req,
 		sender: this.sender,
 		dbConn: this.dbConn,
 	}
-	this.router.route(item)
+	select {
+	case this.requests <- item:
+	case <-this.sender.quit.GetChan():
+	case <-this.quit.GetChan():
+	}
+}
+
+// work routes requests of the connection one after another,
+// script waiting for its statements keeps the worker busy but not the reader.
+func (this *networkConnection) work() {
+	this.quit.Join()
+	defer this.quit.Leave()
+	for {
+		select {
+		case item := <-this.requests:
+			this.router.route(item)
+		case <-this.quit.GetChan():
+			return
+		case <-this.sender.quit.GetChan():
+			return
+		}
+	}
+}
+
+// logResponse logs the request answered by the message
+// when it is the first response to the request.
+func (this *networkConnection) logResponse(res response, msg []byte) {
+	var header netHeader
+	header.readFrom(msg)
+	if header.RequestId == 0 {
+		return
+	}
+	pending, ok := this.pending.remove(header.RequestId)
+	if !ok {
+		return
+	}
+	entry := RequestLog{
+		ConnectionId: this.sender.connectionId,
+		RequestId:    header.RequestId,
+		Command:      pending.command,
+		Duration:     time.Since(pending.start),
+	}
+	if errres, ok := res.(*errorResponse); ok {
+		entry.ErrorCode = string(errres.code)
+		entry.Error = errres.msg
+	}
+	requestLogger.LogRequest(entry)
+}
+
+// isRateLimited rejects the request when the client exceeded its commands per second.
+// The connection stays open so that the client can back off.
+func (this *networkConnection) isRateLimited(header *netHeader) bool {
+	if this.limiter.allow(time.Now()) {
+		return false
+	}
+	res := newErrorResponseWithCode(errorCodeRateLimited, "rate limited")
+	res.requestId = header.RequestId
+	this.sender.send(res)
+	return true
+}
+
+// onHandshake negotiates protocol with the client.
+// Legacy clients never send handshake and keep the original behavior.
+func (this *networkConnection) onHandshake(header *netHeader, message []byte) {
+	negotiated, err := negotiateHandshake(message)
+	if err != nil {
+		res := newErrorResponse(err.Error())
+		res.requestId = header.RequestId
+		this.sender.send(res)
+		return
+	}
+	this.handshake = negotiated
+	// client is able to read compressed responses
+	if negotiated.supports(capabilityCompression) {
+		atomic.StoreInt32(&this.compress, 1)
+	}
+	res := newHandshakeResponse(negotiated, this.sender.connectionId)
+	res.requestId = header.RequestId
+	this.sender.send(res)
+}
+
+// supports returns true when the capability was negotiated in the handshake.
+func (this *networkConnection) supports(capability string) bool {
+	return this.handshake != nil && this.handshake.supports(capability)
+}
+
+// isRetry answers retry of idempotent request with the original response.
+// Idempotent requests are rejected unless idempotency was negotiated.
+// Returns true when the request must not be executed.
+func (this *networkConnection) isRetry(header *netHeader) bool {
+	if !header.Idempotent {
+		return false
+	}
+	if !this.supports(capabilityIdempotency) {
+		res := newErrorResponse("idempotency was not negotiated")
+		res.requestId = header.RequestId
+		this.sender.send(res)
+		return true
+	}
+	res, retry := this.idempotency.begin(header.IdempotencyKey, header.RequestId, time.Now())
+	if res != nil {
+		this.sender.send(res)
+	}
+	return retry
 }
 
 func (this *networkConnection) read() {
@@ -91,23 +280,62 @@ func (this *networkConnection) read() {
 	var err error
 	var message []byte
 	var header *netHeader
+	var timedout bool
+	// connection is probed once it is idle for the configured time
+	// and dropped when it stays idle after the probe,
+	// clients that did not negotiate probes are dropped once idle
+	idleTimeout := int64(config.WAIT_MILLISECOND_IDLE_CONNECTION)
+	probing := false
 	tokens := newTokens()
 	for {
 		err = nil
 		if this.Done() {
 			break
 		}
-		header, message, err = reader.readMessage()
+		if idleTimeout > 0 {
+			header, message, err, timedout = reader.readMessageTimeout(idleTimeout)
+		} else {
+			header, message, err = reader.readMessage()
+		}
 		if err != nil {
 			break
 		}
-		tokens.reuse()
+		if timedout {
+			if probing || !this.supports(capabilityProbe) {
+				logInfo("closing idle client connection:", this.sender.connectionId)
+				this.sender.quit.Quit(0)
+				break
+			}
+			probing = true
+			this.sender.send(newProbeResponse())
+			continue
+		}
+		probing = false
+		if header.Probe {
+			continue
+		}
+		if args, ok := handshakeArguments(message); ok && !header.Idempotent {
+			this.onHandshake(header, args)
+			continue
+		}
+		if this.isRateLimited(header) || this.isRetry(header) {
+			continue
+		}
 		// parse and route the message
-		lex(string(message), tokens)
-		req := parse(tokens)
+		start := time.Now()
+		req := parseMessage(string(message), tokens)
+		if this.isRejectedWhileDraining(header, req) {
+			continue
+		}
+		if this.pending != nil && header.RequestId != 0 && !req.isStreaming() {
+			this.pending.add(header.RequestId, requestCommand(message), start)
+		}
 		this.route(header, req)
 	}
-	if err != nil && !this.Done() {
+	if err == io.EOF && !this.Done() {
+		logInfo("client connection:", this.sender.connectionId, "was closed by the client")
+		this.sender.quit.Quit(0)
+	} else if err != nil && !this.Done() {
 		logWarn("failed to read from client connection:", this.sender.connectionId, err.Error())
 		// notify writer and sender that we are done
 		this.sender.quit.Quit(0)
@@ -123,6 +351,7 @@ func (this *networkConnection) write() {
 		select {
 		case res := <-this.sender.sender:
 			debug("response is ready to be send over tcp")
+			dequeued(res)
 			// merge responses if applicable
 			nextRes := this.sender.tryRecv()
 			for nextRes != nil && res.merge(nextRes) {
@@ -135,8 +364,17 @@ func (this *networkConnection) write() {
 				if this.Done() {
 					return
 				}
+				if writer.compressThreshold == 0 && atomic.LoadInt32(&this.compress) == 1 {
+					writer.compressThreshold = config.NET_COMPRESSION_THRESHOLD
+				}
 				msg, more = res.toNetworkReadyJSON()
-				err = writer.writeMessage(msg)
+				if this.pending != nil {
+					this.logResponse(res, msg)
+				}
+				for _, replay := range this.idempotency.record(msg, more, time.Now()) {
+					this.sender.send(replay)
+				}
+				err = writer.writeNetworkMessage(msg)
 				if err != nil {
 					break
 				}
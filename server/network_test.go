@@ -17,7 +17,10 @@
 package server
 
 import (
+	"io"
 	"net"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -101,6 +104,18 @@ func validateConnect(t *testing.T, address string) net.Conn {
 	return c
 }
 
+func validateHandshake(t *testing.T, rw *netHelper, capabilities ...string) {
+	if err := rw.writeHandshake(0, protocolVersion, capabilities); err != nil {
+		t.Fatal(err)
+	}
+	_, bytes, err := rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(bytes), `"action":"handshake"`) {
+		t.Error("Expected handshake response but got", string(bytes))
+	}
+}
+
 func TestNetworkWriteRead(t *testing.T) {
 	debug("TestNetworkReadWrite")
 	context := newNetworkContextStub()
@@ -175,3 +190,473 @@ func TestNetworkBatchRead(t *testing.T) {
 	n.stop()
 	s.Wait(time.Millisecond * 500)
 }
+
+func TestNetHeaderCompressedFlag(t *testing.T) {
+	header := newNetHeader(1234, 56)
+	header.Compressed = true
+	var read netHeader
+	read.readFrom(header.getBytes())
+	if read.MessageSize != 1234 || read.RequestId != 56 || !read.Compressed {
+		t.Error("Unexpected header", read.String())
+	}
+	header.Compressed = false
+	read.readFrom(header.getBytes())
+	if read.MessageSize != 1234 || read.Compressed {
+		t.Error("Unexpected header", read.String())
+	}
+}
+
+func TestNetHeaderProbeFlag(t *testing.T) {
+	header := newNetHeader(0, 7)
+	header.Probe = true
+	var read netHeader
+	read.readFrom(header.getBytes())
+	if read.MessageSize != 0 || read.RequestId != 7 || !read.Probe || read.Compressed {
+		t.Error("Unexpected header", read.String())
+	}
+}
+
+func TestNetHeaderMessageSizeOverflow(t *testing.T) {
+	bytes := make([]byte, _HEADER_SIZE)
+	// largest size below header flags fits
+	if err := newNetHeader(0x0FFFFFFF, 1).writeTo(bytes); err != nil {
+		t.Error(err)
+	}
+	var read netHeader
+	read.readFrom(bytes)
+	if read.MessageSize != 0x0FFFFFFF || read.Compressed || read.Probe || read.Idempotent {
+		t.Error("Unexpected header", read.String())
+	}
+	// larger size would set header flags
+	if err := newNetHeader(0x10000000, 1).writeTo(bytes); err == nil {
+		t.Error("Expected message size error")
+	}
+	// net helper refuses to write the header
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	header := newNetHeader(0x10000000, 1)
+	if err := newNetHelper(client, config.NET_READWRITE_BUFFER_SIZE).writeHeader(header); err == nil {
+		t.Error("Expected message size error")
+	}
+}
+
+func TestNetHelperMaxMessageSize(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	writer := newNetHelper(client, config.NET_READWRITE_BUFFER_SIZE)
+	reader := newNetHelper(server, config.NET_READWRITE_BUFFER_SIZE)
+	reader.maxMessageSize = 16
+	// message within the limit
+	go writer.writeHeaderAndMessage(1, []byte("select * from t"))
+	if _, bytes, err := reader.readMessage(); err != nil || string(bytes) != "select * from t" {
+		t.Error("Unexpected message", string(bytes), err)
+	}
+	// header declaring huge message is rejected before the message is read
+	go writer.writeMessage(newNetHeader(0x0FFFFFFF, 2).getBytes())
+	if _, _, err := reader.readMessage(); err == nil {
+		t.Error("Expected max message size error")
+	}
+	// compressed message that inflates beyond the limit
+	compressed, _ := compressMessage([]byte(strings.Repeat("a", 1000)))
+	if _, err := decompressMessage(compressed, 16); err == nil {
+		t.Error("Expected max message size error on decompress")
+	}
+}
+
+func TestNetHelperReadEOF(t *testing.T) {
+	// clean close between messages
+	client, server := net.Pipe()
+	reader := newNetHelper(server, config.NET_READWRITE_BUFFER_SIZE)
+	client.Close()
+	if _, _, err := reader.readMessage(); err != io.EOF {
+		t.Error("Expected io.EOF but got", err)
+	}
+	server.Close()
+	// close in the middle of the header
+	client, server = net.Pipe()
+	reader = newNetHelper(server, config.NET_READWRITE_BUFFER_SIZE)
+	go func() {
+		client.Write(newNetHeader(10, 1).getBytes()[:3])
+		client.Close()
+	}()
+	if _, _, err := reader.readMessage(); err != io.ErrUnexpectedEOF {
+		t.Error("Expected io.ErrUnexpectedEOF but got", err)
+	}
+	server.Close()
+	// close in the middle of the message
+	client, server = net.Pipe()
+	reader = newNetHelper(server, config.NET_READWRITE_BUFFER_SIZE)
+	go func() {
+		client.Write(newNetHeader(10, 1).getBytes())
+		client.Write([]byte("abc"))
+		client.Close()
+	}()
+	if _, _, err := reader.readMessage(); err != io.ErrUnexpectedEOF {
+		t.Error("Expected io.ErrUnexpectedEOF but got", err)
+	}
+	server.Close()
+}
+
+func TestNetworkIdempotentRequest(t *testing.T) {
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	insert := []byte("insert into orders (id, qty) values (1, 10)")
+	// idempotent request is rejected unless idempotency was negotiated
+	rw.writeHeaderKeyAndMessage(1, "order1", insert)
+	_, bytes, err := rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(bytes), `"status":"err"`) {
+		t.Error("Expected error for idempotent request without handshake", string(bytes))
+	}
+	validateHandshake(t, rw, capabilityIdempotency)
+	var original string
+	for requestId := uint32(1); requestId <= 3; requestId++ {
+		if err := rw.writeHeaderKeyAndMessage(requestId, "order1", insert); err != nil {
+			t.Fatal(err)
+		}
+		header, bytes, err := rw.readMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.RequestId != requestId {
+			t.Error("Expected requestid", requestId, "but got", header.RequestId)
+		}
+		if requestId == 1 {
+			original = string(bytes)
+		} else if string(bytes) != original {
+			t.Error("Expected original response", original, "but got", string(bytes))
+		}
+	}
+	// retries were not executed
+	rw.writeHeaderAndMessage(4, []byte("select * from orders"))
+	_, bytes, err = rw.readMessage()
+	if err != nil {
+		t.Error(err)
+	} else if !strings.Contains(string(bytes), `"rows":1`) {
+		t.Error("Expected single order", string(bytes))
+	}
+	// invalid key is rejected
+	if rw.writeHeaderKeyAndMessage(5, "", insert) == nil {
+		t.Error("Expected empty idempotency key to be rejected")
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkIdleConnection(t *testing.T) {
+	config.WAIT_MILLISECOND_IDLE_CONNECTION = 100
+	defer func() { config.WAIT_MILLISECOND_IDLE_CONNECTION = 0 }()
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	validateHandshake(t, rw, capabilityProbe)
+	// answered probe keeps connection alive
+	for i := 0; i < 3; i++ {
+		header, _, err := rw.readMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !header.Probe {
+			t.Error("Expected probe", header.String())
+		}
+		probe := newNetHeader(0, 0)
+		probe.Probe = true
+		rw.writeMessage(probe.getBytes())
+	}
+	validateWriteRead(t, c, "insert into stocks (ticker, bid, ask) values (IBM, 123, 124)", 1)
+	// unanswered probe drops connection
+	header, _, err := rw.readMessage()
+	if err != nil || !header.Probe {
+		t.Error("Expected probe")
+	}
+	_, _, err = rw.readMessage()
+	if err == nil {
+		t.Error("Expected idle connection to be closed")
+	}
+	c.Close()
+	// client that did not negotiate probes is closed without probe
+	c = validateConnect(t, address)
+	rw = newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	if header, _, err = rw.readMessage(); err == nil {
+		t.Error("Expected idle connection to be closed but got", header.String())
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkIdleConnectionSlowMessage(t *testing.T) {
+	config.WAIT_MILLISECOND_IDLE_CONNECTION = 100
+	defer func() { config.WAIT_MILLISECOND_IDLE_CONNECTION = 0 }()
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	// message that started to arrive is read completely however slowly it is sent
+	message := []byte("insert into stocks (ticker, bid, ask) values (IBM, 123, 124)")
+	rw.writeMessage(newNetHeader(uint32(len(message)), 1).getBytes())
+	rw.writeMessage(message[:10])
+	time.Sleep(300 * time.Millisecond)
+	rw.writeMessage(message[10:])
+	header, bytes, err := rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Probe || header.RequestId != 1 || !strings.Contains(string(bytes), `"status":"ok"`) {
+		t.Error("Expected insert response but got", header.String(), string(bytes))
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkCompression(t *testing.T) {
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	// connection that never sends compressed messages gets uncompressed responses
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	for i := 0; i < 50; i++ {
+		validateWriteRead(t, c, "insert into stocks (ticker, bid, ask) values (IBM, 123, 124)", uint32(i+1))
+	}
+	rw.writeHeaderAndMessage(100, []byte("select * from stocks"))
+	header, _, err := rw.readMessage()
+	if err != nil {
+		t.Error(err)
+	} else if header.Compressed {
+		t.Error("Expected uncompressed response")
+	}
+	// compressed request is read but does not enable compressed responses
+	rw.compressThreshold = 1
+	rw.writeHeaderAndMessage(101, []byte("select * from stocks"))
+	header, bytes, err := rw.readMessage()
+	if err != nil {
+		t.Error(err)
+	} else if header.Compressed {
+		t.Error("Expected uncompressed response without negotiated compression")
+	} else if header.RequestId != 101 || !strings.Contains(string(bytes), `"action":"select"`) {
+		t.Error("Unexpected response", string(bytes))
+	}
+	// negotiated compression enables compressed responses
+	validateHandshake(t, rw, capabilityCompression)
+	rw.writeHeaderAndMessage(102, []byte("select * from stocks"))
+	header, bytes, err = rw.readMessage()
+	if err != nil {
+		t.Error(err)
+	} else if !header.Compressed {
+		t.Error("Expected compressed response")
+	} else if header.RequestId != 102 || !strings.Contains(string(bytes), `"action":"select"`) {
+		t.Error("Unexpected response", string(bytes))
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkHandshake(t *testing.T) {
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	// invalid handshake is rejected and connection stays open
+	rw.writeHandshake(1, 0, nil)
+	header, bytes, err := rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if header.RequestId != 1 || !strings.Contains(string(bytes), `"status":"err"`) {
+		t.Error("Expected handshake error", string(bytes))
+	}
+	// negotiated compression enables compressed responses before client compresses anything
+	rw.writeHandshake(2, 2, []string{"compression", "multiplexing"})
+	header, bytes, err = rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"status":"ok","action":"handshake","server":"1.0","protocol":1,"capabilities":["compression"],"connectionid":"1"}`
+	if header.RequestId != 2 || strings.TrimSpace(string(bytes)) != expected {
+		t.Error("Expected", expected, "but got", string(bytes))
+	}
+	for i := 0; i < 50; i++ {
+		validateWriteRead(t, c, "insert into stocks (ticker, bid, ask) values (IBM, 123, 124)", uint32(i+3))
+	}
+	rw.writeHeaderAndMessage(100, []byte("select * from stocks"))
+	header, _, err = rw.readMessage()
+	if err != nil {
+		t.Error(err)
+	} else if !header.Compressed {
+		t.Error("Expected compressed response")
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+// testLogger records logs of processed requests.
+type testLogger struct {
+	mutex   sync.Mutex
+	entries []RequestLog
+}
+
+func (this *testLogger) LogRequest(entry RequestLog) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.entries = append(this.entries, entry)
+}
+
+func TestNetworkRequestLogger(t *testing.T) {
+	logger := new(testLogger)
+	SetLogger(logger)
+	defer SetLogger(nil)
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	validateWriteRead(t, c, "  INSERT into stocks (ticker, bid) values (IBM, 123)", 1)
+	validateWriteRead(t, c, "key stocks bid", 2)
+	validateWriteRead(t, c, "key stocks bid", 3)
+	c.Close()
+	logger.mutex.Lock()
+	entries := logger.entries
+	logger.mutex.Unlock()
+	if len(entries) != 3 {
+		t.Fatal("Expected 3 logged requests but got", len(entries))
+	}
+	if entries[0].RequestId != 1 || entries[0].Command != "insert" || entries[0].ConnectionId != 1 || entries[0].Error != "" {
+		t.Error("Unexpected log", entries[0])
+	}
+	if entries[2].RequestId != 3 || entries[2].Command != "key" || entries[2].Error == "" || entries[2].Duration <= 0 {
+		t.Error("Expected logged error", entries[2])
+	}
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkDrain(t *testing.T) {
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	validateWriteRead(t, c, "insert into stocks (ticker, bid) values (IBM, 123)", 1)
+	n.drain()
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	header, bytes, err := rw.readMessage()
+	if err != nil || header.RequestId != 0 || !strings.Contains(string(bytes), `"action":"closing"`) {
+		t.Fatal("Expected server closing frame", string(bytes), err)
+	}
+	// reads complete, mutations are rejected
+	rw.writeHeaderAndMessage(2, []byte("select * from stocks"))
+	header, bytes, err = rw.readMessage()
+	if err != nil || header.RequestId != 2 || !strings.Contains(string(bytes), `"status":"ok"`) {
+		t.Error("Expected select to succeed while draining", string(bytes), err)
+	}
+	rw.writeHeaderAndMessage(3, []byte("insert into stocks (ticker, bid) values (MSFT, 12)"))
+	header, bytes, err = rw.readMessage()
+	if err != nil || header.RequestId != 3 || !strings.Contains(string(bytes), string(errorCodeServerClosing)) {
+		t.Error("Expected insert to be rejected while draining", string(bytes), err)
+	}
+	rw.writeHeaderAndMessage(4, []byte("select * from stocks into snapshot"))
+	header, bytes, err = rw.readMessage()
+	if err != nil || header.RequestId != 4 || !strings.Contains(string(bytes), string(errorCodeServerClosing)) {
+		t.Error("Expected select into to be rejected while draining", string(bytes), err)
+	}
+	// new connections are not accepted
+	if conn, err := net.Dial("tcp", address); err == nil {
+		conn.Close()
+		t.Error("Expected new connection to be refused while draining")
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkScript(t *testing.T) {
+	context := newNetworkContextStub()
+	address := "localhost:54321"
+	s := context.quit
+	n := newNetwork(context)
+	n.start(address)
+	c := validateConnect(t, address)
+	rw := newNetHelper(c, config.NET_READWRITE_BUFFER_SIZE)
+	// requests following the script are executed after it
+	rw.writeHeaderAndMessage(1, []byte("insert into stocks (ticker) values (IBM); insert into stocks (ticker) values (MSFT)"))
+	rw.writeHeaderAndMessage(2, []byte("select * from stocks"))
+	header, bytes, err := rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if header.RequestId != 1 || !strings.Contains(string(bytes), `"status":"ok"`) {
+		t.Error("Expected script response but got", header.String(), string(bytes))
+	}
+	header, bytes, err = rw.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if header.RequestId != 2 || !strings.Contains(string(bytes), `"rows":2`) {
+		t.Error("Expected both records but got", header.String(), string(bytes))
+	}
+	c.Close()
+	// shutdown
+	s.Quit(0)
+	n.stop()
+	s.Wait(time.Millisecond * 500)
+}
+
+func TestNetworkNoDelay(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:54322")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	c := validateConnect(t, "localhost:54322")
+	defer c.Close()
+	for _, noDelay := range []bool{false, true} {
+		if err := setNoDelay(c, noDelay); err != nil {
+			t.Error("Failed to set no delay", noDelay, err)
+		}
+	}
+	// non tcp connections are left as is
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	if err := setNoDelay(client, true); err != nil {
+		t.Error("Expected no delay to be ignored on pipe", err)
+	}
+}
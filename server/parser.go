@@ -16,16 +16,71 @@
 
 package server
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // tokenProducer produces tokens for the parser.
 type tokenProducer interface {
 	Produce() *token
 }
 
+// lexerErrorTokens wraps tokenProducer and remembers the error token
+// emitted by the lexer so that the parser can report it back.
+type lexerErrorTokens struct {
+	tokens tokenProducer
+	err    *token
+}
+
+func (this *lexerErrorTokens) Produce() *token {
+	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeError {
+		this.err = tok
+	}
+	return tok
+}
+
+// qualifiedColumnTokens wraps tokenProducer and strips table qualifiers
+// of column names, remembering them so that the parser can validate them
+// against the table of the request.
+type qualifiedColumnTokens struct {
+	tokens tokenProducer
+	tables []string
+}
+
+func (this *qualifiedColumnTokens) Produce() *token {
+	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeSqlColumnTable {
+		this.tables = append(this.tables, tok.val)
+		tok = this.tokens.Produce()
+	}
+	return tok
+}
+
+// unreadTokens wraps tokenProducer and lets the parser put back
+// the token it looked ahead at.
+type unreadTokens struct {
+	tokens tokenProducer
+	back   *token
+}
+
+func (this *unreadTokens) Produce() *token {
+	if tok := this.back; tok != nil {
+		this.back = nil
+		return tok
+	}
+	return this.tokens.Produce()
+}
+
+func (this *unreadTokens) unread(tok *token) {
+	this.back = tok
+}
+
 // parser
 type parser struct {
-	tokens    tokenProducer
+	tokens    *unreadTokens
 	streaming bool
 }
 
@@ -55,13 +110,27 @@ func (this *parser) parseSqlEqualVal(colval *columnValue, tok *token) request {
 	}
 	// value
 	tok = this.tokens.Produce()
-	if tok.typ != tokenTypeSqlValue {
+	if !isValueToken(tok) {
 		return this.parseError("expected valid value")
 	}
 	colval.val = tok.val
 	return nil
 }
 
+// Returns true if the token is a value, boolean literals are values too.
+func isValueToken(tok *token) bool {
+	return tok.typ == tokenTypeSqlValue || tok.typ == tokenTypeSqlBool
+}
+
+func (this *parser) parseSqlValue(val *string) request {
+	tok := this.tokens.Produce()
+	if !isValueToken(tok) {
+		return this.parseError("expected valid value")
+	}
+	*val = tok.val
+	return nil
+}
+
 func (this *parser) parseTableName(table *string) request {
 	tok := this.tokens.Produce()
 	if tok.typ != tokenTypeSqlTable {
@@ -93,19 +162,177 @@ func (this *parser) parseSqlWhere(filter *sqlFilter, tok *token) request {
 	if tok != nil && tok.typ != tokenTypeSqlWhere {
 		return this.parseError("expected where clause")
 	}
-	return this.parseSqlEqualVal(&(filter.columnValue), nil)
+	// not prefix, double negation cancels out
+	tok = this.tokens.Produce()
+	for tok.typ == tokenTypeSqlNot {
+		filter.not = !filter.not
+		tok = this.tokens.Produce()
+	}
+	// column
+	if tok.typ != tokenTypeSqlColumn {
+		return this.parseError("expected column name")
+	}
+	filter.col = tok.val
+	tok = this.tokens.Produce()
+	var errreq request
+	switch tok.typ {
+	case tokenTypeSqlEqual:
+		errreq = this.parseSqlValue(&filter.val)
+		if errreq == nil {
+			errreq = this.parseSqlCollate(filter)
+		}
+	case tokenTypeSqlBetween:
+		errreq = this.parseSqlBetween(filter)
+	case tokenTypeSqlIn:
+		if filter.not {
+			return this.parseError("not is not supported with in")
+		}
+		filter, errreq = this.parseSqlIn(filter)
+	default:
+		return this.parseError("expected = sign, between or in")
+	}
+	if errreq != nil {
+		return errreq
+	}
+	// or another condition
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlOr {
+		this.tokens.unread(tok)
+		return nil
+	}
+	filter.or = new(sqlFilter)
+	return this.parseSqlWhere(filter.or, nil)
+}
+
+// Parses optional collate nocase of equality condition.
+func (this *parser) parseSqlCollate(filter *sqlFilter) request {
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlCollate {
+		this.tokens.unread(tok)
+		return nil
+	}
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlValue || strings.ToLower(tok.val) != "nocase" {
+		return this.parseError("unsupported collation " + tok.val + ": expected nocase")
+	}
+	filter.nocase = true
+	return nil
+}
+
+// Parses in (value, ...) list as or conditions of equality on the same column.
+// Returns the last condition so that following or conditions are chained after it.
+func (this *parser) parseSqlIn(filter *sqlFilter) (*sqlFilter, request) {
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlLeftParenthesis {
+		return nil, this.parseError("expected ( ")
+	}
+	last := filter
+	for first := true; ; first = false {
+		if !first {
+			last.or = new(sqlFilter)
+			last = last.or
+			last.col = filter.col
+		}
+		if errreq := this.parseSqlValue(&last.val); errreq != nil {
+			return nil, errreq
+		}
+		tok = this.tokens.Produce()
+		switch tok.typ {
+		case tokenTypeSqlRightParenthesis:
+			return last, nil
+		case tokenTypeSqlComma:
+		default:
+			return nil, this.parseError("expected , or ) ")
+		}
+	}
+}
+
+// Parses between from and to range.
+func (this *parser) parseSqlBetween(filter *sqlFilter) request {
+	if errreq := this.parseSqlValue(&filter.val); errreq != nil {
+		return errreq
+	}
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlAnd {
+		return this.parseError("expected and")
+	}
+	if errreq := this.parseSqlValue(&filter.to); errreq != nil {
+		return errreq
+	}
+	filter.between = true
+	return nil
 }
 
 // STATUS cmd
 func (this *parser) parseCmdStatus() request {
 	// into
 	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeCmdTables {
+		tok = this.tokens.Produce()
+		if tok.typ != tokenTypeEOF {
+			return this.parseError("unexpected extra token")
+		}
+		return new(cmdStatusTablesRequest)
+	}
 	if tok.typ != tokenTypeEOF {
 		return this.parseError("unexpected extra token")
 	}
 	return new(cmdStatusRequest)
 }
 
+// SHOW cmd
+func (this *parser) parseCmdShow() request {
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeCmdTables {
+		return this.parseError("expected tables")
+	}
+	return this.parseEOF(new(cmdShowTablesRequest))
+}
+
+// PING cmd
+func (this *parser) parseCmdPing() request {
+	return this.parseEOF(new(cmdPingRequest))
+}
+
+// SET SESSION cmd
+func (this *parser) parseCmdSet() request {
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeCmdSession {
+		return this.parseError("expected session")
+	}
+	req := new(cmdSetSessionRequest)
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlColumn {
+		return this.parseError("expected setting name")
+	}
+	req.name = tok.val
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlEqual {
+		return this.parseError("expected =")
+	}
+	tok = this.tokens.Produce()
+	if !isValueToken(tok) {
+		return this.parseError("expected setting value")
+	}
+	req.value = tok.val
+	return this.parseEOF(req)
+}
+
+// BEGIN cmd
+func (this *parser) parseCmdBegin() request {
+	return this.parseEOF(new(cmdBeginRequest))
+}
+
+// COMMIT cmd
+func (this *parser) parseCmdCommit() request {
+	return this.parseEOF(new(cmdCommitRequest))
+}
+
+// ROLLBACK cmd
+func (this *parser) parseCmdRollback() request {
+	return this.parseEOF(new(cmdRollbackRequest))
+}
+
 // STOP cmd
 func (this *parser) parseCmdStop() request {
 	// into
@@ -187,7 +414,22 @@ func (this *parser) parseSqlInsert() request {
 		s := fmt.Sprintf("number of columns:%d and values:%d do not match", columns, values)
 		return this.parseError(s)
 	}
-	return this.returningColumnsHelper(nil, req, &req.returningColumns)
+	return this.parseSqlInsertTtl(req, req)
+}
+
+// Parses optional ttl clause followed by optional returning clause.
+func (this *parser) parseSqlInsertTtl(req request, insert *sqlInsertRequest) request {
+	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeSqlTtl {
+		tok = this.tokens.Produce()
+		ttl, err := strconv.Atoi(tok.val)
+		if tok.typ != tokenTypeSqlValue || err != nil || ttl < 1 {
+			return this.parseError("ttl expects positive number of seconds")
+		}
+		insert.ttl = ttl
+		tok = nil
+	}
+	return this.returningColumnsHelper(tok, req, &insert.returningColumns)
 }
 
 func (this *parser) returningColumnsHelper(tok *token, req request, r *returningColumns) request {
@@ -213,6 +455,15 @@ func (this *parser) returningColumnsHelper(tok *token, req request, r *returning
 	return req
 }
 
+// Parses sql upsert statement and returns sqlUpsertRequest on success.
+func (this *parser) parseSqlUpsert() request {
+	req := this.parseSqlInsert()
+	if insert, ok := req.(*sqlInsertRequest); ok {
+		return &sqlUpsertRequest{sqlInsertRequest: *insert}
+	}
+	return req
+}
+
 // Parses sql push statement and returns sqlInsertRequest on success.
 func (this *parser) parseSqlPush() request {
 	req := newSqlPushRequest()
@@ -278,7 +529,70 @@ func (this *parser) parseSqlPush() request {
 		s := fmt.Sprintf("number of columns:%d and values:%d do not match", columns, values)
 		return this.parseError(s)
 	}
-	return this.returningColumnsHelper(nil, req, &req.returningColumns)
+	return this.parseSqlInsertTtl(req, &req.sqlInsertRequest)
+}
+
+// COPY sql statement
+
+func (this *parser) parseSqlCopy() request {
+	// into
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlInto {
+		return this.parseError("expected into")
+	}
+	req := &sqlCopyRequest{
+		cols: make([]string, 0, config.PARSER_SQL_INSERT_REQUEST_COLUMN_CAPACITY),
+	}
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	// (
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlLeftParenthesis {
+		return this.parseError("expected ( ")
+	}
+	// columns
+	expectedType := tokenTypeSqlColumn
+	var errreq request
+	var str string
+	for expectedType == tokenTypeSqlColumn {
+		errreq, expectedType, str = this.parseSqlInsertColumn()
+		if errreq != nil {
+			return errreq
+		}
+		req.cols = append(req.cols, str)
+	}
+	// from stream
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlFrom {
+		return this.parseError("expected from")
+	}
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlStream {
+		return this.parseError("expected stream")
+	}
+	// rows
+	for tok = this.tokens.Produce(); tok.typ == tokenTypeSqlLeftParenthesis; tok = this.tokens.Produce() {
+		values := make([]string, 0, len(req.cols))
+		expectedType = tokenTypeSqlValue
+		for expectedType == tokenTypeSqlValue {
+			errreq, expectedType, str = this.parseSqlInsertValue()
+			if errreq != nil {
+				return errreq
+			}
+			values = append(values, str)
+		}
+		if len(values) != len(req.cols) {
+			s := fmt.Sprintf("number of columns:%d and values:%d do not match in row:%d", len(req.cols), len(values), len(req.rows)+1)
+			return this.parseError(s)
+		}
+		req.rows = append(req.rows, values)
+	}
+	if tok.typ != tokenTypeEOF {
+		return this.parseError("expected ( or EOF")
+	}
+	return req
 }
 
 func (this *parser) parseSqlInsertColumn() (request, tokenType, string) {
@@ -299,7 +613,7 @@ func (this *parser) parseSqlInsertColumn() (request, tokenType, string) {
 
 func (this *parser) parseSqlInsertValue() (request, tokenType, string) {
 	tok := this.tokens.Produce()
-	if tok.typ != tokenTypeSqlValue {
+	if !isValueToken(tok) {
 		return this.parseError("expected value"), tokenTypeError, ""
 	}
 	str := tok.val
@@ -340,12 +654,28 @@ func (this *parser) parseSqlSelect() request {
 	// *
 	req := newSqlSelectRequest()
 	tok := this.tokens.Produce()
+	// distinct
+	if tok.typ == tokenTypeSqlDistinct {
+		req.distinct = true
+		tok = this.tokens.Produce()
+		if tok.typ == tokenTypeSqlStar {
+			return this.parseError("distinct requires column names")
+		}
+	}
 	if tok.typ != tokenTypeSqlStar {
-		if errreq := this.parseReturningColumns(&tok, &req.returningColumns); errreq != nil {
+		if errreq := this.parseSqlSelectColumns(&tok, req); errreq != nil {
 			return errreq
 		}
 	} else {
 		tok = this.tokens.Produce()
+		// all columns followed by more columns
+		if tok.typ == tokenTypeSqlComma {
+			req.allColumns = true
+			tok = this.tokens.Produce()
+			if errreq := this.parseSqlSelectColumns(&tok, req); errreq != nil {
+				return errreq
+			}
+		}
 	}
 	// from
 	if tok.typ != tokenTypeSqlFrom {
@@ -358,16 +688,221 @@ func (this *parser) parseSqlSelect() request {
 	// possible eof
 	tok = this.tokens.Produce()
 	if tok.typ == tokenTypeEOF {
-		return req
+		return this.validateSqlSelectGroupBy(req)
+	}
+	// into
+	if tok.typ == tokenTypeSqlInto {
+		if errreq := this.parseTableName(&req.into); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
 	}
 	// where
-	if errreq := this.parseSqlWhere(&(req.filter), tok); errreq != nil {
+	if tok.typ == tokenTypeSqlWhere {
+		if errreq := this.parseSqlWhere(&(req.filter), tok); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
+	}
+	// group by
+	if tok.typ == tokenTypeSqlGroup {
+		if errreq := this.parseSqlGroupBy(&tok, req); errreq != nil {
+			return errreq
+		}
+	}
+	// order by
+	if tok.typ == tokenTypeSqlOrder {
+		if errreq := this.parseSqlOrderBy(&tok, req); errreq != nil {
+			return errreq
+		}
+	}
+	// limit offset
+	if errreq := this.parseSqlLimit(&req.limit, &tok); errreq != nil {
 		return errreq
 	}
+	if tok.typ != tokenTypeEOF {
+		return this.parseError("expected EOF")
+	}
 	// we are good
+	return this.validateSqlSelectGroupBy(req)
+}
+
+// Parses select columns that may include count(*) aggregate.
+func (this *parser) parseSqlSelectColumns(tok **token, req *sqlSelectRequest) request {
+	nextIsColumn := true
+	for {
+		if nextIsColumn {
+			switch (*tok).typ {
+			case tokenTypeSqlCount:
+				req.addColumn((*tok).val)
+				req.aliases = append(req.aliases, "")
+			case tokenTypeSqlColumn, tokenTypeSqlValue:
+				if errreq := this.parseSqlConcat(tok, req); errreq != nil {
+					return errreq
+				}
+				req.aliases = append(req.aliases, "")
+			default:
+				return this.parseError("expected column name")
+			}
+			nextIsColumn = false
+		} else if (*tok).typ == tokenTypeSqlAs {
+			*tok = this.tokens.Produce()
+			if (*tok).typ != tokenTypeSqlColumn {
+				return this.parseError("expected alias")
+			}
+			req.aliases[len(req.aliases)-1] = (*tok).val
+		} else {
+			if (*tok).typ != tokenTypeSqlComma {
+				break
+			}
+			nextIsColumn = true
+		}
+		*tok = this.tokens.Produce()
+	}
+	return this.validateSqlSelectAliases(req)
+}
+
+// Parses select column that is either a plain column or operands joined by || operator.
+// Concatenation is named after its expression unless it is aliased.
+func (this *parser) parseSqlConcat(tok **token, req *sqlSelectRequest) request {
+	operands := []sqlConcatOperand{newSqlConcatOperand(*tok)}
+	for {
+		next := this.tokens.Produce()
+		if next.typ != tokenTypeSqlConcat {
+			this.tokens.unread(next)
+			break
+		}
+		*tok = this.tokens.Produce()
+		if (*tok).typ != tokenTypeSqlColumn && (*tok).typ != tokenTypeSqlValue {
+			return this.parseError("expected column name or value after ||")
+		}
+		operands = append(operands, newSqlConcatOperand(*tok))
+	}
+	if len(operands) == 1 && operands[0].column {
+		req.addColumn(operands[0].val)
+		return nil
+	}
+	req.addConcat(operands)
+	return nil
+}
+
+// Validates that column aliases do not collide with other column names or aliases.
+func (this *parser) validateSqlSelectAliases(req *sqlSelectRequest) request {
+	for idx, alias := range req.aliases {
+		if alias == "" {
+			continue
+		}
+		for other, col := range req.cols {
+			if other != idx && (col == alias || req.aliases[other] == alias) {
+				return this.parseError("alias " + alias + " collides with another column")
+			}
+		}
+	}
+	return nil
+}
+
+// Parses group by column list.
+func (this *parser) parseSqlGroupBy(tok **token, req *sqlSelectRequest) request {
+	*tok = this.tokens.Produce()
+	if (*tok).typ != tokenTypeSqlBy {
+		return this.parseError("expected by")
+	}
+	var groupBy returningColumns
+	*tok = this.tokens.Produce()
+	if errreq := this.parseReturningColumns(tok, &groupBy); errreq != nil {
+		return errreq
+	}
+	req.groupBy = groupBy.cols
+	return nil
+}
+
+// Parses comma separated order by columns each with optional direction.
+func (this *parser) parseSqlOrderBy(tok **token, req *sqlSelectRequest) request {
+	if req.isAggregate() {
+		return this.parseError("order by is not supported with aggregates")
+	}
+	*tok = this.tokens.Produce()
+	if (*tok).typ != tokenTypeSqlBy {
+		return this.parseError("expected by")
+	}
+	orderBy := &req.orderBy
+	for {
+		*tok = this.tokens.Produce()
+		if (*tok).typ != tokenTypeSqlColumn {
+			return this.parseError("expected column name")
+		}
+		orderBy.col = (*tok).val
+		orderBy.use = true
+		*tok = this.tokens.Produce()
+		if (*tok).typ == tokenTypeSqlOrderDirection {
+			orderBy.desc = strings.ToLower((*tok).val) == "desc"
+			*tok = this.tokens.Produce()
+		}
+		if (*tok).typ != tokenTypeSqlComma {
+			return nil
+		}
+		orderBy.then = new(sqlOrderBy)
+		orderBy = orderBy.then
+	}
+}
+
+// Validates that selected columns are either aggregates or group by columns.
+func (this *parser) validateSqlSelectGroupBy(req *sqlSelectRequest) request {
+	if !req.isAggregate() {
+		return req
+	}
+	if len(req.cols) == 0 {
+		return this.parseError("group by requires column names")
+	}
+	if len(req.concats) > 0 {
+		return this.parseError("concatenation is not supported with group by or count(*)")
+	}
+	if req.allColumns {
+		return this.parseError("* is not supported with group by or count(*)")
+	}
+	for _, col := range req.cols {
+		if col != sqlCountStar && !req.isGroupByColumn(col) {
+			return this.parseError("column " + col + " must appear in group by")
+		}
+	}
 	return req
 }
 
+// Parses optional limit and offset clauses in any order.
+func (this *parser) parseSqlLimit(limit *sqlLimit, tok **token) request {
+	offset := false
+	for {
+		var val *int
+		switch (*tok).typ {
+		case tokenTypeSqlLimit:
+			if limit.use {
+				return this.parseError("limit was already specified")
+			}
+			limit.use = true
+			val = &limit.count
+		case tokenTypeSqlOffset:
+			if offset {
+				return this.parseError("offset was already specified")
+			}
+			offset = true
+			val = &limit.offset
+		default:
+			return nil
+		}
+		name := (*tok).val
+		*tok = this.tokens.Produce()
+		if (*tok).typ != tokenTypeSqlValue {
+			return this.parseError("expected " + name + " value")
+		}
+		n, err := strconv.Atoi((*tok).val)
+		if err != nil || n < 0 {
+			return this.parseError(name + " must be a non negative integer")
+		}
+		*val = n
+		*tok = this.tokens.Produce()
+	}
+}
+
 // Parses sql peek statement and returns sqlPeekRequest on success.
 func (this *parser) parseSqlPeek() request {
 	req := newSqlPeekRequest()
@@ -478,7 +1013,13 @@ loop:
 			if errreq := this.parseSqlWhere(&(req.filter), tok); errreq != nil {
 				return errreq
 			}
-			tok = nil
+			tok = this.tokens.Produce()
+			if tok.typ == tokenTypeSqlAnd {
+				if errreq := this.parseSqlUpdateVersion(req); errreq != nil {
+					return errreq
+				}
+				tok = nil
+			}
 			break loop
 		case tokenTypeSqlReturning:
 			break loop
@@ -488,6 +1029,11 @@ loop:
 		case tokenTypeSqlComma:
 			continue
 
+		case tokenTypeSqlArithmetic:
+			if errreq := this.parseSqlUpdateIncrement(req, tok); errreq != nil {
+				return errreq
+			}
+
 		default:
 			return this.parseError("expected.col or where keyword")
 		}
@@ -498,6 +1044,50 @@ loop:
 	return this.returningColumnsHelper(tok, req, &req.returningColumns)
 }
 
+// Parses + number or - number that follows column value of update assignment.
+func (this *parser) parseSqlUpdateIncrement(req *sqlUpdateRequest, op *token) request {
+	idx := len(req.colVals) - 1
+	if idx < 0 || req.increments[idx] != nil {
+		return this.parseError("unexpected " + op.val)
+	}
+	var number string
+	if errreq := this.parseSqlValue(&number); errreq != nil {
+		return errreq
+	}
+	inc := newSqlIncrement(req.colVals[idx].val, op.val, number)
+	if !inc.valid() {
+		return this.parseError("expected number but got " + number)
+	}
+	if req.increments == nil {
+		req.increments = make(map[int]*sqlIncrement)
+	}
+	req.increments[idx] = inc
+	return nil
+}
+
+// Parses version = value condition of optimistic update.
+func (this *parser) parseSqlUpdateVersion(req *sqlUpdateRequest) request {
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlColumn || tok.val != "version" {
+		return this.parseError("expected version")
+	}
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlEqual {
+		return this.parseError("expected = sign")
+	}
+	var val string
+	if errreq := this.parseSqlValue(&val); errreq != nil {
+		return errreq
+	}
+	version, err := strconv.Atoi(val)
+	if err != nil || version < 0 {
+		return this.parseError("version must be a non-negative integer")
+	}
+	req.version = version
+	req.useVersion = true
+	return nil
+}
+
 // DELETE sql statement
 
 // Parses sql delete statement and returns sqlDeleteRequest on success.
@@ -526,6 +1116,157 @@ func (this *parser) parseSqlDelete() request {
 	return this.returningColumnsHelper(tok, req, &req.returningColumns)
 }
 
+// TRUNCATE sql statement
+
+// Parses sql truncate statement and returns sqlTruncateRequest on success.
+func (this *parser) parseSqlTruncate() request {
+	req := new(sqlTruncateRequest)
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	return this.parseEOF(req)
+}
+
+// SAVE and LOAD sql statements
+
+// Parses sql save statement and returns sqlSaveRequest on success.
+func (this *parser) parseSqlSave() request {
+	req := new(sqlSaveRequest)
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	// to
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlTo {
+		return this.parseError("expected to")
+	}
+	// file
+	if errreq := this.parseSqlValue(&req.file); errreq != nil {
+		return errreq
+	}
+	return this.parseEOF(req)
+}
+
+// Parses sql load statement and returns sqlLoadRequest on success.
+func (this *parser) parseSqlLoad() request {
+	req := new(sqlLoadRequest)
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	// from
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlFrom {
+		return this.parseError("expected from")
+	}
+	// file
+	if errreq := this.parseSqlValue(&req.file); errreq != nil {
+		return errreq
+	}
+	// optional replace
+	tok = this.tokens.Produce()
+	if tok.typ == tokenTypeSqlReplace {
+		req.replace = true
+		return this.parseEOF(req)
+	}
+	if tok.typ != tokenTypeEOF {
+		return this.parseError("expected replace or EOF")
+	}
+	return req
+}
+
+// DESCRIBE sql statement
+
+// Parses sql describe statement and returns sqlDescribeRequest on success.
+func (this *parser) parseSqlDescribe() request {
+	req := new(sqlDescribeRequest)
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	return this.parseEOF(req)
+}
+
+// CREATE sql statement
+
+// Parses sql create table statement and returns sqlCreateRequest on success.
+func (this *parser) parseSqlCreate() request {
+	req := new(sqlCreateRequest)
+	// if not exists
+	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeSqlIf {
+		if this.tokens.Produce().typ != tokenTypeSqlNot || this.tokens.Produce().typ != tokenTypeSqlExists {
+			return this.parseError("expected if not exists")
+		}
+		req.ifNotExists = true
+	} else {
+		this.tokens.unread(tok)
+	}
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	// (
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlLeftParenthesis {
+		return this.parseError("expected ( ")
+	}
+	// columns with optional types
+	for {
+		var def columnDefinition
+		if errreq := this.parseColumnName(&def.name); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
+		if tok.typ == tokenTypeSqlColumnType {
+			dataType, ok := columnDataTypeFromString(strings.ToLower(tok.val))
+			if !ok {
+				return this.parseError("invalid column type " + tok.val + " expected int, float, string, bool or blob")
+			}
+			def.dataType = dataType
+			tok = this.tokens.Produce()
+		}
+		req.columns = append(req.columns, def)
+		switch tok.typ {
+		case tokenTypeSqlComma:
+			continue
+		case tokenTypeSqlRightParenthesis:
+			return this.parseSqlCreateWith(req)
+		}
+		return this.parseError("expected , or )")
+	}
+}
+
+// Parses optional with id = sequential | uuid table option.
+func (this *parser) parseSqlCreateWith(req *sqlCreateRequest) request {
+	tok := this.tokens.Produce()
+	if tok.typ != tokenTypeSqlWith {
+		this.tokens.unread(tok)
+		return this.parseEOF(req)
+	}
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlColumn || tok.val != "id" {
+		return this.parseError("expected id option")
+	}
+	tok = this.tokens.Produce()
+	if tok.typ != tokenTypeSqlEqual {
+		return this.parseError("expected =")
+	}
+	tok = this.tokens.Produce()
+	if !isValueToken(tok) {
+		return this.parseError("expected id format")
+	}
+	switch format := strings.ToLower(tok.val); format {
+	case idFormatSequential, idFormatUuid:
+		req.idFormat = format
+	default:
+		return this.parseError("invalid id format " + tok.val + " expected sequential or uuid")
+	}
+	return this.parseEOF(req)
+}
+
 // KEY sql statement
 
 // Parses sql key statement and returns sqlKeyRequest on success.
@@ -555,6 +1296,31 @@ func (this *parser) parseSqlTag() request {
 	if errreq := this.parseColumnName(&req.column); errreq != nil {
 		return errreq
 	}
+	// multi
+	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeSqlMulti {
+		req.multiValue = true
+		return this.parseEOF(req)
+	}
+	if tok.typ != tokenTypeEOF {
+		return this.parseError("expected multi or EOF")
+	}
+	return req
+}
+
+// RANGE sql statement
+
+// Parses sql range statement and returns sqlRangeRequest on success.
+func (this *parser) parseSqlRange() request {
+	req := new(sqlRangeRequest)
+	// table name
+	if errreq := this.parseTableName(&req.table); errreq != nil {
+		return errreq
+	}
+	// column name
+	if errreq := this.parseColumnName(&req.column); errreq != nil {
+		return errreq
+	}
 	return this.parseEOF(req)
 }
 
@@ -564,6 +1330,10 @@ func (this *parser) parseSqlTag() request {
 func (this *parser) parseSqlSubscribe() request {
 	tok := this.tokens.Produce()
 	if tok.typ == tokenTypeSqlTopic {
+		// schema is reserved topic of schema changes
+		if strings.ToLower(tok.val) == "schema" {
+			return new(sqlSubscribeSchemaRequest)
+		}
 		return &sqlSubscribeTopicRequest { topic: tok.val }
 	}
 	req := new(sqlSubscribeRequest)
@@ -573,11 +1343,20 @@ func (this *parser) parseSqlSubscribe() request {
 		tok = this.tokens.Produce()
 	}
 
-	if tok.typ != tokenTypeSqlStar {
-		return this.parseError("expected * symbol")
+	// * or watched columns
+	switch tok.typ {
+	case tokenTypeSqlStar:
+		tok = this.tokens.Produce()
+	case tokenTypeSqlColumn:
+		var watched returningColumns
+		if errreq := this.parseReturningColumns(&tok, &watched); errreq != nil {
+			return errreq
+		}
+		req.columns = watched.cols
+	default:
+		return this.parseError("expected * symbol or column names")
 	}
 	// from
-	tok = this.tokens.Produce()
 	if tok.typ != tokenTypeSqlFrom {
 		return this.parseError("expected from")
 	}
@@ -591,19 +1370,101 @@ func (this *parser) parseSqlSubscribe() request {
 		return req
 	}
 	// where
-	if errreq := this.parseSqlWhere(&(req.filter), tok); errreq != nil {
-		return errreq
+	if tok.typ != tokenTypeSqlHaving && tok.typ != tokenTypeSqlSince {
+		if errreq := this.parseSqlWhere(&(req.filter), tok); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
+	}
+	// having
+	if tok.typ == tokenTypeSqlHaving {
+		if errreq := this.parseSqlHavingDelta(req); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
+	}
+	// since
+	if tok.typ == tokenTypeSqlSince {
+		if errreq := this.parseSqlSince(req); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
+	}
+	if tok.typ != tokenTypeEOF {
+		return this.parseError("expected EOF")
 	}
 	// we are good
 	return req
 }
 
+// Parses delta(column) > value thresholds joined by and.
+func (this *parser) parseSqlHavingDelta(req *sqlSubscribeRequest) request {
+	req.thresholds = make(map[string]float64)
+	for {
+		tok := this.tokens.Produce()
+		if tok.typ != tokenTypeSqlDelta {
+			return this.parseError("expected delta")
+		}
+		tok = this.tokens.Produce()
+		if tok.typ != tokenTypeSqlLeftParenthesis {
+			return this.parseError("expected ( ")
+		}
+		var column string
+		if errreq := this.parseColumnName(&column); errreq != nil {
+			return errreq
+		}
+		tok = this.tokens.Produce()
+		if tok.typ != tokenTypeSqlRightParenthesis {
+			return this.parseError("expected ) ")
+		}
+		tok = this.tokens.Produce()
+		if tok.typ != tokenTypeSqlGreater {
+			return this.parseError("expected > ")
+		}
+		var val string
+		if errreq := this.parseSqlValue(&val); errreq != nil {
+			return errreq
+		}
+		delta, err := strconv.ParseFloat(val, 64)
+		if err != nil || delta < 0 {
+			return this.parseError("expected non negative number but got " + val)
+		}
+		req.thresholds[column] = delta
+		tok = this.tokens.Produce()
+		if tok.typ != tokenTypeSqlAnd {
+			this.tokens.unread(tok)
+			return nil
+		}
+	}
+}
+
+// Parses sequence number of the last change seen by resuming subscriber.
+func (this *parser) parseSqlSince(req *sqlSubscribeRequest) request {
+	if req.skip {
+		return this.parseError("can not use since with skip")
+	}
+	var val string
+	if errreq := this.parseSqlValue(&val); errreq != nil {
+		return errreq
+	}
+	seq, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return this.parseError("invalid since sequence number " + val)
+	}
+	req.since = &seq
+	return nil
+}
+
 // UNSUBSCRIBE sql statement
 
 // Parses sql unsubscribe statement and returns sqlUnsubscribeRequest on success.
 func (this *parser) parseSqlUnsubscribe() request {
-	// from
+	// all
 	tok := this.tokens.Produce()
+	if tok.typ == tokenTypeSqlAll {
+		return this.parseEOF(new(sqlUnsubscribeAllRequest))
+	}
+	// from
 	if tok.typ != tokenTypeSqlFrom {
 		return this.parseError("expected from")
 	}
@@ -634,12 +1495,20 @@ func (this *parser) run() request {
 		return this.run()
 	case tokenTypeSqlInsert:
 		return this.parseSqlInsert()
+	case tokenTypeSqlUpsert:
+		return this.parseSqlUpsert()
+	case tokenTypeSqlCopy:
+		return this.parseSqlCopy()
 	case tokenTypeSqlSelect:
 		return this.parseSqlSelect()
 	case tokenTypeSqlUpdate:
 		return this.parseSqlUpdate()
 	case tokenTypeSqlDelete:
 		return this.parseSqlDelete()
+	case tokenTypeSqlTruncate:
+		return this.parseSqlTruncate()
+	case tokenTypeSqlCreate:
+		return this.parseSqlCreate()
 	case tokenTypeSqlPush:
 		return this.parseSqlPush()
 	case tokenTypeSqlPop:
@@ -654,8 +1523,28 @@ func (this *parser) run() request {
 		return this.parseSqlKey()
 	case tokenTypeSqlTag:
 		return this.parseSqlTag()
+	case tokenTypeSqlRange:
+		return this.parseSqlRange()
+	case tokenTypeSqlDescribe:
+		return this.parseSqlDescribe()
+	case tokenTypeSqlSave:
+		return this.parseSqlSave()
+	case tokenTypeSqlLoad:
+		return this.parseSqlLoad()
 	case tokenTypeCmdStatus:
 		return this.parseCmdStatus()
+	case tokenTypeCmdShow:
+		return this.parseCmdShow()
+	case tokenTypeCmdPing:
+		return this.parseCmdPing()
+	case tokenTypeCmdSet:
+		return this.parseCmdSet()
+	case tokenTypeCmdBegin:
+		return this.parseCmdBegin()
+	case tokenTypeCmdCommit:
+		return this.parseCmdCommit()
+	case tokenTypeCmdRollback:
+		return this.parseCmdRollback()
 	case tokenTypeCmdStop:
 		return this.parseCmdStop()
 	case tokenTypeCmdClose:
@@ -666,13 +1555,70 @@ func (this *parser) run() request {
 	return this.parseError("invalid request")
 }
 
+// validateColumnTables validates that table qualifiers of column names
+// match the table of the request.
+func (this *parser) validateColumnTables(req request, tables []string) request {
+	if len(tables) == 0 || req.getRequestType() != requestTypeSql {
+		return nil
+	}
+	for _, table := range tables {
+		if table != req.getTableName() {
+			return this.parseError("column qualifier " + table + " does not match table " + req.getTableName())
+		}
+	}
+	return nil
+}
+
+// Scans and parses the message of one or more statements separated by ;.
+// Message of several statements is parsed to sqlScriptRequest, which is
+// rejected as a whole when any of the statements is not valid.
+func parseMessage(message string, tokens *tokensProducerConsumer) request {
+	var statements []string
+	if strings.IndexByte(message, ';') >= 0 {
+		statements = splitStatements(message)
+	}
+	if len(statements) < 2 {
+		tokens.reuse()
+		lex(message, tokens)
+		return parse(tokens)
+	}
+	script := new(sqlScriptRequest)
+	for idx, stmt := range statements {
+		tokens.reuse()
+		lex(stmt, tokens)
+		req := parse(tokens)
+		prefix := "statement " + strconv.Itoa(idx+1) + ": "
+		if errreq, ok := req.(*errorRequest); ok {
+			errreq.err = prefix + errreq.err
+			return errreq
+		}
+		if !isScriptable(req) {
+			return &errorRequest{err: prefix + "statement can not be part of script"}
+		}
+		script.requests = append(script.requests, req)
+	}
+	return script
+}
+
 // Parses tokens and returns an request.
+// Syntax errors reported by the lexer take precedence over parse errors.
 func parse(tokens tokenProducer) request {
+	lexerTokens := &lexerErrorTokens{
+		tokens: tokens,
+	}
+	qualifiedTokens := &qualifiedColumnTokens{
+		tokens: lexerTokens,
+	}
 	parser := &parser{
-		tokens:    tokens,
+		tokens:    &unreadTokens{tokens: qualifiedTokens},
 		streaming: false,
 	}
 	req := parser.run()
+	if lexerTokens.err != nil {
+		req = parser.parseError(lexerTokens.err.val)
+	} else if errreq := parser.validateColumnTables(req, qualifiedTokens.tables); errreq != nil {
+		req = errreq
+	}
 	if parser.streaming {
 		req.setStreaming()
 	}
@@ -49,6 +49,42 @@ func TestParseCmdStatus(t *testing.T) {
 	validateStatus(t, req)
 }
 
+func TestParseCmdShowTables(t *testing.T) {
+	pc := newTokens()
+	lex(" show tables ", pc)
+	switch req := parse(pc); req.(type) {
+	case *cmdShowTablesRequest:
+	default:
+		t.Errorf("parse error: invalid request type expected cmdShowTablesRequest but got %T", req)
+	}
+	//
+	pc = newTokens()
+	lex(" show ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" show tables stocks ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseCmdStatusTables(t *testing.T) {
+	pc := newTokens()
+	lex(" status tables ", pc)
+	switch req := parse(pc); req.(type) {
+	case *cmdStatusTablesRequest:
+	default:
+		t.Errorf("parse error: invalid request type expected cmdStatusTablesRequest but got %T", req)
+	}
+	//
+	pc = newTokens()
+	lex(" status tables stocks ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" status stocks ", pc)
+	expectedError(t, parse(pc))
+}
+
 // STOP
 func validateStop(t *testing.T, req request) {
 	switch req.(type) {
@@ -70,6 +106,64 @@ func TestParseCmdStop(t *testing.T) {
 	validateStop(t, req)
 }
 
+// PING
+func TestParseCmdPing(t *testing.T) {
+	pc := newTokens()
+	lex(" ping ", pc)
+	if _, ok := parse(pc).(*cmdPingRequest); !ok {
+		t.Errorf("parse error: invalid request type expected cmdPingRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" ping stocks ", pc)
+	expectedError(t, parse(pc))
+}
+
+// SET SESSION
+func TestParseCmdSetSession(t *testing.T) {
+	pc := newTokens()
+	lex(" set session strict = 'false' ", pc)
+	req, ok := parse(pc).(*cmdSetSessionRequest)
+	if !ok {
+		t.Errorf("parse error: invalid request type expected cmdSetSessionRequest")
+	} else if req.name != "strict" || req.value != "false" {
+		t.Errorf("parse error: unexpected setting %s = %s", req.name, req.value)
+	}
+	//
+	pc = newTokens()
+	lex(" set session strict ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" set strict = 1 ", pc)
+	expectedError(t, parse(pc))
+}
+
+// BEGIN COMMIT ROLLBACK
+func TestParseCmdTransaction(t *testing.T) {
+	pc := newTokens()
+	lex(" begin ", pc)
+	if _, ok := parse(pc).(*cmdBeginRequest); !ok {
+		t.Errorf("parse error: invalid request type expected cmdBeginRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" commit ", pc)
+	if _, ok := parse(pc).(*cmdCommitRequest); !ok {
+		t.Errorf("parse error: invalid request type expected cmdCommitRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" rollback ", pc)
+	if _, ok := parse(pc).(*cmdRollbackRequest); !ok {
+		t.Errorf("parse error: invalid request type expected cmdRollbackRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" commit stocks ", pc)
+	expectedError(t, parse(pc))
+}
+
 // CLOSE
 func validateClose(t *testing.T, req request) {
 	switch req.(type) {
@@ -152,6 +246,52 @@ func TestParseSqlInsertStatement1(t *testing.T) {
 	validateInsert(t, x, &y)
 }
 
+func TestParseSqlInsertBool(t *testing.T) {
+	pc := newTokens()
+	lex(" insert into users (name, active, admin) values (john, TRUE, false) ", pc)
+	x := parse(pc)
+	var y sqlInsertRequest
+	y.table = "users"
+	y.addColVal("name", "john")
+	y.addColVal("active", "true")
+	y.addColVal("admin", "false")
+	validateInsert(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from users where active = False ", pc)
+	if req, ok := parse(pc).(*sqlSelectRequest); !ok || req.filter.val != "false" {
+		t.Errorf("parse error: expected boolean filter value")
+	}
+}
+
+func TestParseSqlInsertTtl(t *testing.T) {
+	pc := newTokens()
+	lex(" insert into cache (key) values (k1) ttl 60 returning *", pc)
+	x := parse(pc)
+	var y sqlInsertRequest
+	y.table = "cache"
+	y.addColVal("key", "k1")
+	y.use = true
+	validateInsert(t, x, &y)
+	if req, ok := x.(*sqlInsertRequest); !ok || req.ttl != 60 {
+		t.Errorf("parse error: expected ttl 60")
+	}
+	//
+	pc = newTokens()
+	lex(" push into cache (key) values (k1) ttl 5", pc)
+	if req, ok := parse(pc).(*sqlPushRequest); !ok || req.ttl != 5 {
+		t.Errorf("parse error: expected push with ttl 5")
+	}
+	//
+	pc = newTokens()
+	lex(" insert into cache (key) values (k1) ttl 0", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" insert into cache (key) values (k1) ttl soon", pc)
+	expectedError(t, parse(pc))
+}
+
 func TestParseSqlInsertStatement2(t *testing.T) {
 	pc := newTokens()
 	lex(" insert into stocks (ticker, bid, ask) values (IBM, 12, 14.5645) returning *", pc)
@@ -179,6 +319,31 @@ func TestParseSqlInsertStatement3(t *testing.T) {
 	validateInsert(t, x, &y)
 }
 
+func TestParseSqlCopy(t *testing.T) {
+	pc := newTokens()
+	lex(" copy into stocks (ticker, bid) from stream (IBM, 12) (MSFT, 37) ", pc)
+	x := parse(pc)
+	if req, ok := x.(*sqlCopyRequest); !ok {
+		t.Errorf("parse error: expected sqlCopyRequest but got %T", x)
+	} else if req.table != "stocks" || len(req.cols) != 2 || req.cols[1] != "bid" || len(req.rows) != 2 || req.rows[1][0] != "MSFT" || req.rows[1][1] != "37" {
+		t.Errorf("parse error: copy request does not match %v %v", req.cols, req.rows)
+	}
+	//
+	pc = newTokens()
+	lex(" copy into stocks (ticker, bid) from stream ", pc)
+	if req, ok := parse(pc).(*sqlCopyRequest); !ok || len(req.rows) != 0 {
+		t.Errorf("parse error: expected copy request without rows")
+	}
+	//
+	pc = newTokens()
+	lex(" copy into stocks (ticker, bid) from stream (IBM, 12) (MSFT) ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" copy into stocks (ticker, bid) (IBM, 12) ", pc)
+	expectedError(t, parse(pc))
+}
+
 func TestParseSqlInsertStatement4(t *testing.T) {
 	pc := newTokens()
 	lex(" insert ", pc)
@@ -246,6 +411,33 @@ func TestParseSqlInsertStatement4(t *testing.T) {
 	expectedError(t, x)
 }
 
+// UPSERT
+
+func TestParseSqlUpsertStatement(t *testing.T) {
+	pc := newTokens()
+	lex(" upsert into stocks (ticker, bid) values (IBM, 12) returning ticker", pc)
+	x := parse(pc)
+	var y sqlInsertRequest
+	y.table = "stocks"
+	y.addColVal("ticker", "IBM")
+	y.addColVal("bid", "12")
+	y.returningColumns.addColumn("ticker")
+	switch x.(type) {
+	case *sqlUpsertRequest:
+		validateInsert(t, &x.(*sqlUpsertRequest).sqlInsertRequest, &y)
+	default:
+		t.Errorf("parse error: invalid request type expected sqlUpsertRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" upsert stocks (ticker) values (IBM) ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" upsert into stocks (ticker, bid) values (IBM) ", pc)
+	expectedError(t, parse(pc))
+}
+
 // SELECT
 func validateSelect(t *testing.T, a request, y *sqlSelectRequest) {
 	switch a.(type) {
@@ -267,6 +459,18 @@ func validateSelect(t *testing.T, a request, y *sqlSelectRequest) {
 		if x.filter != y.filter {
 			t.Errorf("parse error: filters do not match")
 		}
+		// limit
+		if x.limit != y.limit {
+			t.Errorf("parse error: limits do not match")
+		}
+		// order by
+		if x.orderBy != y.orderBy {
+			t.Errorf("parse error: order by does not match")
+		}
+		// distinct
+		if x.distinct != y.distinct {
+			t.Errorf("parse error: distinct does not match")
+		}
 	default:
 		t.Errorf("parse error: invalid request type expected sqlSelectRequest")
 	}
@@ -281,6 +485,61 @@ func TestParseSqlSelectStatement1(t *testing.T) {
 	validateSelect(t, x, &y)
 }
 
+func TestParseSqlStatementTerminator(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks; ", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex("ping;", pc)
+	if _, ok := parse(pc).(*cmdPingRequest); !ok {
+		t.Errorf("parse error: expected cmdPingRequest")
+	}
+	// only single terminator is accepted
+	pc = newTokens()
+	lex(" select * from stocks;; ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" select * from stocks; select * from bonds ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseMessage(t *testing.T) {
+	tokens := newTokens()
+	x := parseMessage(" select * from stocks; ", tokens)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	validateSelect(t, x, &y)
+	//
+	x = parseMessage(" begin; insert into stocks (ticker) values ('a;b'); commit; select * from stocks ", tokens)
+	script, ok := x.(*sqlScriptRequest)
+	if !ok || len(script.requests) != 4 {
+		t.Fatalf("parse error: expected script of 4 statements but got %T", x)
+	}
+	if _, ok := script.requests[0].(*cmdBeginRequest); !ok {
+		t.Errorf("parse error: expected cmdBeginRequest")
+	}
+	if ins, ok := script.requests[1].(*sqlInsertRequest); !ok || ins.colVals[0].val != "a;b" {
+		t.Errorf("parse error: expected sqlInsertRequest with quoted ;")
+	}
+	validateSelect(t, script.requests[3], &y)
+	// invalid and unsupported statements reject the script
+	for _, message := range []string{
+		" select * from stocks; select * form bonds ",
+		" select * from stocks; subscribe * from stocks ",
+		" select * from stocks; stream insert into stocks (ticker) values (IBM) ",
+		" select * from stocks; stop ",
+	} {
+		x = parseMessage(message, tokens)
+		if errreq, ok := x.(*errorRequest); !ok || errreq.err[:13] != "statement 2: " {
+			t.Errorf("parse error: expected error of statement 2 for %s but got %T", message, x)
+		}
+	}
+}
+
 func TestParseSqlSelectStatement2(t *testing.T) {
 	pc := newTokens()
 	lex(" select ticker, bid, ask  from stocks ", pc)
@@ -303,6 +562,419 @@ func TestParseSqlSelectStatement3(t *testing.T) {
 	validateSelect(t, x, &y)
 }
 
+func TestParseSqlSelectBetween(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks where price between 100 and 'two hundred' ", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.filter.addBetweenFilter("price", "100", "two hundred")
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from stocks where price between 100 ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from stocks where price between 100 or 200", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectNot(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks where not price between 100 and 200 ", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.filter.addBetweenFilter("price", "100", "200")
+	y.filter.not = true
+	validateSelect(t, x, &y)
+	// double negation
+	pc = newTokens()
+	lex(" select * from stocks where not not ticker = IBM ", pc)
+	x = parse(pc)
+	y = sqlSelectRequest{}
+	y.table = "stocks"
+	y.filter.addFilter("ticker", "IBM")
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from stocks where not ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlWhereCollate(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks where name = 'ibm' collate NOCASE or ticker = IBM", pc)
+	req, ok := parse(pc).(*sqlSelectRequest)
+	if !ok || !req.filter.nocase || req.filter.val != "ibm" || req.filter.or == nil || req.filter.or.nocase {
+		t.Errorf("parse error: expected case insensitive first filter only")
+	}
+	//
+	pc = newTokens()
+	lex(" update stocks set bid = 1 where ticker = ibm collate nocase and version = 2", pc)
+	if req, ok := parse(pc).(*sqlUpdateRequest); !ok || !req.filter.nocase || req.version != 2 {
+		t.Errorf("parse error: expected case insensitive update with version")
+	}
+	//
+	pc = newTokens()
+	lex(" select * from stocks where name = ibm collate binary", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlWhereIn(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks where id in (3, 1, 2) or ticker = IBM", pc)
+	req, ok := parse(pc).(*sqlSelectRequest)
+	if !ok {
+		t.Fatalf("parse error: expected sqlSelectRequest")
+	}
+	values := ""
+	for f := &req.filter; f != nil; f = f.or {
+		values += " " + f.col + "=" + f.val
+	}
+	if values != " id=3 id=1 id=2 ticker=IBM" {
+		t.Errorf("parse error: in list does not match or filters%v", values)
+	}
+	//
+	pc = newTokens()
+	lex(" delete from stocks where ticker in (IBM) ", pc)
+	if req, ok := parse(pc).(*sqlDeleteRequest); !ok || req.filter.val != "IBM" || req.filter.or != nil {
+		t.Errorf("parse error: expected single value in list")
+	}
+	//
+	pc = newTokens()
+	lex(" select * from stocks where not id in (1, 2) ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" select * from stocks where id in () ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectOr(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks where ticker = IBM or not bid between 1 and 2 or sector = TECH limit 5", pc)
+	x := parse(pc)
+	req, ok := x.(*sqlSelectRequest)
+	if !ok {
+		t.Fatalf("parse error: expected sqlSelectRequest but got %T", x)
+	}
+	var second, third sqlFilter
+	second.addBetweenFilter("bid", "1", "2")
+	second.not = true
+	third.addFilter("sector", "TECH")
+	filter := req.filter
+	if filter.col != "ticker" || filter.val != "IBM" || filter.or == nil || filter.or.or == nil {
+		t.Fatalf("parse error: or filters do not match")
+	}
+	if *filter.or.or != third {
+		t.Errorf("parse error: third filter does not match")
+	}
+	filter.or.or = nil
+	if *filter.or != second {
+		t.Errorf("parse error: second filter does not match")
+	}
+	if req.limit != (sqlLimit{count: 5, use: true}) {
+		t.Errorf("parse error: limit does not match")
+	}
+	//
+	pc = newTokens()
+	lex(" select * from stocks where ticker = IBM or ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectGroupBy(t *testing.T) {
+	pc := newTokens()
+	lex(" select exchange, count(*) from stocks where sector = TECH group by exchange limit 5", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.addColumn("exchange")
+	y.addColumn(sqlCountStar)
+	y.filter.addFilter("sector", "TECH")
+	y.limit = sqlLimit{count: 5, use: true}
+	validateSelect(t, x, &y)
+	if req, ok := x.(*sqlSelectRequest); !ok || len(req.groupBy) != 1 || req.groupBy[0] != "exchange" || req.cols[1] != sqlCountStar {
+		t.Errorf("parse error: group by does not match")
+	}
+	//
+	pc = newTokens()
+	lex(" select count(*) from stocks", pc)
+	x = parse(pc)
+	y = sqlSelectRequest{}
+	y.table = "stocks"
+	y.addColumn(sqlCountStar)
+	validateSelect(t, x, &y)
+	// columns must be grouped
+	pc = newTokens()
+	lex(" select exchange, ticker, count(*) from stocks group by exchange", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select ticker, count(*) from stocks", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from stocks group by exchange", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select exchange from stocks group exchange", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectInto(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from live into snapshot where exchange = NYSE ", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "live"
+	y.filter.addFilter("exchange", "NYSE")
+	validateSelect(t, x, &y)
+	if req, ok := x.(*sqlSelectRequest); !ok || req.into != "snapshot" {
+		t.Errorf("parse error: into table does not match")
+	}
+	//
+	pc = newTokens()
+	lex(" select * from live into ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" delete from live into snapshot ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectAs(t *testing.T) {
+	pc := newTokens()
+	lex(" select price as p, ticker as symbol, exchange from stocks", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.addColumn("price")
+	y.addColumn("ticker")
+	y.addColumn("exchange")
+	validateSelect(t, x, &y)
+	if req, ok := x.(*sqlSelectRequest); ok {
+		if req.getAlias(0) != "p" || req.getAlias(1) != "symbol" || req.getAlias(2) != "" {
+			t.Errorf("parse error: aliases do not match %v", req.aliases)
+		}
+	}
+	// alias colliding with another column
+	pc = newTokens()
+	lex(" select price as ticker, ticker from stocks", pc)
+	expectedError(t, parse(pc))
+	// alias colliding with another alias
+	pc = newTokens()
+	lex(" select price as p, ticker as p from stocks", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select price as from stocks", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectConcat(t *testing.T) {
+	pc := newTokens()
+	lex(" select ticker || ' @ ' || exchange as label, price from stocks", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.addConcat([]sqlConcatOperand{{val: "ticker", column: true}, {val: " @ "}, {val: "exchange", column: true}})
+	y.addColumn("price")
+	validateSelect(t, x, &y)
+	if req, ok := x.(*sqlSelectRequest); ok {
+		if req.cols[0] != "ticker || ' @ ' || exchange" || req.getAlias(0) != "label" {
+			t.Errorf("parse error: unexpected concatenation %s as %s", req.cols[0], req.getAlias(0))
+		}
+		if len(req.concats[0]) != 3 || req.concats[1] != nil {
+			t.Errorf("parse error: unexpected concatenation operands %v", req.concats)
+		}
+	}
+	//
+	pc = newTokens()
+	lex(" select ticker || from stocks", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select ticker || exchange, count(*) from stocks group by ticker", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectStarColumns(t *testing.T) {
+	pc := newTokens()
+	lex(" select *, ticker || exchange as label, price as last from stocks", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.addConcat([]sqlConcatOperand{{val: "ticker", column: true}, {val: "exchange", column: true}})
+	y.addColumn("price")
+	validateSelect(t, x, &y)
+	if req, ok := x.(*sqlSelectRequest); ok {
+		if !req.allColumns || req.getAlias(0) != "label" || req.getAlias(1) != "last" {
+			t.Errorf("parse error: expected all columns followed by label and last")
+		}
+	}
+	// plain star does not list columns
+	pc = newTokens()
+	lex(" select * from stocks", pc)
+	if req, ok := parse(pc).(*sqlSelectRequest); !ok || req.allColumns {
+		t.Errorf("parse error: select * should not be followed by columns")
+	}
+	//
+	pc = newTokens()
+	lex(" select *, from stocks", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select *, count(*) from stocks group by ticker", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectDistinct(t *testing.T) {
+	pc := newTokens()
+	lex(" select distinct sector, exchange from stocks limit 10", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.addColumn("sector")
+	y.addColumn("exchange")
+	y.distinct = true
+	y.limit = sqlLimit{count: 10, use: true}
+	validateSelect(t, x, &y)
+	// column name that begins with distinct
+	pc = newTokens()
+	lex(" select distinctive from stocks", pc)
+	x = parse(pc)
+	y = sqlSelectRequest{}
+	y.table = "stocks"
+	y.addColumn("distinctive")
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select distinct * from stocks", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select distinct from stocks", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectLimitOffset(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from stocks where ticker = IBM limit 50 offset 100", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.filter.addFilter("ticker", "IBM")
+	y.limit = sqlLimit{count: 50, offset: 100, use: true}
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from stocks offset 5 limit 0", pc)
+	x = parse(pc)
+	y = sqlSelectRequest{}
+	y.table = "stocks"
+	y.limit = sqlLimit{count: 0, offset: 5, use: true}
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from stocks offset -1", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from stocks limit abc", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from stocks limit 1 limit 2", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from stocks limit", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSelectQualifiedColumn(t *testing.T) {
+	pc := newTokens()
+	lex(" select stocks.ticker, bid from stocks where stocks.ticker = IBM order by stocks.bid", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "stocks"
+	y.addColumn("ticker")
+	y.addColumn("bid")
+	y.filter.addFilter("ticker", "IBM")
+	y.orderBy = sqlOrderBy{col: "bid", use: true}
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select orders.ticker from stocks", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" update stocks set orders.bid = 10", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" delete from stocks where stocks.ticker = IBM", pc)
+	x = parse(pc)
+	var d sqlDeleteRequest
+	d.table = "stocks"
+	d.filter.addFilter("ticker", "IBM")
+	validateDelete(t, x, &d)
+}
+
+func TestParseSqlSelectOrderBy(t *testing.T) {
+	pc := newTokens()
+	lex(" select * from events order by id desc limit 50", pc)
+	x := parse(pc)
+	var y sqlSelectRequest
+	y.table = "events"
+	y.orderBy = sqlOrderBy{col: "id", desc: true, use: true}
+	y.limit = sqlLimit{count: 50, use: true}
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from events order by bid ASC", pc)
+	x = parse(pc)
+	y = sqlSelectRequest{}
+	y.table = "events"
+	y.orderBy = sqlOrderBy{col: "bid", use: true}
+	validateSelect(t, x, &y)
+	//
+	pc = newTokens()
+	lex(" select * from events order by exchange, price desc, id limit 5", pc)
+	req, ok := parse(pc).(*sqlSelectRequest)
+	if !ok || req.limit.count != 5 {
+		t.Fatalf("parse error: expected select with order by columns")
+	}
+	orderBy := req.orderBy
+	if orderBy.col != "exchange" || orderBy.desc || orderBy.then == nil {
+		t.Fatalf("parse error: unexpected first order by column %v", orderBy)
+	}
+	if second := *orderBy.then; second.col != "price" || !second.desc || second.then == nil {
+		t.Fatalf("parse error: unexpected second order by column %v", second)
+	}
+	if third := *orderBy.then.then; third != (sqlOrderBy{col: "id", use: true}) {
+		t.Errorf("parse error: unexpected third order by column %v", third)
+	}
+	//
+	pc = newTokens()
+	lex(" select * from events order by bid, ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from events order id", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select * from events order by", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" select count(*) from events order by id desc", pc)
+	expectedError(t, parse(pc))
+}
+
 func TestParseSqlSelectStatement4(t *testing.T) {
 	pc := newTokens()
 	lex(" select ", pc)
@@ -446,6 +1118,58 @@ func TestParseSqlUpdateStatement5(t *testing.T) {
 	expectedError(t, x)
 }
 
+func TestParseSqlUpdateVersion(t *testing.T) {
+	pc := newTokens()
+	lex(" update stocks set bid = 140.45 where id = 3 and version = 2", pc)
+	x := parse(pc)
+	var y sqlUpdateRequest
+	y.table = "stocks"
+	y.addColVal("bid", "140.45")
+	y.filter.addFilter("id", "3")
+	validateUpdate(t, x, &y)
+	if req, ok := x.(*sqlUpdateRequest); ok && (!req.useVersion || req.version != 2) {
+		t.Errorf("parse error: expected version 2")
+	}
+	//
+	pc = newTokens()
+	lex(" update stocks set bid = 140.45 where id = 3 and ticker = IBM", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" update stocks set bid = 140.45 where id = 3 and version = x", pc)
+	expectedError(t, parse(pc))
+	// version condition is only supported by update
+	pc = newTokens()
+	lex(" select * from stocks where id = 3 and version = 2", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlUpdateIncrement(t *testing.T) {
+	pc := newTokens()
+	lex(" update stats set hits = hits + 1, note = a, misses = misses - -2 where id = 3", pc)
+	x := parse(pc)
+	var y sqlUpdateRequest
+	y.table = "stats"
+	y.addColVal("hits", "hits")
+	y.addColVal("note", "a")
+	y.addColVal("misses", "misses")
+	y.filter.addFilter("id", "3")
+	validateUpdate(t, x, &y)
+	if req, ok := x.(*sqlUpdateRequest); ok {
+		if len(req.increments) != 2 || *req.increments[0] != (sqlIncrement{"hits", "1"}) || *req.increments[2] != (sqlIncrement{"misses", "2"}) {
+			t.Errorf("parse error: increments do not match")
+		}
+	}
+	//
+	pc = newTokens()
+	lex(" update stats set hits = hits + abc", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" update stats set hits = hits + 1 + 2", pc)
+	expectedError(t, parse(pc))
+}
+
 // DELETE
 func validateDelete(t *testing.T, a request, y *sqlDeleteRequest) {
 	switch a.(type) {
@@ -626,6 +1350,60 @@ func TestParseSqlSubscribeStatement4(t *testing.T) {
 	expectedError(t, x)
 }
 
+func TestParseSqlSubscribeColumns(t *testing.T) {
+	pc := newTokens()
+	lex(" subscribe price, qty from stocks where ticker = IBM", pc)
+	x := parse(pc)
+	var y sqlSubscribeRequest
+	y.table = "stocks"
+	y.filter.addFilter("ticker", "IBM")
+	validateSubscribe(t, x, &y, false)
+	if req, ok := x.(*sqlSubscribeRequest); ok && (len(req.columns) != 2 || req.columns[0] != "price" || req.columns[1] != "qty") {
+		t.Errorf("parse error: watched columns do not match %v", req.columns)
+	}
+	//
+	pc = newTokens()
+	lex(" subscribe skip price from stocks", pc)
+	x = parse(pc)
+	y = sqlSubscribeRequest{}
+	y.table = "stocks"
+	validateSubscribe(t, x, &y, true)
+	//
+	pc = newTokens()
+	lex(" subscribe price, from stocks", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlSubscribeHaving(t *testing.T) {
+	pc := newTokens()
+	lex(" subscribe price from ticks where ticker = IBM having delta(price) > 0.5 and delta(qty) > 10 ", pc)
+	x := parse(pc)
+	var y sqlSubscribeRequest
+	y.table = "ticks"
+	y.filter.addFilter("ticker", "IBM")
+	validateSubscribe(t, x, &y, false)
+	if req, ok := x.(*sqlSubscribeRequest); ok && (len(req.thresholds) != 2 || req.thresholds["price"] != 0.5 || req.thresholds["qty"] != 10) {
+		t.Errorf("parse error: thresholds do not match %v", req.thresholds)
+	}
+	//
+	pc = newTokens()
+	lex(" subscribe * from ticks having delta(price) > 0 ", pc)
+	x = parse(pc)
+	y = sqlSubscribeRequest{}
+	y.table = "ticks"
+	validateSubscribe(t, x, &y, false)
+	//
+	pc = newTokens()
+	lex(" subscribe * from ticks having delta(price) > abc ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" subscribe * from ticks having delta(price) > -1 ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" subscribe * from ticks having delta(price) ", pc)
+	expectedError(t, parse(pc))
+}
+
 // SUBSCRIBE TOPIC
 func validateSubscribeTopic(t *testing.T, a request, y *sqlSubscribeTopicRequest) {
 	switch a.(type) {
@@ -646,6 +1424,55 @@ func validateSubscribeTopic(t *testing.T, a request, y *sqlSubscribeTopicRequest
 
 }
 
+func TestParseSqlSubscribeSince(t *testing.T) {
+	pc := newTokens()
+	lex(" subscribe * from ticks where ticker = IBM since 42 ", pc)
+	x := parse(pc)
+	var y sqlSubscribeRequest
+	y.table = "ticks"
+	y.filter.addFilter("ticker", "IBM")
+	validateSubscribe(t, x, &y, false)
+	if req, ok := x.(*sqlSubscribeRequest); ok && (req.since == nil || *req.since != 42) {
+		t.Errorf("parse error: expected since 42")
+	}
+	//
+	pc = newTokens()
+	lex(" subscribe * from ticks since 0 ", pc)
+	x = parse(pc)
+	y = sqlSubscribeRequest{}
+	y.table = "ticks"
+	validateSubscribe(t, x, &y, false)
+	if req, ok := x.(*sqlSubscribeRequest); ok && (req.since == nil || *req.since != 0) {
+		t.Errorf("parse error: expected since 0")
+	}
+	//
+	pc = newTokens()
+	lex(" subscribe * from ticks having delta(price) > 1 since 7 ", pc)
+	x = parse(pc)
+	if req, ok := x.(*sqlSubscribeRequest); !ok || req.since == nil || *req.since != 7 || len(req.thresholds) != 1 {
+		t.Errorf("parse error: expected since 7 with threshold")
+	}
+	//
+	pc = newTokens()
+	lex(" subscribe * from ticks ", pc)
+	if req, ok := parse(pc).(*sqlSubscribeRequest); !ok || req.since != nil {
+		t.Errorf("parse error: expected no since")
+	}
+	//
+	pc = newTokens()
+	lex(" subscribe * from ticks since -1 ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" subscribe * from ticks since abc ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" subscribe skip * from ticks since 1 ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" subscribe * from ticks since 1 having delta(price) > 1 ", pc)
+	expectedError(t, parse(pc))
+}
+
 func TestParseSqlSubscribeTopic(t *testing.T) {
 	pc := newTokens()
 	lex(" subscribe topic1 ", pc)
@@ -655,6 +1482,14 @@ func TestParseSqlSubscribeTopic(t *testing.T) {
 	validateSubscribeTopic(t, x, &y)
 }
 
+func TestParseSqlSubscribeSchema(t *testing.T) {
+	pc := newTokens()
+	lex(" subscribe SCHEMA ", pc)
+	if _, ok := parse(pc).(*sqlSubscribeSchemaRequest); !ok {
+		t.Errorf("parse error: expected sqlSubscribeSchemaRequest")
+	}
+}
+
 // UNSUBSCRIBE
 func validateUnsubscribe(t *testing.T, a request, y *sqlUnsubscribeRequest) {
 	switch a.(type) {
@@ -711,6 +1546,18 @@ func TestParseSqlUnsubscribeStatement3(t *testing.T) {
 	validateUnsubscribe(t, x, &y)
 }
 
+func TestParseSqlUnsubscribeAll(t *testing.T) {
+	pc := newTokens()
+	lex(" unsubscribe all ", pc)
+	if _, ok := parse(pc).(*sqlUnsubscribeAllRequest); !ok {
+		t.Errorf("parse error: invalid request type expected sqlUnsubscribeAllRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" unsubscribe all from stocks ", pc)
+	expectedError(t, parse(pc))
+}
+
 // KEY
 func validateKey(t *testing.T, a request, y *sqlKeyRequest) {
 	switch a.(type) {
@@ -802,6 +1649,23 @@ func TestParseSqlTagStatement2(t *testing.T) {
 	expectedError(t, x)
 }
 
+func TestParseSqlTagMulti(t *testing.T) {
+	pc := newTokens()
+	lex(" tag stocks labels multi", pc)
+	x := parse(pc)
+	var y sqlTagRequest
+	y.table = "stocks"
+	y.column = "labels"
+	validateTag(t, x, &y)
+	if req, ok := x.(*sqlTagRequest); ok && !req.multiValue {
+		t.Errorf("parse error: expected multi value tag")
+	}
+	//
+	pc = newTokens()
+	lex(" tag stocks labels many", pc)
+	expectedError(t, parse(pc))
+}
+
 // STREAM
 
 func TestParseSqlStream1(t *testing.T) {
@@ -1086,3 +1950,175 @@ func TestParseSqlPeekStatement6(t *testing.T) {
 	y.sqlSelectRequest.addColumn("ask")
 	validatePeek(t, x, &y)
 }
+
+// CREATE
+
+func TestParseSqlCreateStatement(t *testing.T) {
+	pc := newTokens()
+	lex(" create table stocks (ticker string, bid Float, qty int, active bool, sector) ", pc)
+	x := parse(pc)
+	switch x.(type) {
+	case *errorRequest:
+		t.Errorf("parse error: " + x.(*errorRequest).err)
+	case *sqlCreateRequest:
+		y := x.(*sqlCreateRequest)
+		expected := []columnDefinition{
+			{"ticker", columnDataTypeString},
+			{"bid", columnDataTypeFloat},
+			{"qty", columnDataTypeInt},
+			{"active", columnDataTypeBool},
+			{"sector", columnDataTypeString},
+		}
+		if y.table != "stocks" || len(y.columns) != len(expected) {
+			t.Errorf("parse error: table or columns do not match")
+			break
+		}
+		for idx, def := range expected {
+			if y.columns[idx] != def {
+				t.Errorf("parse error: expected column %v but got %v", def, y.columns[idx])
+			}
+		}
+	default:
+		t.Errorf("parse error: invalid request type expected sqlCreateRequest")
+	}
+	//
+	for _, sql := range []string{
+		" create stocks (ticker) ",
+		" create table stocks ",
+		" create table stocks () ",
+		" create table stocks (ticker decimal) ",
+		" create table stocks (ticker string int) ",
+		" create table stocks (ticker, ) ",
+		" create table stocks (ticker) values ",
+		" create table stocks (ticker) with id = guid ",
+		" create table stocks (ticker) with key = uuid ",
+		" create table stocks (ticker) with id ",
+		" create table if exists stocks (ticker) ",
+		" create table if not stocks (ticker) ",
+	} {
+		pc = newTokens()
+		lex(sql, pc)
+		expectedError(t, parse(pc))
+	}
+	// if not exists
+	pc = newTokens()
+	lex(" create table if not exists stocks (ticker) ", pc)
+	if req, ok := parse(pc).(*sqlCreateRequest); !ok || !req.ifNotExists || req.table != "stocks" {
+		t.Errorf("parse error: expected create table if not exists")
+	}
+	// id format
+	for sql, format := range map[string]string{
+		" create table stocks (ticker) ":                     "",
+		" create table stocks (ticker) with id = UUID ":      idFormatUuid,
+		" create table stocks (ticker) with id = sequential": idFormatSequential,
+	} {
+		pc = newTokens()
+		lex(sql, pc)
+		if req, ok := parse(pc).(*sqlCreateRequest); !ok || req.idFormat != format {
+			t.Errorf("parse error: expected id format %s for %s", format, sql)
+		}
+	}
+}
+
+// TRUNCATE
+
+func TestParseSqlDescribeStatement(t *testing.T) {
+	pc := newTokens()
+	lex(" describe stocks ", pc)
+	x := parse(pc)
+	switch x.(type) {
+	case *errorRequest:
+		e := x.(*errorRequest)
+		t.Errorf("parse error: " + e.err)
+	case *sqlDescribeRequest:
+		y := x.(*sqlDescribeRequest)
+		if y.table != "stocks" {
+			t.Errorf("parse error: table does not match")
+		}
+	default:
+		t.Errorf("invalid request expected sqlDescribeRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" describe stocks bonds ", pc)
+	expectedError(t, parse(pc))
+	// delete is still recognized
+	pc = newTokens()
+	lex(" delete from stocks ", pc)
+	if _, ok := parse(pc).(*sqlDeleteRequest); !ok {
+		t.Errorf("invalid request expected sqlDeleteRequest")
+	}
+}
+
+func TestParseSqlSaveLoadStatement(t *testing.T) {
+	pc := newTokens()
+	lex(" save table stocks to 'stocks.snap' ", pc)
+	if x, ok := parse(pc).(*sqlSaveRequest); !ok || x.table != "stocks" || x.file != "stocks.snap" {
+		t.Errorf("parse error: expected sqlSaveRequest")
+	}
+	pc = newTokens()
+	lex(" load table stocks from 'stocks.snap' ", pc)
+	if x, ok := parse(pc).(*sqlLoadRequest); !ok || x.table != "stocks" || x.file != "stocks.snap" || x.replace {
+		t.Errorf("parse error: expected sqlLoadRequest")
+	}
+	pc = newTokens()
+	lex(" load table stocks from 'stocks.snap' replace ", pc)
+	if x, ok := parse(pc).(*sqlLoadRequest); !ok || !x.replace {
+		t.Errorf("parse error: expected sqlLoadRequest with replace")
+	}
+	//
+	pc = newTokens()
+	lex(" save stocks to 'stocks.snap' ", pc)
+	expectedError(t, parse(pc))
+	pc = newTokens()
+	lex(" load table stocks 'stocks.snap' ", pc)
+	expectedError(t, parse(pc))
+}
+
+func TestParseSqlTruncateStatement(t *testing.T) {
+	pc := newTokens()
+	lex(" truncate table stocks ", pc)
+	x := parse(pc)
+	switch x.(type) {
+	case *errorRequest:
+		e := x.(*errorRequest)
+		t.Errorf("parse error: " + e.err)
+	case *sqlTruncateRequest:
+		y := x.(*sqlTruncateRequest)
+		if y.table != "stocks" {
+			t.Errorf("parse error: table does not match")
+		}
+	default:
+		t.Errorf("invalid request expected sqlTruncateRequest")
+	}
+	//
+	pc = newTokens()
+	lex(" truncate stocks ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" truncate table ", pc)
+	expectedError(t, parse(pc))
+	//
+	pc = newTokens()
+	lex(" truncate table stocks where id = 1", pc)
+	expectedError(t, parse(pc))
+}
+
+// ERRORS
+
+func TestParseLexerErrorPosition(t *testing.T) {
+	pc := newTokens()
+	lex(" update stocks set bid 12", pc)
+	x := parse(pc)
+	switch x.(type) {
+	case *errorRequest:
+		e := x.(*errorRequest)
+		expected := "syntax error at position 23: expected = "
+		if e.err != expected {
+			t.Errorf("parse error: expected %q but got %q", expected, e.err)
+		}
+	default:
+		t.Errorf("invalid request expected errorRequest")
+	}
+}
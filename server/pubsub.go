@@ -16,6 +16,12 @@
 
 package server
 
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
 // pubsub
 type pubsub struct {
 	head *subscription
@@ -67,9 +73,20 @@ func (this *pubsub) publishTest(res response) {
 
 // subscription represents individual client subscription
 type subscription struct {
-	next   *subscription // next node
-	sender *responseSender
-	id     uint64
+	next    *subscription // next node
+	sender  *responseSender
+	id      uint64
+	columns []string // watched columns, all columns are watched when empty
+	table   string   // published with events of table pattern subscription
+	queue   *subscriptionQueue
+	// events are skipped until subscriber catches up
+	overflowed bool
+	// minimum change of numeric column values, updates below are suppressed
+	thresholds map[string]float64
+	// last published values of thresholded columns per record
+	published map[*record]map[string]float64
+	// or filter of table subscription, nil when every record matches
+	filter *subscriptionFilter
 }
 
 // factory
@@ -78,6 +95,7 @@ func newSubscription(sender *responseSender, id uint64) *subscription {
 		next:   nil,
 		sender: sender,
 		id:     id,
+		queue:  new(subscriptionQueue),
 	}
 }
 
@@ -91,6 +109,98 @@ func (this *subscription) deactivate() {
 	this.sender = nil
 }
 
+// Returns true when subscription watches the column.
+func (this *subscription) watches(col *column) bool {
+	if len(this.columns) == 0 {
+		return true
+	}
+	for _, name := range this.columns {
+		if col.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true when subscription watches any of the columns.
+func (this *subscription) watchesAny(cols []*column) bool {
+	for _, col := range cols {
+		if this.watches(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true when update of the record should be published.
+// Update is published when any watched column changed by more than its threshold,
+// has no threshold, holds non numeric value or was not published for the record before.
+// Last published values are remembered only when update is published.
+func (this *subscription) exceedsThresholds(cols []*column, rec *record) bool {
+	last := this.published[rec]
+	exceeds := last == nil
+	for _, col := range cols {
+		if exceeds {
+			break
+		}
+		if col.typ == columnTypeId || !this.watches(col) {
+			continue
+		}
+		delta, ok := this.thresholds[col.name]
+		if !ok {
+			exceeds = true
+			break
+		}
+		val, err := strconv.ParseFloat(rec.getValue(col.ordinal), 64)
+		prev, known := last[col.name]
+		exceeds = err != nil || !known || math.Abs(val-prev) > delta
+	}
+	if !exceeds {
+		return false
+	}
+	if this.published == nil {
+		this.published = make(map[*record]map[string]float64)
+	}
+	if last == nil {
+		last = make(map[string]float64, len(this.thresholds))
+		this.published[rec] = last
+	}
+	for _, col := range cols {
+		if _, ok := this.thresholds[col.name]; !ok {
+			continue
+		}
+		if val, err := strconv.ParseFloat(rec.getValue(col.ordinal), 64); err == nil {
+			last[col.name] = val
+		}
+	}
+	return true
+}
+
+// Forgets last published values of the record.
+func (this *subscription) forget(rec *record) {
+	if this.published != nil {
+		delete(this.published, rec)
+	}
+}
+
+// subscriptionQueue counts published responses of a subscription
+// that were not yet taken by the connection writer.
+type subscriptionQueue struct {
+	pending int32
+}
+
+func (this *subscriptionQueue) size() int {
+	return int(atomic.LoadInt32(&this.pending))
+}
+
+func (this *subscriptionQueue) enqueue() {
+	atomic.AddInt32(&this.pending, 1)
+}
+
+func (this *subscriptionQueue) dequeue() {
+	atomic.AddInt32(&this.pending, -1)
+}
+
 //
 
 type mapSubscriptionById map[uint64]*subscription
@@ -125,13 +235,14 @@ func (this *mapSubscriptionByConnection) deactivate(connectionId uint64, pubsubi
 	return true
 }
 
-func (this *mapSubscriptionByConnection) deactivateAll(connectionId uint64) int {
+// Deactivates all subscriptions of the connection and returns their pubsubids.
+func (this *mapSubscriptionByConnection) deactivateAll(connectionId uint64) []uint64 {
 	mapsub := this.getOrAdd(connectionId)
-	count := 0
+	pubsubids := make([]uint64, 0, len(mapsub))
 	for _, sub := range mapsub {
 		sub.deactivate()
-		count++
+		pubsubids = append(pubsubids, sub.id)
 	}
 	delete(*this, connectionId)
-	return count
+	return pubsubids
 }
@@ -83,7 +83,7 @@ func TestPubSubMap(t *testing.T) {
 	sub3 := newSubscription(sender, 3)
 	m.add(sender.connectionId, sub3)
 	//
-	if m.deactivateAll(1) != 2 {
+	if len(m.deactivateAll(1)) != 2 {
 		t.Errorf("expected 2 subscription")
 	}
 	//
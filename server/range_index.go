@@ -0,0 +1,180 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have idxeived a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+const _RANGE_INDEX_MAX_LEVEL = 24
+
+// rangeKey orders values numerically when both values are numbers,
+// numbers go before other values which are ordered as strings.
+// Record id breaks ties between equal values.
+type rangeKey struct {
+	val     string
+	num     float64
+	numeric bool
+	id      int
+}
+
+func newRangeKey(val string, id int) rangeKey {
+	num, err := strconv.ParseFloat(val, 64)
+	return rangeKey{
+		val:     val,
+		num:     num,
+		numeric: err == nil,
+		id:      id,
+	}
+}
+
+// Compares values ignoring record id.
+func (this *rangeKey) compareValue(other *rangeKey) int {
+	switch {
+	case this.numeric && other.numeric:
+		switch {
+		case this.num < other.num:
+			return -1
+		case this.num > other.num:
+			return 1
+		}
+		return 0
+	case this.numeric:
+		return -1
+	case other.numeric:
+		return 1
+	case this.val < other.val:
+		return -1
+	case this.val > other.val:
+		return 1
+	}
+	return 0
+}
+
+// Compares values and then record ids.
+func (this *rangeKey) compare(other *rangeKey) int {
+	if c := this.compareValue(other); c != 0 {
+		return c
+	}
+	return this.id - other.id
+}
+
+// rangeIndexNode is an element of the range index.
+type rangeIndexNode struct {
+	key  rangeKey
+	rec  *record
+	next []*rangeIndexNode
+}
+
+// rangeIndex keeps records sorted by column value.
+// Implemented as skip list so that add and remove are O(log n).
+type rangeIndex struct {
+	head  rangeIndexNode
+	level int
+	count int
+}
+
+func newRangeIndex() *rangeIndex {
+	idx := new(rangeIndex)
+	idx.clear()
+	return idx
+}
+
+// Removes all records from the index.
+func (this *rangeIndex) clear() {
+	this.head.next = make([]*rangeIndexNode, _RANGE_INDEX_MAX_LEVEL)
+	this.level = 1
+	this.count = 0
+}
+
+func randomRangeIndexLevel() int {
+	level := 1
+	for level < _RANGE_INDEX_MAX_LEVEL && rand.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
+
+// Finds the last node on every level that goes before the key.
+func (this *rangeIndex) findPrevious(key *rangeKey, prev []*rangeIndexNode) *rangeIndexNode {
+	node := &this.head
+	for i := this.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].key.compare(key) < 0 {
+			node = node.next[i]
+		}
+		if prev != nil {
+			prev[i] = node
+		}
+	}
+	return node
+}
+
+// Adds record with a given value and id to the index.
+func (this *rangeIndex) add(val string, id int, rec *record) {
+	key := newRangeKey(val, id)
+	var prev [_RANGE_INDEX_MAX_LEVEL]*rangeIndexNode
+	this.findPrevious(&key, prev[:])
+	level := randomRangeIndexLevel()
+	for ; this.level < level; this.level++ {
+		prev[this.level] = &this.head
+	}
+	node := &rangeIndexNode{
+		key:  key,
+		rec:  rec,
+		next: make([]*rangeIndexNode, level),
+	}
+	for i := 0; i < level; i++ {
+		node.next[i] = prev[i].next[i]
+		prev[i].next[i] = node
+	}
+	this.count++
+}
+
+// Removes record with a given value and id from the index.
+// Returns false when record was not found.
+func (this *rangeIndex) remove(val string, id int) bool {
+	key := newRangeKey(val, id)
+	var prev [_RANGE_INDEX_MAX_LEVEL]*rangeIndexNode
+	this.findPrevious(&key, prev[:])
+	node := prev[0].next[0]
+	if node == nil || node.key.compare(&key) != 0 {
+		return false
+	}
+	for i := 0; i < this.level && prev[i].next[i] == node; i++ {
+		prev[i].next[i] = node.next[i]
+	}
+	for this.level > 1 && this.head.next[this.level-1] == nil {
+		this.level--
+	}
+	this.count--
+	return true
+}
+
+// Returns records with values within inclusive from and to range ordered by value.
+func (this *rangeIndex) getRecords(from string, to string) []*record {
+	records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	fromKey := newRangeKey(from, -1)
+	toKey := newRangeKey(to, 0)
+	for node := this.findPrevious(&fromKey, nil).next[0]; node != nil; node = node.next[0] {
+		if node.key.compareValue(&toKey) > 0 {
+			break
+		}
+		records = append(records, node.rec)
+	}
+	return records
+}
@@ -0,0 +1,97 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have idxeived a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"strconv"
+	"testing"
+)
+
+func validateRangeIndexIds(t *testing.T, records []*record, expected ...int) {
+	if len(records) != len(expected) {
+		t.Errorf("range index error: expected %d records but got %d", len(expected), len(records))
+		return
+	}
+	for i, rec := range records {
+		if rec.id() != expected[i] {
+			t.Errorf("range index error: expected id %d at %d but got %d", expected[i], i, rec.id())
+		}
+	}
+}
+
+func TestRangeKey(t *testing.T) {
+	for _, pair := range [][2]string{{"9", "10"}, {"-1.5", "0"}, {"100", "abc"}, {"abc", "abd"}, {"", "a"}} {
+		x := newRangeKey(pair[0], 0)
+		y := newRangeKey(pair[1], 0)
+		if x.compare(&y) >= 0 || y.compare(&x) <= 0 {
+			t.Errorf("range key error: expected %s before %s", pair[0], pair[1])
+		}
+	}
+	x := newRangeKey("1.0", 1)
+	y := newRangeKey("1", 2)
+	if x.compareValue(&y) != 0 || x.compare(&y) >= 0 {
+		t.Errorf("range key error: expected equal values ordered by id")
+	}
+}
+
+func TestRangeIndex(t *testing.T) {
+	idx := newRangeIndex()
+	values := []string{"50", "7", "100", "7", "abc", "-3", "20"}
+	for id, val := range values {
+		idx.add(val, id, newRecord(1, id))
+	}
+	if idx.count != len(values) {
+		t.Errorf("range index error: expected count %d but got %d", len(values), idx.count)
+	}
+	validateRangeIndexIds(t, idx.getRecords("7", "50"), 1, 3, 6, 0)
+	validateRangeIndexIds(t, idx.getRecords("-100", "100"), 5, 1, 3, 6, 0, 2)
+	validateRangeIndexIds(t, idx.getRecords("a", "z"), 4)
+	validateRangeIndexIds(t, idx.getRecords("50", "7"))
+	// remove
+	if !idx.remove("7", 1) || idx.remove("7", 1) || idx.remove("8", 3) {
+		t.Errorf("range index error: unexpected remove result")
+	}
+	validateRangeIndexIds(t, idx.getRecords("7", "50"), 3, 6, 0)
+	// clear
+	idx.clear()
+	validateRangeIndexIds(t, idx.getRecords("-100", "z"))
+}
+
+func TestRangeIndexMany(t *testing.T) {
+	idx := newRangeIndex()
+	n := 1000
+	for id := 0; id < n; id++ {
+		idx.add(strconv.Itoa((id*7919)%n), id, newRecord(1, id))
+	}
+	for id := 0; id < n; id += 2 {
+		if !idx.remove(strconv.Itoa((id*7919)%n), id) {
+			t.Errorf("range index error: failed to remove %d", id)
+		}
+	}
+	records := idx.getRecords("0", strconv.Itoa(n))
+	if len(records) != n/2 {
+		t.Errorf("range index error: expected %d records but got %d", n/2, len(records))
+	}
+	prev := -1
+	for _, rec := range records {
+		val := (rec.id() * 7919) % n
+		if val <= prev {
+			t.Errorf("range index error: records are not sorted")
+		}
+		prev = val
+	}
+}
@@ -0,0 +1,48 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import "time"
+
+// rateLimiter counts commands of a client connection in one second windows.
+// It is accessed only by the connection reader.
+type rateLimiter struct {
+	limit int
+	start time.Time // beginning of the current window
+	count int       // commands allowed in the current window
+}
+
+// rateLimiter factory, limit of 0 allows every command
+func newRateLimiter(limit int) *rateLimiter {
+	return &rateLimiter{limit: limit}
+}
+
+// allow returns true when another command fits into the current window.
+func (this *rateLimiter) allow(now time.Time) bool {
+	if this.limit == 0 {
+		return true
+	}
+	if now.Sub(this.start) >= time.Second {
+		this.start = now
+		this.count = 0
+	}
+	if this.count >= this.limit {
+		return false
+	}
+	this.count++
+	return true
+}
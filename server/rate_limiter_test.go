@@ -0,0 +1,44 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(2)
+	if !limiter.allow(now) || !limiter.allow(now.Add(time.Millisecond*100)) {
+		t.Error("expected commands within the limit to be allowed")
+	}
+	if limiter.allow(now.Add(time.Millisecond * 900)) {
+		t.Error("expected command over the limit to be rejected")
+	}
+	// next window
+	if !limiter.allow(now.Add(time.Second)) {
+		t.Error("expected command in the next window to be allowed")
+	}
+	// disabled limiter allows every command
+	limiter = newRateLimiter(0)
+	for i := 0; i < 100; i++ {
+		if !limiter.allow(now) {
+			t.Fatal("expected disabled limiter to allow every command")
+		}
+	}
+}
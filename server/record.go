@@ -22,25 +22,59 @@ import "strconv"
 type link struct {
 	pubsub *pubsub
 	tg     *tag
+	more   []link // links of additional values of multi value tag
 }
 
 func (this *link) clear() {
 	this.pubsub = nil
 	this.tg = nil
+	this.more = nil
+}
+
+// Replaces tag of the link or of one of the additional values links.
+func (this *link) retag(from *tag, to *tag) {
+	if this.tg == from {
+		this.tg = to
+		return
+	}
+	for idx := range this.more {
+		if this.more[idx].tg == from {
+			this.more[idx].tg = to
+			return
+		}
+	}
+}
+
+// Returns pubsubs of the link including additional values links.
+func (this *link) getPubsubs() []*pubsub {
+	pubsubs := make([]*pubsub, 0, len(this.more)+1)
+	if this.pubsub != nil {
+		pubsubs = append(pubsubs, this.pubsub)
+	}
+	for _, lnk := range this.more {
+		if lnk.pubsub != nil {
+			pubsubs = append(pubsubs, lnk.pubsub)
+		}
+	}
+	return pubsubs
 }
 
 // record
 type record struct {
-	values []string
-	links  []link
-	prev   *record
-	next   *record
+	values  []string
+	links   []link
+	prev    *record
+	next    *record
+	version int   // incremented on every update
+	idx     int   // position in table records
+	expires int64 // unix time in nanoseconds the record expires at, 0 when it never expires
 }
 
 // record factory
 func newRecord(columns int, id int) *record {
 	rec := record{
 		values: make([]string, columns, columns),
+		idx:    id,
 	}
 	rec.setValue(0, strconv.Itoa(id))
 	return &rec
@@ -54,15 +88,12 @@ func (this *record) free() {
 }
 
 // Returns record index in a table.
+// Index is the same as id value unless the table generates uuid ids.
 func (r *record) id() int {
-	id, err := strconv.Atoi(r.values[0])
-	if err != nil {
-		panic("record id can not be 0")
-	}
-	return id
+	return r.idx
 }
 
-// Returns record index in a table as string.
+// Returns record id value.
 func (r *record) idAsString() string {
 	return r.values[0]
 }
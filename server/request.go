@@ -16,6 +16,12 @@
 
 package server
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
 type requestType uint8
 
 const (
@@ -51,6 +57,29 @@ func (this *errorRequest) isStreaming() bool {
 	return false
 }
 
+// Returns true when the request does not change tables or subscriptions,
+// such requests are still served while the server is draining.
+func isReadRequest(req request) bool {
+	switch req.(type) {
+	case *errorRequest, *cmdStatusRequest, *cmdStatusTablesRequest, *cmdShowTablesRequest,
+		*cmdPingRequest, *cmdSetSessionRequest, *cmdRollbackRequest, *cmdStopRequest, *cmdCloseRequest,
+		*sqlPeekRequest, *sqlDescribeRequest, *sqlSaveRequest,
+		*sqlUnsubscribeRequest, *sqlUnsubscribeAllRequest, *mysqlStatusRequest:
+		return true
+	case *sqlSelectRequest:
+		// select into inserts records into another table
+		return len(req.(*sqlSelectRequest).into) == 0
+	case *sqlScriptRequest:
+		for _, stmt := range req.(*sqlScriptRequest).requests {
+			if !isReadRequest(stmt) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 // sqlRequest is a generic sql request.
 type sqlRequest struct {
 	request
@@ -98,6 +127,43 @@ type cmdStatusRequest struct {
 	cmdRequest
 }
 
+// cmdStatusTablesRequest is a request for table statistics.
+type cmdStatusTablesRequest struct {
+	cmdRequest
+}
+
+// cmdShowTablesRequest is a request for table names.
+type cmdShowTablesRequest struct {
+	cmdRequest
+}
+
+// cmdPingRequest is a health check request that does not touch any table.
+type cmdPingRequest struct {
+	cmdRequest
+}
+
+// cmdSetSessionRequest changes a setting of the client connection.
+type cmdSetSessionRequest struct {
+	cmdRequest
+	name  string
+	value string
+}
+
+// cmdBeginRequest starts a transaction on the client connection.
+type cmdBeginRequest struct {
+	cmdRequest
+}
+
+// cmdCommitRequest applies statements of the transaction in progress.
+type cmdCommitRequest struct {
+	cmdRequest
+}
+
+// cmdRollbackRequest discards statements of the transaction in progress.
+type cmdRollbackRequest struct {
+	cmdRequest
+}
+
 type cmdStopRequest struct {
 	cmdRequest
 }
@@ -113,8 +179,37 @@ type columnValue struct {
 }
 
 // Temporarely stub for sqlFilter type that will be more capble in future versions.
+// When between is set the filter matches inclusive range from val to to,
+// reversed range where val is greater than to matches nothing.
+// When not is set the filter matches records the predicate does not match.
+// When or is set the filter also matches records matched by the or filter.
 type sqlFilter struct {
 	columnValue
+	between bool
+	to      string
+	not     bool
+	nocase  bool // string values are compared case insensitively
+	or      *sqlFilter
+	// set by the client session, overrides strict columns configuration
+	strictColumns *bool
+	// set by the client session, overrides query timeout configuration
+	queryTimeout *time.Duration
+}
+
+// Returns true when filters on unknown columns are rejected.
+func (this *sqlFilter) isStrict() bool {
+	if this.strictColumns != nil {
+		return *this.strictColumns
+	}
+	return config.WHERE_STRICT_COLUMNS
+}
+
+// Returns time after which scan of records matching the filter is aborted, 0 means no timeout.
+func (this *sqlFilter) getQueryTimeout() time.Duration {
+	if this.queryTimeout != nil {
+		return *this.queryTimeout
+	}
+	return time.Millisecond * config.WAIT_MILLISECOND_QUERY_TIMEOUT
 }
 
 // Adds col = val to sqlFilter.
@@ -123,11 +218,26 @@ func (this *sqlFilter) addFilter(col string, val string) {
 	this.val = val
 }
 
+// Adds col between from and to to sqlFilter.
+func (this *sqlFilter) addBetweenFilter(col string, from string, to string) {
+	this.col = col
+	this.val = from
+	this.to = to
+	this.between = true
+}
+
 // sqlInsertRequest is a request for sql insert statement.
 type sqlInsertRequest struct {
 	sqlRequest
 	returningColumns
 	colVals []*columnValue
+	ttl     int // seconds before inserted record expires, 0 when it never expires
+}
+
+// sqlUpsertRequest is a request for sql upsert statement.
+// Record that matches key columns is updated otherwise new record is inserted.
+type sqlUpsertRequest struct {
+	sqlInsertRequest
 }
 
 // sqlPushRequest is a request for sql push statement.
@@ -183,7 +293,110 @@ func newSqlSelectRequest() *sqlSelectRequest {
 type sqlSelectRequest struct {
 	sqlRequest
 	returningColumns
-	filter sqlFilter
+	filter   sqlFilter
+	limit    sqlLimit
+	distinct bool // true when only unique rows are returned
+	groupBy  []string
+	into     string   // destination table of select into
+	aliases  []string // result column names by column position, empty when not aliased
+	orderBy  sqlOrderBy
+	concats  map[int][]sqlConcatOperand // concatenation expressions by column position
+	// all table columns precede the listed columns as in select *, col
+	allColumns bool
+}
+
+// sqlConcatOperand is a column name or a constant string joined by || operator.
+type sqlConcatOperand struct {
+	val    string
+	column bool // true when val is a column name
+}
+
+func newSqlConcatOperand(tok *token) sqlConcatOperand {
+	return sqlConcatOperand{val: tok.val, column: tok.typ == tokenTypeSqlColumn}
+}
+
+// Returns operand as it appears in the select statement.
+func (this sqlConcatOperand) String() string {
+	if this.column {
+		return this.val
+	}
+	return "'" + strings.Replace(this.val, "'", "''", -1) + "'"
+}
+
+// Adds concatenation expression as the next select column named after the expression.
+func (this *sqlSelectRequest) addConcat(operands []sqlConcatOperand) {
+	if this.concats == nil {
+		this.concats = make(map[int][]sqlConcatOperand)
+	}
+	names := make([]string, len(operands))
+	for idx, operand := range operands {
+		names[idx] = operand.String()
+	}
+	this.concats[len(this.cols)] = operands
+	this.addColumn(strings.Join(names, " || "))
+}
+
+// Returns names of selected table columns leaving out concatenation expressions.
+func (this *sqlSelectRequest) tableColumns() []string {
+	if len(this.concats) == 0 {
+		return this.cols
+	}
+	cols := make([]string, 0, len(this.cols))
+	for idx, col := range this.cols {
+		if this.concats[idx] == nil {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// sqlOrderBy contains order by clause values.
+type sqlOrderBy struct {
+	col  string
+	desc bool
+	use  bool        // true when order by was specified
+	then *sqlOrderBy // next column, orders records with equal values
+}
+
+// sqlCountStar is a name of count(*) aggregate in select columns.
+const sqlCountStar = "count(*)"
+
+// Returns true when select groups records or counts them.
+func (this *sqlSelectRequest) isAggregate() bool {
+	if len(this.groupBy) > 0 {
+		return true
+	}
+	for _, col := range this.cols {
+		if col == sqlCountStar {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns alias of the column at given position or empty string when column is not aliased.
+func (this *sqlSelectRequest) getAlias(idx int) string {
+	if idx < len(this.aliases) {
+		return this.aliases[idx]
+	}
+	return ""
+}
+
+// Returns true when column is one of group by columns.
+func (this *sqlSelectRequest) isGroupByColumn(col string) bool {
+	for _, groupCol := range this.groupBy {
+		if groupCol == col {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlLimit contains limit and offset clause values.
+type sqlLimit struct {
+	count  int
+	offset int
+	use    bool // true when limit was specified
 }
 
 // sqlPeekRequest is a request for sql peek statement.
@@ -215,8 +428,54 @@ type sqlPopRequest struct {
 type sqlUpdateRequest struct {
 	sqlRequest
 	returningColumns
-	colVals []*columnValue
-	filter  sqlFilter
+	colVals    []*columnValue
+	filter     sqlFilter
+	version    int  // expected record version
+	useVersion bool // true when only records with expected version are updated
+	// col = source + delta assignments by index of colVals
+	increments map[int]*sqlIncrement
+}
+
+// sqlIncrement is col = source + number or col = source - number assignment
+// of sql update statement, subtraction is stored as negative delta.
+type sqlIncrement struct {
+	source string
+	delta  string
+}
+
+// Returns new sqlIncrement for the operator and number.
+func newSqlIncrement(source string, op string, number string) *sqlIncrement {
+	number = strings.TrimPrefix(number, "+")
+	if op == "-" {
+		if strings.HasPrefix(number, "-") {
+			number = number[1:]
+		} else {
+			number = "-" + number
+		}
+	}
+	return &sqlIncrement{source: source, delta: number}
+}
+
+// Returns true when delta is a number.
+func (this *sqlIncrement) valid() bool {
+	_, err := strconv.ParseFloat(this.delta, 64)
+	return err == nil
+}
+
+// Adds delta to the value.
+// Integers stay integers, returns false when the value is not a number.
+func (this *sqlIncrement) apply(val string) (string, bool) {
+	if delta, err := strconv.ParseInt(this.delta, 10, 64); err == nil {
+		if current, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return strconv.FormatInt(current+delta, 10), true
+		}
+	}
+	delta, _ := strconv.ParseFloat(this.delta, 64)
+	current, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatFloat(current+delta, 'f', -1, 64), true
 }
 
 // Adds column and value to columnValue slice for udpate request.
@@ -231,6 +490,135 @@ type sqlDeleteRequest struct {
 	filter sqlFilter
 }
 
+// columnDefinition is a column name and data type of create table statement.
+type columnDefinition struct {
+	name     string
+	dataType columnDataType
+}
+
+// sqlCreateRequest is a request for sql create table statement.
+type sqlCreateRequest struct {
+	sqlRequest
+	columns     []columnDefinition
+	idFormat    string // sequential when empty
+	ifNotExists bool   // existing table is left as is
+}
+
+// sqlTruncateRequest is a request for sql truncate table statement.
+type sqlTruncateRequest struct {
+	sqlRequest
+}
+
+// sqlSaveRequest is a request to save table snapshot to the file.
+type sqlSaveRequest struct {
+	sqlRequest
+	file string
+}
+
+// sqlLoadRequest is a request to load table snapshot from the file.
+type sqlLoadRequest struct {
+	sqlRequest
+	file    string
+	replace bool // existing records are removed when true
+}
+
+// sqlDescribeRequest is a request for columns of the table.
+type sqlDescribeRequest struct {
+	sqlRequest
+}
+
+// sqlCopyRequest is a request for sql copy statement that bulk loads rows of values.
+type sqlCopyRequest struct {
+	sqlRequest
+	cols []string
+	rows [][]string
+}
+
+// sqlSelectIntoRequest copies records selected by select into statement
+// to the destination table.
+type sqlSelectIntoRequest struct {
+	sqlRequest
+	cols   []string
+	values [][]string
+}
+
+// Returns new sqlSelectIntoRequest with columns and values of selected records.
+// Id column is not copied, destination table generates fresh ids.
+func newSqlSelectIntoRequest(table string, res *sqlSelectResponse) *sqlSelectIntoRequest {
+	req := &sqlSelectIntoRequest{
+		cols:   make([]string, 0, len(res.columns)),
+		values: make([][]string, 0, len(res.records)),
+	}
+	req.table = table
+	ordinals := make([]int, 0, len(res.columns))
+	for ordinal, col := range res.columns {
+		if col.name != "id" {
+			req.cols = append(req.cols, col.name)
+			ordinals = append(ordinals, ordinal)
+		}
+	}
+	for _, rec := range res.records {
+		values := make([]string, len(ordinals))
+		for idx, ordinal := range ordinals {
+			values[idx] = rec.getValue(ordinal)
+		}
+		req.values = append(req.values, values)
+	}
+	return req
+}
+
+// sqlTransactionRequest holds statements buffered between begin and commit.
+// Statements are applied by the table all at once or not at all.
+type sqlTransactionRequest struct {
+	sqlRequest
+	requests []request
+}
+
+// Adds statement to the transaction.
+// All statements of the transaction must target the same table.
+func (this *sqlTransactionRequest) add(req request) bool {
+	if len(this.requests) == 0 {
+		this.table = req.getTableName()
+	} else if this.table != req.getTableName() {
+		return false
+	}
+	this.requests = append(this.requests, req)
+	return true
+}
+
+// Returns true when the request can be part of a transaction.
+func isTransactional(req request) bool {
+	switch req.(type) {
+	case *sqlInsertRequest, *sqlUpsertRequest, *sqlUpdateRequest, *sqlDeleteRequest:
+		return true
+	}
+	return false
+}
+
+// sqlScriptRequest holds statements of a single message separated by ;.
+// Statements are independent, they are executed one after another until
+// the first of them fails and changes made by previous statements are kept.
+// Statements enclosed in begin and commit are applied all at once or not at all.
+type sqlScriptRequest struct {
+	sqlRequest
+	requests []request
+}
+
+// Returns true when the request can be a statement of a script.
+// Subscriptions are not supported because their events would follow the script response.
+func isScriptable(req request) bool {
+	switch req.(type) {
+	case *sqlInsertRequest, *sqlUpsertRequest, *sqlPushRequest, *sqlCopyRequest,
+		*sqlSelectRequest, *sqlSelectIntoRequest, *sqlPeekRequest, *sqlPopRequest,
+		*sqlUpdateRequest, *sqlDeleteRequest, *sqlTruncateRequest, *sqlCreateRequest,
+		*sqlKeyRequest, *sqlTagRequest, *sqlRangeRequest, *sqlDescribeRequest,
+		*sqlSaveRequest, *sqlLoadRequest,
+		*cmdBeginRequest, *cmdCommitRequest, *cmdRollbackRequest, *cmdSetSessionRequest:
+		return !req.isStreaming()
+	}
+	return false
+}
+
 // sqlKeyRequest is a request for sql key statement.
 // Key defines unique index.
 type sqlKeyRequest struct {
@@ -241,6 +629,14 @@ type sqlKeyRequest struct {
 // sqlTagRequest is a request for sql tag statement.
 // Tag defines non-unique index.
 type sqlTagRequest struct {
+	sqlRequest
+	column     string
+	multiValue bool // true when column holds comma or space separated values
+}
+
+// sqlRangeRequest is a request for sql range statement.
+// Range defines sorted index used by range predicates.
+type sqlRangeRequest struct {
 	sqlRequest
 	column string
 }
@@ -248,9 +644,17 @@ type sqlTagRequest struct {
 // sqlSubscribeRequest is a request for sql subscribe statement.
 type sqlSubscribeRequest struct {
 	sqlRequest
-	skip   bool
-	filter sqlFilter
-	sender *responseSender
+	skip    bool
+	filter  sqlFilter
+	sender  *responseSender
+	columns []string // watched columns, updates of any column are published when empty
+	// minimum change of numeric column values for updates to be published
+	thresholds map[string]float64
+	// table pattern and shared pubsubid of subscription fanned out to matching tables
+	pattern  string
+	pubsubid uint64
+	// sequence number of the last table change seen by resuming subscriber, nil when not resuming
+	since *uint64
 }
 
 // sqlUnsubscribeRequest is a request for sql unsubscribe statement.
@@ -261,6 +665,19 @@ type sqlUnsubscribeRequest struct {
 }
 
 
+// sqlUnsubscribeAllRequest is a request for sql unsubscribe all statement,
+// which removes every subscription of the connection from every table.
+type sqlUnsubscribeAllRequest struct {
+	sqlRequest
+	tally *unsubscribeTally // shared by tables the request was fanned out to
+}
+
+// sqlSubscribeSchemaRequest is a request for subscribe schema statement,
+// which subscribes to tables being created and columns being added to tables.
+type sqlSubscribeSchemaRequest struct {
+	sqlRequest
+}
+
 // sqlSubscribeTopicRequest is a request for sql subscribe topic statement.
 type sqlSubscribeTopicRequest struct {
 	sqlRequest
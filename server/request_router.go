@@ -16,6 +16,10 @@
 
 package server
 
+import (
+	"strconv"
+)
+
 // requestRouter routs request to appropriate service for processing
 type requestRouter struct {
 	dataSrv            *dataService
@@ -29,7 +33,7 @@ func newRequestRouter(dataSrv *dataService) *requestRouter {
 
 func (this *requestRouter) onError(item *requestItem) {
 	ereq := item.req.(*errorRequest)
-	res := newErrorResponse(ereq.err)
+	res := newErrorResponseWithCode(errorCodeSyntax, ereq.err)
 	res.requestId = item.getRequestId()
 	item.sender.send(res)
 }
@@ -37,6 +41,19 @@ func (this *requestRouter) onError(item *requestItem) {
 func (this *requestRouter) route(item *requestItem) {
 	switch item.req.getRequestType() {
 	case requestTypeSql:
+		if _, ok := item.req.(*sqlScriptRequest); ok {
+			this.onScript(item)
+			return
+		}
+		item.sender.session.apply(item.req)
+		if item.sender.tx != nil && isTransactional(item.req) {
+			this.onTransactionStatement(item)
+			return
+		}
+		if req, ok := item.req.(*sqlSelectRequest); ok && len(req.into) > 0 {
+			this.onSelectInto(item, req)
+			return
+		}
 		this.dataSrv.acceptRequest(item)
 	case requestTypeCmd:
 		this.onCmd(item)
@@ -53,11 +70,181 @@ func (this *requestRouter) onCmd(item *requestItem) {
 		logInfo("client connection:", item.sender.connectionId, "requested to disconnect ")
 		item.sender.disconnecting = true
 		item.sender.quit.Quit(0)
+	case *cmdStatusTablesRequest, *cmdShowTablesRequest:
+		// tables are owned by the data service
+		this.dataSrv.acceptRequest(item)
+	case *cmdPingRequest:
+		this.onPing(item)
+	case *cmdSetSessionRequest:
+		this.onSetSession(item)
+	case *cmdBeginRequest:
+		this.onBegin(item)
+	case *cmdCommitRequest:
+		this.onCommit(item)
+	case *cmdRollbackRequest:
+		this.onRollback(item)
 	default:
 		this.onControllerCmd(item)
 	}
 }
 
+// onPing responds immediately without involving any service so that
+// health checks stay cheap during load and shutdown.
+func (this *requestRouter) onPing(item *requestItem) {
+	if item.req.isStreaming() {
+		return
+	}
+	res := newCmdPingResponse(!this.dataSrv.isQuitting())
+	res.requestId = item.getRequestId()
+	item.sender.send(res)
+}
+
+// reply sends response back to the client unless the request is streaming.
+func (this *requestRouter) reply(item *requestItem, res response) {
+	if item.req.isStreaming() {
+		return
+	}
+	res.setRequestId(item.getRequestId())
+	item.sender.send(res)
+}
+
+// onSetSession changes the setting for subsequent statements of the client connection.
+func (this *requestRouter) onSetSession(item *requestItem) {
+	req := item.req.(*cmdSetSessionRequest)
+	if res := item.sender.session.set(req.name, req.value); res != nil {
+		this.reply(item, res)
+		return
+	}
+	this.reply(item, newOkResponse("set"))
+}
+
+// onBegin starts buffering statements of the client connection.
+func (this *requestRouter) onBegin(item *requestItem) {
+	if item.sender.tx != nil {
+		this.reply(item, newErrorResponseWithCode(errorCodeTransaction, "transaction is already in progress"))
+		return
+	}
+	item.sender.tx = new(sqlTransactionRequest)
+	this.reply(item, newOkResponse("begin"))
+}
+
+// onTransactionStatement buffers the statement until the transaction is committed.
+func (this *requestRouter) onTransactionStatement(item *requestItem) {
+	tx := item.sender.tx
+	if !tx.add(item.req) {
+		this.reply(item, newErrorResponseWithCode(errorCodeTransaction, "transaction statements must target table "+tx.table))
+		return
+	}
+	this.reply(item, newOkResponse("queued"))
+}
+
+// onCommit forwards buffered statements to the table that applies them as a single batch.
+func (this *requestRouter) onCommit(item *requestItem) {
+	tx := item.sender.tx
+	if tx == nil {
+		this.reply(item, newErrorResponseWithCode(errorCodeTransaction, "no transaction in progress"))
+		return
+	}
+	item.sender.tx = nil
+	if len(tx.requests) == 0 {
+		this.reply(item, newOkResponse("commit"))
+		return
+	}
+	if item.req.isStreaming() {
+		tx.setStreaming()
+	}
+	this.dataSrv.acceptRequest(&requestItem{
+		header: item.header,
+		req:    tx,
+		sender: item.sender,
+		dbConn: item.dbConn,
+	})
+}
+
+// onRollback discards buffered statements.
+func (this *requestRouter) onRollback(item *requestItem) {
+	if item.sender.tx == nil {
+		this.reply(item, newErrorResponseWithCode(errorCodeTransaction, "no transaction in progress"))
+		return
+	}
+	item.sender.tx = nil
+	this.reply(item, newOkResponse("rollback"))
+}
+
+// onScript executes statements of the script one after another.
+// Execution stops at the first failed statement, client receives its error
+// or response of the last statement when all of them succeed.
+// Statements run independently, changes made before the failed statement are kept
+// unless the statements are enclosed in begin and commit.
+// Transaction begun by the script is discarded when the script fails before commit.
+func (this *requestRouter) onScript(item *requestItem) {
+	script := item.req.(*sqlScriptRequest)
+	tx := item.sender.tx
+	var res response
+	for idx, req := range script.requests {
+		res = this.execute(item, req)
+		if res == nil {
+			// connection is closed
+			return
+		}
+		if errres, ok := res.(*errorResponse); ok {
+			res = newErrorResponseWithCode(errres.code, "statement "+strconv.Itoa(idx+1)+": "+errres.msg)
+			if item.sender.tx != tx {
+				item.sender.tx = nil
+			}
+			break
+		}
+	}
+	this.reply(item, res)
+}
+
+// onSelectInto forwards select into request and waits until the destination table
+// copies the records, so that following requests of the connection see them.
+// Streaming request is answered too, the response is not sent to the client.
+func (this *requestRouter) onSelectInto(item *requestItem, req *sqlSelectRequest) {
+	streaming := req.isStreaming()
+	req.streaming = false
+	sender := newStatementSender(item.sender)
+	this.dataSrv.acceptRequest(&requestItem{
+		header: item.header,
+		req:    req,
+		sender: sender,
+		dbConn: item.dbConn,
+	})
+	if res := this.wait(item, sender); res != nil && !streaming {
+		this.reply(item, res)
+	}
+}
+
+// execute routes the script statement and waits for its response.
+// Returns nil when the client connection is closed or the server shuts down
+// before the response arrives.
+func (this *requestRouter) execute(item *requestItem, req request) response {
+	sender := newStatementSender(item.sender)
+	this.route(&requestItem{
+		header: item.header,
+		req:    req,
+		sender: sender,
+		dbConn: item.dbConn,
+	})
+	// begin and commit change transaction in progress
+	item.sender.tx = sender.tx
+	return this.wait(item, sender)
+}
+
+// wait waits for the response sent to the statement sender on behalf of the client connection.
+// Returns nil when the client connection is closed or the server shuts down.
+func (this *requestRouter) wait(item *requestItem, sender *responseSender) response {
+	select {
+	case res := <-sender.sender:
+		return dequeued(res)
+	case <-item.sender.quit.GetChan():
+		return nil
+	case <-this.dataSrv.quit.GetChan():
+		return nil
+	}
+}
+
 func (this *requestRouter) onControllerCmd(item *requestItem) {
 	if this.controllerRequests != nil {
 		this.controllerRequests <- item
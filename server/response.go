@@ -55,15 +55,43 @@ func action(builder *JSONBuilder, action string) {
 	builder.nameValue("action", action)
 }
 
+// errorCode is a machine readable code of errorResponse.
+type errorCode string
+
+const (
+	errorCodeGeneric        errorCode = "error"
+	errorCodeSyntax         errorCode = "syntax_error"
+	errorCodeTableNotFound  errorCode = "table_not_found"
+	errorCodeTableExists    errorCode = "table_exists"
+	errorCodeTableNotEmpty  errorCode = "table_not_empty"
+	errorCodeRecordNotFound errorCode = "record_not_found"
+	errorCodeInvalidColumn  errorCode = "invalid_column"
+	errorCodeInvalidFilter  errorCode = "invalid_filter"
+	errorCodeInvalidValue   errorCode = "invalid_value"
+	errorCodeDuplicateKey   errorCode = "duplicate_key"
+	errorCodeTransaction    errorCode = "transaction_error"
+	errorCodeOverflow       errorCode = "subscription_overflow"
+	errorCodeLimitExceeded  errorCode = "limit_exceeded"
+	errorCodeRateLimited    errorCode = "rate_limited"
+	errorCodeServerClosing  errorCode = "server_closing"
+	errorCodeQueryTimeout   errorCode = "query_timeout"
+)
+
 // errorResponse
 type errorResponse struct {
 	requestIdResponse
-	msg string
+	code errorCode
+	msg  string
 }
 
 func newErrorResponse(msg string) *errorResponse {
+	return newErrorResponseWithCode(errorCodeGeneric, msg)
+}
+
+func newErrorResponseWithCode(code errorCode, msg string) *errorResponse {
 	return &errorResponse{
-		msg: msg,
+		code: code,
+		msg:  msg,
 	}
 }
 
@@ -76,11 +104,106 @@ func (this *errorResponse) toNetworkReadyJSON() ([]byte, bool) {
 	builder.beginObject()
 	builder.nameValue("status", "err")
 	builder.valueSeparator()
+	if len(this.code) > 0 {
+		builder.nameValue("code", string(this.code))
+		builder.valueSeparator()
+	}
 	builder.nameValue("msg", this.msg)
 	builder.endObject()
 	return builder.getNetworkBytes(this.requestId), false
 }
 
+// probeResponse asks idle client connection to confirm that it is still alive
+type probeResponse struct {
+	requestIdResponse
+}
+
+func newProbeResponse() *probeResponse {
+	return &probeResponse{}
+}
+
+func (this *probeResponse) getResponsStatus() responseStatusType {
+	return responseStatusOk
+}
+
+func (this *probeResponse) toNetworkReadyJSON() ([]byte, bool) {
+	header := newNetHeader(0, this.requestId)
+	header.Probe = true
+	return header.getBytes(), false
+}
+
+// handshakeResponse carries server version, protocol negotiated with the client
+// and id of the client connection.
+type handshakeResponse struct {
+	requestIdResponse
+	handshake    *handshake
+	connectionId uint64
+}
+
+func newHandshakeResponse(negotiated *handshake, connectionId uint64) *handshakeResponse {
+	return &handshakeResponse{
+		handshake:    negotiated,
+		connectionId: connectionId,
+	}
+}
+
+func (this *handshakeResponse) getResponsStatus() responseStatusType {
+	return responseStatusOk
+}
+
+func (this *handshakeResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "handshake")
+	builder.valueSeparator()
+	builder.nameValue("server", serverVersion)
+	builder.valueSeparator()
+	builder.nameIntValue("protocol", this.handshake.protocol)
+	builder.valueSeparator()
+	builder.string("capabilities")
+	builder.nameSeparator()
+	builder.beginArray()
+	for idx, capability := range this.handshake.capabilities {
+		if idx > 0 {
+			builder.valueSeparator()
+		}
+		builder.string(capability)
+	}
+	builder.endArray()
+	builder.valueSeparator()
+	builder.nameValue("connectionid", strconv.FormatUint(this.connectionId, 10))
+	builder.endObject()
+	return builder.getNetworkBytes(this.requestId), false
+}
+
+// serverClosingResponse notifies client that the server is draining before shutdown,
+// new mutations are rejected and client should reconnect elsewhere.
+type serverClosingResponse struct {
+	requestIdResponse
+}
+
+func newServerClosingResponse() *serverClosingResponse {
+	return &serverClosingResponse{}
+}
+
+func (this *serverClosingResponse) getResponsStatus() responseStatusType {
+	return responseStatusOk
+}
+
+func (this *serverClosingResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "closing")
+	builder.valueSeparator()
+	builder.nameValue("msg", "server is closing")
+	builder.endObject()
+	return builder.getNetworkBytes(0), false
+}
+
 // okResponse
 type okResponse struct {
 	requestIdResponse
@@ -129,6 +252,102 @@ func (this *cmdStatusResponse) toNetworkReadyJSON() ([]byte, bool) {
 	return builder.getNetworkBytes(this.requestId), false
 }
 
+// cmdPingResponse is a response for ping command.
+// Carries accepting or draining state of the server.
+type cmdPingResponse struct {
+	requestIdResponse
+	accepting bool
+}
+
+func newCmdPingResponse(accepting bool) *cmdPingResponse {
+	return &cmdPingResponse{
+		accepting: accepting,
+	}
+}
+
+func (this *cmdPingResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "ping")
+	builder.valueSeparator()
+	if this.accepting {
+		builder.nameValue("state", "accepting")
+	} else {
+		builder.nameValue("state", "draining")
+	}
+	builder.endObject()
+	return builder.getNetworkBytes(this.requestId), false
+}
+
+// cmdStatusTablesResponse is a response for status tables command.
+// Returns one row per table in the select response format.
+type cmdStatusTablesResponse struct {
+	sqlSelectResponse
+}
+
+func newCmdStatusTablesResponse() *cmdStatusTablesResponse {
+	res := new(cmdStatusTablesResponse)
+	for idx, name := range []string{"table", "records", "columns", "subscriptions", "indexbytes"} {
+		res.columns = append(res.columns, &column{name: name, ordinal: idx})
+	}
+	return res
+}
+
+func (this *cmdStatusTablesResponse) addTable(name string, stats tableStats) {
+	rec := &record{
+		values: []string{
+			name,
+			strconv.FormatInt(stats.records, 10),
+			strconv.FormatInt(stats.columns, 10),
+			strconv.FormatInt(stats.subscriptions, 10),
+			strconv.FormatInt(stats.indexBytes, 10),
+		},
+	}
+	this.records = append(this.records, rec)
+}
+
+func (this *cmdStatusTablesResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "status")
+	builder.valueSeparator()
+	more := this.data(builder, false)
+	builder.endObject()
+	return builder.getNetworkBytes(this.requestId), more
+}
+
+// cmdShowTablesResponse is a response for show tables command.
+// Returns one row per table with the table name.
+type cmdShowTablesResponse struct {
+	sqlSelectResponse
+}
+
+func newCmdShowTablesResponse(names []string) *cmdShowTablesResponse {
+	res := new(cmdShowTablesResponse)
+	res.columns = []*column{&column{name: "table"}}
+	res.records = make([]*record, 0, len(names))
+	for _, name := range names {
+		res.records = append(res.records, &record{values: []string{name}})
+	}
+	return res
+}
+
+func (this *cmdShowTablesResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "show")
+	builder.valueSeparator()
+	more := this.data(builder, false)
+	builder.endObject()
+	return builder.getNetworkBytes(this.requestId), more
+}
+
 // sqlSelectResponse is a response for sql select statement
 type sqlSelectResponse struct {
 	requestIdResponse
@@ -139,8 +358,17 @@ type sqlSelectResponse struct {
 	rows    int
 	fromrow int
 	torow   int
+	// set when not all matched records are returned
+	truncated string
+	total     int
 }
 
+// Reasons for select response being truncated.
+const (
+	truncatedByLimit = "limit" // limit or offset clause
+	truncatedByCap   = "cap"   // server maximum number of rows
+)
+
 func row(builder *JSONBuilder, columns []*column, rec *record) {
 	builder.beginArray()
 	// columns and values
@@ -205,6 +433,12 @@ func (this *sqlSelectResponse) data(builder *JSONBuilder, pubsub bool) bool {
 	builder.valueSeparator()
 	builder.nameIntValue("torow", torow)
 	builder.valueSeparator()
+	if len(this.truncated) > 0 {
+		builder.nameValue("truncated", this.truncated)
+		builder.valueSeparator()
+		builder.nameIntValue("total", this.total)
+		builder.valueSeparator()
+	}
 	//
 	builder.string("data")
 	builder.nameSeparator()
@@ -234,13 +468,31 @@ func (this *sqlSelectResponse) toNetworkReadyJSON() ([]byte, bool) {
 	return builder.getNetworkBytes(this.requestId), more
 }
 
+// sqlDescribeResponse is a response for sql describe statement.
+// Every row describes single column of the table.
+type sqlDescribeResponse struct {
+	sqlSelectResponse
+}
+
+func (this *sqlDescribeResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "describe")
+	builder.valueSeparator()
+	more := this.data(builder, false)
+	builder.endObject()
+	return builder.getNetworkBytes(this.requestId), more
+}
+
 func (this *sqlSelectResponse) copyRecordData(source *record) {
 	l := len(this.columns)
 	dest := &record{
 		values: make([]string, l, l),
 	}
 	for idx, col := range this.columns {
-		dest.setValue(idx, source.getValue(col.ordinal))
+		dest.setValue(idx, col.valueOf(source))
 	}
 	addRecordToSlice(&this.records, dest)
 }
@@ -248,7 +500,9 @@ func (this *sqlSelectResponse) copyRecordData(source *record) {
 // sqlActionDataResponse
 type sqlActionDataResponse struct {
 	sqlSelectResponse
-	action string
+	action     string
+	version    int  // new version of the only updated record
+	useVersion bool // true when version is reported
 }
 
 func newUpdateResponse() *sqlActionDataResponse {
@@ -269,6 +523,24 @@ func newInsertResponse() *sqlActionDataResponse {
 	}
 }
 
+func newTruncateResponse() *sqlActionDataResponse {
+	return &sqlActionDataResponse{
+		action: "truncate",
+	}
+}
+
+func newSaveResponse() *sqlActionDataResponse {
+	return &sqlActionDataResponse{
+		action: "save",
+	}
+}
+
+func newLoadResponse() *sqlActionDataResponse {
+	return &sqlActionDataResponse{
+		action: "load",
+	}
+}
+
 func newPushResponse() *sqlActionDataResponse {
 	return &sqlActionDataResponse{
 		action: "push",
@@ -294,11 +566,42 @@ func (this *sqlActionDataResponse) toNetworkReadyJSON() ([]byte, bool) {
 	builder.valueSeparator()
 	action(builder, this.action)
 	builder.valueSeparator()
+	if this.useVersion {
+		builder.nameIntValue("version", this.version)
+		builder.valueSeparator()
+	}
 	more := this.data(builder, false)
 	builder.endObject()
 	return builder.getNetworkBytes(this.requestId), more
 }
 
+// sqlCopyResponse reports number of rows loaded by copy statement
+// and range of ids generated for them.
+type sqlCopyResponse struct {
+	requestIdResponse
+	rows   int
+	fromId int
+	toId   int
+}
+
+func (this *sqlCopyResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, "copy")
+	builder.valueSeparator()
+	builder.nameIntValue("rows", this.rows)
+	if this.rows > 0 {
+		builder.valueSeparator()
+		builder.nameValue("fromid", strconv.Itoa(this.fromId))
+		builder.valueSeparator()
+		builder.nameValue("toid", strconv.Itoa(this.toId))
+	}
+	builder.endObject()
+	return builder.getNetworkBytes(this.requestId), false
+}
+
 // sqlSubscribeResponse
 type sqlSubscribeResponse struct {
 	requestIdResponse
@@ -323,10 +626,45 @@ func newSubscribeResponse(sub *subscription) response {
 	}
 }
 
+// queuedResponse is a pubsub response accounted by subscription queue.
+type queuedResponse interface {
+	setQueue(queue *subscriptionQueue)
+	dequeued()
+}
+
+// sequencedResponse is a pubsub response that carries sequence number
+// of the last table change subscriber has seen once it is processed.
+type sequencedResponse interface {
+	getSeq() uint64
+	setSeq(seq uint64)
+}
+
 // sqlPubSubResponse
 type sqlPubSubResponse struct {
 	sqlSelectResponse
 	pubsubid uint64
+	table    string // source table of table pattern subscription
+	queue    *subscriptionQueue
+	seq      uint64 // sequence number of table change, omitted when 0
+}
+
+func (this *sqlPubSubResponse) setQueue(queue *subscriptionQueue) {
+	this.queue = queue
+}
+
+func (this *sqlPubSubResponse) getSeq() uint64 {
+	return this.seq
+}
+
+func (this *sqlPubSubResponse) setSeq(seq uint64) {
+	this.seq = seq
+}
+
+// dequeued is called once the response is taken from the response sender.
+func (this *sqlPubSubResponse) dequeued() {
+	if this.queue != nil {
+		this.queue.dequeue()
+	}
 }
 
 func (this *sqlPubSubResponse) toNetworkReadyJSONHelper(act string) ([]byte, bool) {
@@ -338,22 +676,110 @@ func (this *sqlPubSubResponse) toNetworkReadyJSONHelper(act string) ([]byte, boo
 	builder.valueSeparator()
 	builder.nameValue("pubsubid", strconv.FormatUint(this.pubsubid, 10))
 	builder.valueSeparator()
+	if len(this.table) > 0 {
+		builder.nameValue("table", this.table)
+		builder.valueSeparator()
+	}
+	if this.seq > 0 {
+		builder.nameValue("seq", strconv.FormatUint(this.seq, 10))
+		builder.valueSeparator()
+	}
 	more := this.data(builder, true)
 	builder.endObject()
 	return builder.getNetworkBytes(0), more
 }
 
 func mergeHelper(res1 *sqlPubSubResponse, res2 *sqlPubSubResponse) bool {
-	if res1.pubsubid != res2.pubsubid {
+	if res1.pubsubid != res2.pubsubid || res1.table != res2.table {
 		return false
 	}
 	if len(res1.columns) != len(res2.columns) {
 		return false
 	}
 	res1.records = append(res1.records, res2.records...)
+	res1.seq = res2.seq
 	return true
 }
 
+// subscriptionOverflowResponse notifies subscriber that it missed events
+// because it did not keep up with them and has to resync.
+type subscriptionOverflowResponse struct {
+	requestIdResponse
+	pubsubid uint64
+	dropped  bool
+}
+
+func newSubscriptionOverflowResponse(pubsubid uint64, dropped bool) *subscriptionOverflowResponse {
+	return &subscriptionOverflowResponse{
+		pubsubid: pubsubid,
+		dropped:  dropped,
+	}
+}
+
+func (this *subscriptionOverflowResponse) getResponsStatus() responseStatusType {
+	return responseStatusErr
+}
+
+func (this *subscriptionOverflowResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	builder.nameValue("status", "err")
+	builder.valueSeparator()
+	builder.nameValue("code", string(errorCodeOverflow))
+	builder.valueSeparator()
+	builder.nameValue("pubsubid", strconv.FormatUint(this.pubsubid, 10))
+	builder.valueSeparator()
+	id := strconv.FormatUint(this.pubsubid, 10)
+	if this.dropped {
+		builder.nameValue("msg", "subscription "+id+" was dropped due to overflow")
+	} else {
+		builder.nameValue("msg", "subscription "+id+" overflowed, events were skipped")
+	}
+	builder.endObject()
+	return builder.getNetworkBytes(0), false
+}
+
+// schemaChangeResponse notifies schema subscriber that table was created
+// or that column was added to the table.
+type schemaChangeResponse struct {
+	requestIdResponse
+	pubsubid uint64
+	action   string // create or alter
+	table    string
+	column   string // added column of alter action
+}
+
+func newSchemaChangeResponse(pubsubid uint64, action string, table string, column string) *schemaChangeResponse {
+	return &schemaChangeResponse{
+		pubsubid: pubsubid,
+		action:   action,
+		table:    table,
+		column:   column,
+	}
+}
+
+func (this *schemaChangeResponse) getResponsStatus() responseStatusType {
+	return responseStatusOk
+}
+
+func (this *schemaChangeResponse) toNetworkReadyJSON() ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, this.action)
+	builder.valueSeparator()
+	builder.nameValue("pubsubid", strconv.FormatUint(this.pubsubid, 10))
+	builder.valueSeparator()
+	builder.nameValue("table", this.table)
+	if len(this.column) > 0 {
+		builder.valueSeparator()
+		builder.nameValue("column", this.column)
+	}
+	builder.endObject()
+	return builder.getNetworkBytes(0), false
+}
+
 // sqlActionAddResponse
 type sqlActionAddResponse struct {
 	sqlPubSubResponse
@@ -372,6 +798,70 @@ func (this *sqlActionAddResponse) merge(res response) bool {
 	return false
 }
 
+// sqlActionCompleteResponse marks the end of initial add actions of a subscription,
+// actions that follow are live updates.
+type sqlActionCompleteResponse struct {
+	requestIdResponse
+	pubsubid uint64
+	table    string // source table of table pattern subscription
+	seq      uint64 // sequence number of the last table change included in initial actions
+}
+
+func (this *sqlActionCompleteResponse) getSeq() uint64 {
+	return this.seq
+}
+
+func (this *sqlActionCompleteResponse) setSeq(seq uint64) {
+	this.seq = seq
+}
+
+func (this *sqlActionCompleteResponse) toNetworkReadyJSON() ([]byte, bool) {
+	return pubSubActionJSON("complete", this.pubsubid, this.table, this.seq)
+}
+
+// sqlActionResyncResponse notifies resuming subscriber that changes since
+// the sequence number it has seen are no longer available.
+// Initial add actions of all matching records follow as if subscriber did not resume.
+type sqlActionResyncResponse struct {
+	requestIdResponse
+	pubsubid uint64
+	table    string // source table of table pattern subscription
+	seq      uint64 // sequence number of the last table change
+}
+
+func (this *sqlActionResyncResponse) getSeq() uint64 {
+	return this.seq
+}
+
+func (this *sqlActionResyncResponse) setSeq(seq uint64) {
+	this.seq = seq
+}
+
+func (this *sqlActionResyncResponse) toNetworkReadyJSON() ([]byte, bool) {
+	return pubSubActionJSON("resync", this.pubsubid, this.table, this.seq)
+}
+
+// Returns network ready JSON of pubsub action without data.
+func pubSubActionJSON(act string, pubsubid uint64, table string, seq uint64) ([]byte, bool) {
+	builder := networkReadyJSONBuilder()
+	builder.beginObject()
+	ok(builder)
+	builder.valueSeparator()
+	action(builder, act)
+	builder.valueSeparator()
+	builder.nameValue("pubsubid", strconv.FormatUint(pubsubid, 10))
+	if len(table) > 0 {
+		builder.valueSeparator()
+		builder.nameValue("table", table)
+	}
+	if seq > 0 {
+		builder.valueSeparator()
+		builder.nameValue("seq", strconv.FormatUint(seq, 10))
+	}
+	builder.endObject()
+	return builder.getNetworkBytes(0), false
+}
+
 // sqlActionInsertResponse
 type sqlActionInsertResponse struct {
 	sqlPubSubResponse
@@ -439,7 +929,7 @@ func (this *sqlActionUpdateResponse) merge(res response) bool {
 	switch res.(type) {
 	case *sqlActionUpdateResponse:
 		source := res.(*sqlActionUpdateResponse)
-		if this.pubsubid != source.pubsubid {
+		if this.pubsubid != source.pubsubid || this.table != source.table {
 			return false
 		}
 		if len(this.columns) != len(source.columns) {
@@ -452,6 +942,7 @@ func (this *sqlActionUpdateResponse) merge(res response) bool {
 			}
 		}
 		this.records = append(this.records, source.records...)
+		this.seq = source.seq
 		return true
 	}
 	return false
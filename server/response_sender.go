@@ -23,6 +23,10 @@ type responseSender struct {
 	connectionId  uint64
 	quit          *Quitter
 	disconnecting bool
+	// transaction in progress, only accessed by the connection reader
+	tx *sqlTransactionRequest
+	// settings changed by set session command, only accessed by the connection reader
+	session *session
 }
 
 // Returns new responseSender.
@@ -32,6 +36,19 @@ func newResponseSenderStub(connectionId uint64) *responseSender {
 		connectionId:  connectionId,
 		quit:          NewQuitter(),
 		disconnecting: false,
+		session:       new(session),
+	}
+}
+
+// Returns sender that receives response of script statement on behalf of the client connection.
+// Statement shares session and transaction in progress with the connection.
+func newStatementSender(sender *responseSender) *responseSender {
+	return &responseSender{
+		sender:       make(chan response, 1),
+		connectionId: sender.connectionId,
+		quit:         NewQuitter(),
+		tx:           sender.tx,
+		session:      sender.session,
 	}
 }
 
@@ -56,7 +73,7 @@ func (this *responseSender) send(res response) bool {
 func (this *responseSender) tryRecv() response {
 	select {
 	case res := <-this.sender:
-		return res
+		return dequeued(res)
 	default:
 		return nil
 	}
@@ -66,5 +83,13 @@ func (this *responseSender) tryRecv() response {
 // recv receives a response from the client.
 // For testing only.
 func (this *responseSender) testRecv() response {
-	return <-this.sender
+	return dequeued(<-this.sender)
+}
+
+// dequeued releases the response from its subscription queue.
+func dequeued(res response) response {
+	if queued, ok := res.(queuedResponse); ok {
+		queued.dequeued()
+	}
+	return res
 }
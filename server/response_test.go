@@ -37,6 +37,18 @@ func TestErrorResponseJSON(t *testing.T) {
 	validateResponseJSON(t, res)
 }
 
+func TestErrorResponseCodeJSON(t *testing.T) {
+	res := newErrorResponseWithCode(errorCodeTableNotFound, "table stocks does not exist")
+	netbytes, _ := res.toNetworkReadyJSON()
+	var v map[string]string
+	if err := json.Unmarshal(fromNetworkBytes(netbytes), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["code"] != "table_not_found" || v["msg"] != "table stocks does not exist" || v["status"] != "err" {
+		t.Errorf("unexpected error response %v", v)
+	}
+}
+
 func TestOkResponseJSON(t *testing.T) {
 	res := &okResponse{}
 	validateResponseJSON(t, res)
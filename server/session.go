@@ -0,0 +1,88 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"strconv"
+	"time"
+)
+
+// session holds settings of the client connection changed by set session command.
+// Settings that were not set fall back to the server configuration.
+type session struct {
+	strictColumns *bool // strict setting, rejects where filters on unknown columns
+	limit         int   // limit setting, default row limit of select statements, 0 means no limit
+	// timeout setting in milliseconds, scan of records is aborted after the timeout, 0 means no timeout
+	queryTimeout *time.Duration
+}
+
+// Changes the setting, returns error response when the setting is unknown
+// or the value is invalid.
+func (this *session) set(name string, val string) response {
+	switch name {
+	case "strict":
+		strict, err := strconv.ParseBool(val)
+		if err != nil {
+			return newErrorResponseWithCode(errorCodeInvalidValue, "invalid value for session setting strict: "+val)
+		}
+		this.strictColumns = &strict
+	case "limit":
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit < 0 {
+			return newErrorResponseWithCode(errorCodeInvalidValue, "invalid value for session setting limit: "+val)
+		}
+		this.limit = limit
+	case "timeout":
+		timeout, err := strconv.Atoi(val)
+		if err != nil || timeout < 0 {
+			return newErrorResponseWithCode(errorCodeInvalidValue, "invalid value for session setting timeout: "+val)
+		}
+		queryTimeout := time.Duration(timeout) * time.Millisecond
+		this.queryTimeout = &queryTimeout
+	default:
+		return newErrorResponse("unknown session setting: " + name)
+	}
+	return nil
+}
+
+// Applies session settings to the request before it is executed.
+func (this *session) apply(req request) {
+	switch x := req.(type) {
+	case *sqlSelectRequest:
+		this.applyFilter(&x.filter)
+		// records copied by select into are not limited
+		if this.limit > 0 && !x.limit.use && len(x.into) == 0 {
+			x.limit.count = this.limit
+			x.limit.use = true
+		}
+	case *sqlUpdateRequest:
+		this.applyFilter(&x.filter)
+	case *sqlDeleteRequest:
+		this.applyFilter(&x.filter)
+	case *sqlSubscribeRequest:
+		this.applyFilter(&x.filter)
+	}
+}
+
+func (this *session) applyFilter(filter *sqlFilter) {
+	if this.strictColumns != nil {
+		filter.strictColumns = this.strictColumns
+	}
+	if this.queryTimeout != nil {
+		filter.queryTimeout = this.queryTimeout
+	}
+}
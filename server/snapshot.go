@@ -0,0 +1,271 @@
+/* Copyright (C) 2013 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Table snapshot is gzip compressed stream of:
+//
+//	magic   "pubsubsql" followed by uint16 version
+//	columns uint32 count, then for every column in ordinal order
+//	        name, uint8 index type, uint8 data type, declared, multi value and range flags
+//	records uint32 count, then for every record from first to last
+//	        uint32 count of values followed by the values in column ordinal order
+//
+// Strings are written as uint32 length followed by the bytes, flags as single byte.
+// All numbers are big endian.
+const (
+	snapshotMagic     = "pubsubsql"
+	snapshotVersion   = 1
+	snapshotExtension = ".snap"
+	// Longest string accepted from the snapshot.
+	snapshotMaxStringSize = 64 * 1024 * 1024
+	// Record ids may exceed number of records by this factor due to deleted records.
+	snapshotMaxIdFactor = 4
+)
+
+// snapshotColumn is a column definition stored in the snapshot.
+type snapshotColumn struct {
+	name       string
+	typ        columnType
+	dataType   columnDataType
+	declared   bool
+	multiValue bool
+	ranged     bool
+}
+
+// snapshot is a decoded table snapshot.
+type snapshot struct {
+	columns []snapshotColumn
+	records [][]string // values of records from first to last, id is the first value
+}
+
+// Returns path of snapshot file inside configured snapshot directory.
+// File name can not refer to other directories and always has the snapshot
+// extension, hence only snapshot files can be written or read.
+func snapshotPath(file string) (string, error) {
+	if config.SNAPSHOT_DIR == "" {
+		return "", errors.New("snapshots are disabled, start the server with snapshotdir")
+	}
+	if file == "" || file != filepath.Base(file) || file == "." || file == ".." {
+		return "", errors.New("invalid snapshot file name " + file)
+	}
+	if !strings.HasSuffix(file, snapshotExtension) {
+		file += snapshotExtension
+	}
+	return filepath.Join(config.SNAPSHOT_DIR, file), nil
+}
+
+// snapshotWriter writes snapshot values remembering the first error.
+type snapshotWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (this *snapshotWriter) write(data interface{}) {
+	if this.err == nil {
+		this.err = binary.Write(this.writer, binary.BigEndian, data)
+	}
+}
+
+func (this *snapshotWriter) writeString(str string) {
+	this.write(uint32(len(str)))
+	if this.err == nil {
+		_, this.err = io.WriteString(this.writer, str)
+	}
+}
+
+func (this *snapshotWriter) writeBool(b bool) {
+	if b {
+		this.write(uint8(1))
+	} else {
+		this.write(uint8(0))
+	}
+}
+
+// snapshotReader reads snapshot values remembering the first error.
+type snapshotReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (this *snapshotReader) read(data interface{}) {
+	if this.err == nil {
+		this.err = binary.Read(this.reader, binary.BigEndian, data)
+	}
+}
+
+func (this *snapshotReader) readUint32() int {
+	var val uint32
+	this.read(&val)
+	return int(val)
+}
+
+func (this *snapshotReader) readUint8() uint8 {
+	var val uint8
+	this.read(&val)
+	return val
+}
+
+func (this *snapshotReader) readString() string {
+	size := this.readUint32()
+	if this.err != nil {
+		return ""
+	}
+	if size > snapshotMaxStringSize {
+		this.err = errors.New("string length " + strconv.Itoa(size) + " is too large")
+		return ""
+	}
+	// buffer grows with the data actually read so the length is not trusted
+	var buffer bytes.Buffer
+	_, this.err = io.CopyN(&buffer, this.reader, int64(size))
+	return buffer.String()
+}
+
+func (this *snapshotReader) readBool() bool {
+	return this.readUint8() != 0
+}
+
+// Writes the table columns and records to the snapshot file.
+// Snapshot is written to a temporary file in the same directory that replaces
+// the snapshot file only after it was completely written and synced to disk,
+// so that a crash while saving never leaves truncated snapshot.
+// Returns number of saved records.
+func (this *table) saveSnapshot(path string) (int, error) {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	temp := file.Name()
+	buffered := bufio.NewWriter(file)
+	compressed := gzip.NewWriter(buffered)
+	writer := this.writeSnapshot(compressed)
+	err = writer.err
+	if err == nil {
+		err = compressed.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, path)
+	}
+	if err != nil {
+		os.Remove(temp)
+		return 0, err
+	}
+	return int(this.count), nil
+}
+
+// Writes uncompressed snapshot.
+func (this *table) writeSnapshot(w io.Writer) *snapshotWriter {
+	writer := &snapshotWriter{writer: w}
+	_, writer.err = io.WriteString(w, snapshotMagic)
+	writer.write(uint16(snapshotVersion))
+	writer.write(uint32(len(this.colSlice)))
+	for _, col := range this.colSlice {
+		writer.writeString(col.name)
+		writer.write(uint8(col.typ))
+		writer.write(uint8(col.dataType))
+		writer.writeBool(col.declared)
+		writer.writeBool(col.multiValue)
+		writer.writeBool(col.ranges != nil)
+	}
+	writer.write(this.count)
+	for rec := this.first; rec != nil; rec = rec.next {
+		writer.write(uint32(len(this.colSlice)))
+		for _, col := range this.colSlice {
+			writer.writeString(rec.getValue(col.ordinal))
+		}
+	}
+	return writer
+}
+
+// Reads and decodes the snapshot file.
+func readSnapshotFile(path string) (*snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	defer compressed.Close()
+	return readSnapshot(compressed)
+}
+
+// Decodes uncompressed snapshot.
+func readSnapshot(r io.Reader) (*snapshot, error) {
+	reader := &snapshotReader{reader: r}
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, errors.New("not a table snapshot")
+	}
+	var version uint16
+	reader.read(&version)
+	if reader.err == nil && version != snapshotVersion {
+		return nil, errors.New("unsupported snapshot version " + strconv.Itoa(int(version)))
+	}
+	snap := new(snapshot)
+	count := reader.readUint32()
+	for i := 0; i < count && reader.err == nil; i++ {
+		var col snapshotColumn
+		col.name = reader.readString()
+		col.typ = columnType(reader.readUint8())
+		col.dataType = columnDataType(reader.readUint8())
+		col.declared = reader.readBool()
+		col.multiValue = reader.readBool()
+		col.ranged = reader.readBool()
+		snap.columns = append(snap.columns, col)
+	}
+	count = reader.readUint32()
+	for i := 0; i < count && reader.err == nil; i++ {
+		size := reader.readUint32()
+		if reader.err == nil && size != len(snap.columns) {
+			return nil, errors.New("corrupted table snapshot: record does not match columns")
+		}
+		values := make([]string, size)
+		for idx := 0; idx < len(values) && reader.err == nil; idx++ {
+			values[idx] = reader.readString()
+		}
+		snap.records = append(snap.records, values)
+	}
+	if reader.err != nil {
+		return nil, errors.New("corrupted table snapshot: " + reader.err.Error())
+	}
+	return snap, nil
+}
@@ -0,0 +1,100 @@
+/* Copyright (C) 2014 CompleteDB LLC.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with PubSubSQL.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector multi"))
+	insertHelper(tbl, " insert into stocks (ticker, sector) values (IBM, 'TECH,IT') ")
+	pc := newTokens()
+	lex(" push front into stocks (ticker) values (MSFT) ", pc)
+	tbl.sqlPush(parse(pc).(*sqlPushRequest))
+	var buffer bytes.Buffer
+	if err := tbl.writeSnapshot(&buffer).err; err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	snap, err := readSnapshot(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.columns) != 3 || snap.columns[1].typ != columnTypeTag || !snap.columns[1].multiValue {
+		t.Errorf("snapshot error: unexpected columns %v", snap.columns)
+	}
+	// records are stored from first to last
+	if len(snap.records) != 2 || snap.records[0][0] != "1" || snap.records[1][1] != "TECH,IT" {
+		t.Errorf("snapshot error: unexpected records %v", snap.records)
+	}
+	// truncated and foreign data
+	if _, err := readSnapshot(bytes.NewReader(data[:len(data)-3])); err == nil {
+		t.Errorf("snapshot error: expected error for truncated snapshot")
+	}
+	if _, err := readSnapshot(bytes.NewReader([]byte("not a snapshot"))); err == nil {
+		t.Errorf("snapshot error: expected error for invalid snapshot")
+	}
+	// string length beyond the limit or the remaining data is not allocated
+	var huge bytes.Buffer
+	writer := &snapshotWriter{writer: &huge}
+	huge.WriteString(snapshotMagic)
+	writer.write(uint16(snapshotVersion))
+	writer.write(uint32(1))
+	writer.write(uint32(0xFFFFFFF0))
+	if _, err := readSnapshot(bytes.NewReader(huge.Bytes())); err == nil {
+		t.Errorf("snapshot error: expected error for too large string")
+	}
+	huge.Truncate(huge.Len() - 4)
+	writer.write(uint32(snapshotMaxStringSize))
+	huge.WriteString("id")
+	if _, err := readSnapshot(bytes.NewReader(huge.Bytes())); err == nil {
+		t.Errorf("snapshot error: expected error for truncated string")
+	}
+	data[len(snapshotMagic)+1]++
+	if _, err := readSnapshot(bytes.NewReader(data)); err == nil {
+		t.Errorf("snapshot error: expected error for unsupported version")
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	defer func(dir string) { config.SNAPSHOT_DIR = dir }(config.SNAPSHOT_DIR)
+	// snapshots are disabled by default
+	config.SNAPSHOT_DIR = defaultConfig().SNAPSHOT_DIR
+	if _, err := snapshotPath("stocks.snap"); err == nil {
+		t.Errorf("snapshot error: expected snapshots to be disabled")
+	}
+	config.SNAPSHOT_DIR = "snapshots"
+	for _, file := range []string{"", ".", "..", "../stocks", "/tmp/stocks", "dir/stocks"} {
+		if _, err := snapshotPath(file); err == nil {
+			t.Errorf("snapshot error: expected invalid file name %s", file)
+		}
+	}
+	// files without snapshot extension can not be written or read
+	for file, expected := range map[string]string{
+		"stocks.snap": filepath.Join("snapshots", "stocks.snap"),
+		"stocks":      filepath.Join("snapshots", "stocks.snap"),
+		"config.json": filepath.Join("snapshots", "config.json.snap"),
+	} {
+		if path, err := snapshotPath(file); err != nil || path != expected {
+			t.Errorf("snapshot error: expected %s but got %s %v", expected, path, err)
+		}
+	}
+}
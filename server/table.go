@@ -17,8 +17,14 @@
 package server
 
 import (
+	"crypto/rand"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
+	"unsafe"
 )
 
 // this function is purely for testing porposes
@@ -36,6 +42,26 @@ func (this *table) getTagedColumnValuesCount(name string, val string) int {
 
 var subid uint64 = 0
 
+// tableStats holds table statistics reported by status tables command.
+// Stored by the table event loop and loaded by the data service atomically,
+// so sampling statistics never waits on a busy table.
+type tableStats struct {
+	records       int64
+	columns       int64
+	subscriptions int64
+	indexBytes    int64
+}
+
+// load returns a consistent enough copy of the statistics.
+func (this *tableStats) load() tableStats {
+	return tableStats{
+		records:       atomic.LoadInt64(&this.records),
+		columns:       atomic.LoadInt64(&this.columns),
+		subscriptions: atomic.LoadInt64(&this.subscriptions),
+		indexBytes:    atomic.LoadInt64(&this.indexBytes),
+	}
+}
+
 // table
 type table struct {
 	name         string
@@ -43,7 +69,10 @@ type table struct {
 	colSlice     []*column
 	records      []*record
 	tagedColumns []*column
+	rangeColumns []*column
 	pubsub       pubsub
+	// routes requests to other tables
+	dataSrv *dataService
 	//
 	subscriptions mapSubscriptionByConnection
 	//
@@ -57,7 +86,29 @@ type table struct {
 	//
 	last  *record
 	first *record
-}
+	//
+	stats *tableStats
+	// transaction in progress
+	tx *tableTransaction
+	// records by uuid id, nil when ids are sequential
+	uuids map[string]*record
+	// earliest expiry of records inserted with ttl, 0 when no record expires
+	nextExpiry int64
+	// sequence number of the last record change, published with pubsub events
+	seq uint64
+	// the most recent record changes replayed to resuming subscribers
+	events *eventLog
+}
+
+// Record id formats.
+// Sequential ids are positions of records in the table starting from 0,
+// they are unique until the table is truncated, which starts them from 0 again.
+// Uuid ids are random version 4 uuids, they are regenerated on the unlikely
+// collision with an id of existing record so they are unique within the table.
+const (
+	idFormatSequential = "sequential"
+	idFormatUuid       = "uuid"
+)
 
 // table factory
 func newTable(name string) *table {
@@ -67,14 +118,48 @@ func newTable(name string) *table {
 		colSlice:      make([]*column, 0, config.TABLE_COLUMNS_CAPACITY),
 		records:       make([]*record, 0, config.TABLE_RECORDS_CAPACITY),
 		tagedColumns:  make([]*column, 0, config.TABLE_COLUMNS_CAPACITY),
+		rangeColumns:  make([]*column, 0, config.TABLE_COLUMNS_CAPACITY),
 		subscriptions: make(mapSubscriptionByConnection),
 		requestId:     0,
 		streaming:     false,
+		stats:         new(tableStats),
+		events:        newEventLog(config.TABLE_EVENT_BUFFER_SIZE),
 	}
 	table.addColumn("id")
+	table.updateStats()
 	return table
 }
 
+// updateStats stores current table statistics.
+func (this *table) updateStats() {
+	subscriptions := 0
+	for _, mapsub := range this.subscriptions {
+		for _, sub := range mapsub {
+			if sub.active() {
+				subscriptions++
+			}
+		}
+	}
+	atomic.StoreInt64(&this.stats.records, int64(this.count))
+	atomic.StoreInt64(&this.stats.columns, int64(len(this.colSlice)))
+	atomic.StoreInt64(&this.stats.subscriptions, int64(subscriptions))
+	atomic.StoreInt64(&this.stats.indexBytes, this.indexBytes())
+}
+
+// indexBytes estimates memory used by key, tag and range indexes.
+// Every record is assumed to have a value in every indexed column.
+func (this *table) indexBytes() int64 {
+	var bytes int64
+	for _, col := range this.tagedColumns {
+		bytes += int64(len(col.tagmap.tags)) * int64(unsafe.Sizeof(tagItem{}))
+		bytes += int64(this.count) * int64(unsafe.Sizeof(tag{}))
+	}
+	for _, col := range this.rangeColumns {
+		bytes += int64(col.ranges.count) * int64(unsafe.Sizeof(rangeIndexNode{}))
+	}
+	return bytes
+}
+
 // COLUMNS functions
 
 // Returns total number of columns.
@@ -94,6 +179,35 @@ func (this *table) addColumn(name string) *column {
 	return col
 }
 
+// Validates that adding columns that do not exist yet would not exceed
+// maximum number of columns per table.
+func (this *table) validateColumnLimit(names []string) response {
+	if config.TABLE_MAX_COLUMNS <= 0 {
+		return nil
+	}
+	count := len(this.colSlice)
+	added := make(map[string]bool)
+	for _, name := range names {
+		if this.getColumn(name) == nil && !added[name] {
+			added[name] = true
+			count++
+		}
+	}
+	if count > config.TABLE_MAX_COLUMNS {
+		return newErrorResponseWithCode(errorCodeLimitExceeded, "table "+this.name+" can not have more than "+strconv.Itoa(config.TABLE_MAX_COLUMNS)+" columns, maximum number of columns reached")
+	}
+	return nil
+}
+
+// Returns column names of column values.
+func columnValueNames(colVals []*columnValue) []string {
+	names := make([]string, len(colVals))
+	for idx, colVal := range colVals {
+		names[idx] = colVal.col
+	}
+	return names
+}
+
 // Tries to retrieve existing column or adds it if does not existhis.
 // Returns true when new column was added.
 func (this *table) getAddColumn(name string) (*column, bool) {
@@ -132,15 +246,37 @@ func (this *table) removeColumns(ordinal int) {
 func (this *table) prepareRecord() (*record, int) {
 	id := len(this.records)
 	rec := newRecord(len(this.colSlice), id)
+	if this.uuids != nil {
+		rec.setValue(0, this.newUuid())
+	}
 	l := len(this.tagedColumns) + 1
 	rec.links = make([]link, l)
 	return rec, id
 }
 
+// Returns random version 4 uuid that is not used by any record of the table.
+func (this *table) newUuid() string {
+	var b [16]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic("failed to generate uuid: " + err.Error())
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		id := fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		if this.uuids[id] == nil {
+			return id
+		}
+	}
+}
+
 // adNewRecord add newly created record to the table
 func (this *table) addNewRecord(rec *record, back bool) {
 	this.count++
 	addRecordToSlice(&this.records, rec)
+	if this.uuids != nil {
+		this.uuids[rec.idAsString()] = rec
+	}
 	// initial record
 	if this.first == nil {
 		this.first = rec
@@ -191,10 +327,15 @@ func (this *table) deleteRecord(rec *record) {
 	for _, col := range this.tagedColumns {
 		this.deleteTag(rec, col)
 	}
+	// delete record range values
+	for _, col := range this.rangeColumns {
+		col.ranges.remove(rec.getValue(col.ordinal), rec.id())
+	}
 	// delete record
 	if this.records[rec.id()] != nil {
 		this.count--
 		this.records[rec.id()] = nil
+		delete(this.uuids, rec.idAsString())
 	}
 	//
 	if rec == this.last {
@@ -214,6 +355,12 @@ func (this *table) deleteRecord(rec *record) {
 // Looks up record by id.
 // Returns record slice with max one elementhis.
 func (this *table) getRecordById(val string) []*record {
+	if this.uuids != nil {
+		if rec := this.uuids[val]; rec != nil {
+			return []*record{rec}
+		}
+		return nil
+	}
 	idx, err := strconv.ParseInt(val, 10, 32)
 	if err != nil {
 		return nil
@@ -233,11 +380,15 @@ func (this *table) validateSqlFilter(filter sqlFilter) (response, *column) {
 	if len(filter.col) > 0 {
 		col = this.getColumn(filter.col)
 		if col == nil {
-			return newErrorResponse("invalid column: " + filter.col), nil
+			return newErrorResponseWithCode(errorCodeInvalidColumn, "unknown column: "+filter.col), nil
 		}
 	}
+	if filter.between || filter.not || filter.nocase {
+		// range, negation and case insensitive equality are evaluated by scanning the records
+		return nil, col
+	}
 	if col != nil && col.typ == columnTypeNormal {
-		return newErrorResponse("can not use non indexed column " + filter.col + " as valid filter"), nil
+		return newErrorResponseWithCode(errorCodeInvalidFilter, "can not use non indexed column "+filter.col+" as valid filter"), nil
 	}
 	return nil, col
 }
@@ -261,13 +412,246 @@ func (this *table) getRecordsByValue(val string, col *column) []*record {
 
 // Retrieves records based on the supplied filter
 func (this *table) getRecordsBySqlFilter(filter sqlFilter) ([]*record, response) {
+	deadline := newQueryDeadline(filter.getQueryTimeout(), time.Now())
+	if filter.or != nil {
+		return this.getRecordsByOrFilter(&filter, deadline)
+	}
+	if len(filter.col) > 0 && !filter.isStrict() && this.getColumn(filter.col) == nil {
+		filters := []*sqlFilter{&filter}
+		return this.scanRecordsByOrFilter(filters, []*column{this.unknownColumn(filter.col)}, deadline)
+	}
 	e, col := this.validateSqlFilter(filter)
 	if e != nil {
 		return nil, e
 	}
+	if filter.not {
+		return this.getRecordsByNegatedFilter(filter, col, deadline)
+	}
+	if filter.nocase && !isIndexedFilter(&filter, col) {
+		return this.scanRecordsByOrFilter([]*sqlFilter{&filter}, []*column{col}, deadline)
+	}
+	if filter.between {
+		return this.getRecordsByRange(filter.val, filter.to, col, deadline)
+	}
 	return this.getRecordsByValue(filter.val, col), nil
 }
 
+// Retrieves records matching any of or filters.
+// When every filter can be answered by an index the records found by each index lookup
+// are merged in id order, otherwise the records are scanned.
+// Values of in (value, ...) list are or filters too, thus id in list is answered by
+// direct id lookups, records are returned in id order regardless of the list order
+// and ids that do not exist are absent from the result.
+func (this *table) getRecordsByOrFilter(filter *sqlFilter, deadline *queryDeadline) ([]*record, response) {
+	var filters []*sqlFilter
+	var cols []*column
+	indexed := true
+	strict := filter.isStrict()
+	for f := filter; f != nil; f = f.or {
+		col := this.getColumn(f.col)
+		if col == nil {
+			if strict {
+				return nil, newErrorResponseWithCode(errorCodeInvalidColumn, "unknown column: "+f.col)
+			}
+			col = this.unknownColumn(f.col)
+		}
+		filters = append(filters, f)
+		cols = append(cols, col)
+		indexed = indexed && isIndexedFilter(f, col)
+	}
+	if !indexed {
+		return this.scanRecordsByOrFilter(filters, cols, deadline)
+	}
+	records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	found := make(map[*record]bool)
+	for idx, f := range filters {
+		var matched []*record
+		if f.between {
+			var errres response
+			if matched, errres = this.getRecordsByRange(f.val, f.to, cols[idx], deadline); errres != nil {
+				return nil, errres
+			}
+		} else {
+			matched = this.getRecordsByValue(f.val, cols[idx])
+		}
+		for _, rec := range matched {
+			if rec != nil && !found[rec] {
+				found[rec] = true
+				records = append(records, rec)
+			}
+		}
+	}
+	if len(filters) > 1 {
+		sort.Sort(&recordsByValue{records: records, ordinal: 0})
+	}
+	return records, nil
+}
+
+// Returns detached column that has no value in any record.
+// Used to evaluate filters on unknown columns when columns are not strict.
+func (this *table) unknownColumn(name string) *column {
+	return newColumn(name, len(this.colSlice))
+}
+
+// Returns true if records matching the filter can be looked up by an index.
+func isIndexedFilter(filter *sqlFilter, col *column) bool {
+	if filter.not || filter.ignoresCase(col) {
+		return false
+	}
+	if filter.between {
+		return col.ranges != nil
+	}
+	return col.typ != columnTypeNormal
+}
+
+// Scans records that match any of or filters.
+// Returns query timeout error when the scan does not finish before the deadline.
+func (this *table) scanRecordsByOrFilter(filters []*sqlFilter, cols []*column, deadline *queryDeadline) ([]*record, response) {
+	records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	for _, rec := range this.records {
+		if deadline.expired() {
+			return nil, this.newQueryTimeoutResponse(deadline)
+		}
+		if rec == nil {
+			continue
+		}
+		for idx, f := range filters {
+			if filterMatches(f, cols[idx], rec.getValue(cols[idx].ordinal)) != f.not {
+				records = append(records, rec)
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+// Returns true if the value matches filter predicate ignoring not.
+func filterMatches(filter *sqlFilter, col *column, recVal string) bool {
+	val := filter.val
+	if col.typ == columnTypeId {
+		// ids are matched numerically by getRecordById
+		if id, err := strconv.ParseInt(val, 10, 32); err == nil {
+			val = strconv.FormatInt(id, 10)
+		}
+	}
+	if filter.between {
+		return compareValues(val, recVal) <= 0 && compareValues(recVal, filter.to) <= 0
+	}
+	if filter.ignoresCase(col) {
+		if col.multiValue {
+			for _, v := range col.tagValues(recVal) {
+				if strings.EqualFold(v, val) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.EqualFold(recVal, val)
+	}
+	if col.multiValue {
+		return containsString(col.tagValues(recVal), val)
+	}
+	return recVal == val
+}
+
+// Returns true if the filter compares values of the column case insensitively.
+// Collation is ignored by numeric and boolean columns.
+func (this *sqlFilter) ignoresCase(col *column) bool {
+	return this.nocase && col.dataType == columnDataTypeString
+}
+
+// Looks up records for column values within inclusive from and to range.
+// Uses range index when defined for the column, otherwise scans the records.
+// Returns query timeout error when the scan does not finish before the deadline.
+func (this *table) getRecordsByRange(from string, to string, col *column, deadline *queryDeadline) ([]*record, response) {
+	if col.ranges != nil {
+		return col.ranges.getRecords(from, to), nil
+	}
+	records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	for _, rec := range this.records {
+		if deadline.expired() {
+			return nil, this.newQueryTimeoutResponse(deadline)
+		}
+		if rec == nil {
+			continue
+		}
+		val := rec.getValue(col.ordinal)
+		if compareValues(from, val) <= 0 && compareValues(val, to) <= 0 {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// Scans records that do not match the filter.
+// Indexes can not be used to find records that are not in them.
+func (this *table) getRecordsByNegatedFilter(filter sqlFilter, col *column, deadline *queryDeadline) ([]*record, response) {
+	records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	for _, rec := range this.records {
+		if deadline.expired() {
+			return nil, this.newQueryTimeoutResponse(deadline)
+		}
+		if rec == nil {
+			continue
+		}
+		if !filterMatches(&filter, col, rec.getValue(col.ordinal)) {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// queryDeadline aborts scan of records that takes longer than the query timeout.
+// Clock is read once per queryDeadlineInterval scanned records.
+type queryDeadline struct {
+	timeout time.Duration
+	at      time.Time // zero when query has no timeout
+	scanned int
+}
+
+const queryDeadlineInterval = 1024
+
+func newQueryDeadline(timeout time.Duration, now time.Time) *queryDeadline {
+	deadline := &queryDeadline{timeout: timeout}
+	if timeout > 0 {
+		deadline.at = now.Add(timeout)
+	}
+	return deadline
+}
+
+// Returns true once the deadline has passed.
+func (this *queryDeadline) expired() bool {
+	if this.at.IsZero() {
+		return false
+	}
+	this.scanned++
+	return this.scanned%queryDeadlineInterval == 0 && time.Now().After(this.at)
+}
+
+// Returns error response of aborted scan, records matched so far are discarded.
+func (this *table) newQueryTimeoutResponse(deadline *queryDeadline) response {
+	return newErrorResponseWithCode(errorCodeQueryTimeout, "query timeout: scan of table "+this.name+" was aborted after "+deadline.timeout.String())
+}
+
+// Returns true when value is one of the values.
+func containsString(values []string, val string) bool {
+	for _, v := range values {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// Compares values numerically when both are numbers
+// falling back to string comparison otherwise.
+// Numbers go before other values so that the order matches range index.
+func compareValues(a string, b string) int {
+	x := newRangeKey(a, 0)
+	y := newRangeKey(b, 0)
+	return x.compareValue(&y)
+}
+
 // Looks up records by tag.
 func (this *table) getRecordsByTag(val string, col *column) []*record {
 	// we need to optimize allocations
@@ -342,6 +726,10 @@ func (ra *pubsubRA) toBeAdded(pubsub *pubsub) {
 }
 
 func (this *table) updateRecordKeyTag(col *column, val string, rec *record, id int, ra **pubsubRA) {
+	if col.multiValue {
+		this.updateRecordMultiValueTag(col, val, rec, id, ra)
+		return
+	}
 	removed := this.deleteTag(rec, col)
 	rec.setValue(col.ordinal, val)
 	added := this.tagValue(col, id, rec)
@@ -356,11 +744,49 @@ func (this *table) updateRecordKeyTag(col *column, val string, rec *record, id i
 	(*ra).toBeAdded(added)
 }
 
+// Retags record with new values of multi value tag column.
+// Only subscriptions of values that were removed or added are notified.
+func (this *table) updateRecordMultiValueTag(col *column, val string, rec *record, id int, ra **pubsubRA) {
+	removed := rec.links[col.tagIndex].getPubsubs()
+	this.deleteTag(rec, col)
+	rec.setValue(col.ordinal, val)
+	this.tagValue(col, id, rec)
+	added := rec.links[col.tagIndex].getPubsubs()
+	for _, pubsub := range removed {
+		if !containsPubsub(added, pubsub) {
+			if *ra == nil {
+				*ra = newPubsubRA()
+			}
+			(*ra).toBeRemoved(pubsub)
+		}
+	}
+	for _, pubsub := range added {
+		if !containsPubsub(removed, pubsub) {
+			if *ra == nil {
+				*ra = newPubsubRA()
+			}
+			(*ra).toBeAdded(pubsub)
+		}
+	}
+}
+
+func containsPubsub(pubsubs []*pubsub, pubsub *pubsub) bool {
+	for _, p := range pubsubs {
+		if p == pubsub {
+			return true
+		}
+	}
+	return false
+}
+
 // Updates record with new values, keys and tags.
 func (this *table) updateRecord(cols []*column, colVals []*columnValue, rec *record, id int) *pubsubRA {
 	var ra *pubsubRA
 	for idx, colVal := range colVals {
 		col := cols[idx]
+		if col.ranges != nil {
+			col.ranges.remove(rec.getValue(col.ordinal), id)
+		}
 		switch col.typ {
 		case columnTypeKey:
 			this.updateRecordKeyTag(col, colVal.val, rec, id, &ra)
@@ -369,10 +795,20 @@ func (this *table) updateRecord(cols []*column, colVals []*columnValue, rec *rec
 		case columnTypeNormal:
 			rec.setValue(col.ordinal, colVal.val)
 		}
+		if col.ranges != nil {
+			col.ranges.add(colVal.val, id, rec)
+		}
 	}
 	return getIfHasData(ra)
 }
 
+// Adds record values to range indexes.
+func (this *table) rangeRecord(rec *record, id int) {
+	for _, col := range this.rangeColumns {
+		col.ranges.add(rec.getValue(col.ordinal), id, rec)
+	}
+}
+
 // TAGS helper functions
 
 // Add value to non unique indexed column.
@@ -381,12 +817,23 @@ func addValueToTags(col *column, val string, idx int) (*tag, *pubsub) {
 }
 
 // Binds tag, pubsub and record.
+// Record is tagged with every value of multi value tag column.
 func (this *table) tagValue(col *column, idx int, rec *record) *pubsub {
 	val := rec.getValue(col.ordinal)
+	var more []link
+	if col.multiValue {
+		values := col.tagValues(val)
+		val = values[0]
+		for _, v := range values[1:] {
+			tg, pubsub := addValueToTags(col, v, idx)
+			more = append(more, link{tg: tg, pubsub: pubsub})
+		}
+	}
 	tg, pubsub := addValueToTags(col, val, idx)
 	lnk := link{
 		tg:     tg,
 		pubsub: pubsub,
+		more:   more,
 	}
 	if len(rec.links) <= col.tagIndex {
 		rec.links = append(rec.links, lnk)
@@ -399,23 +846,39 @@ func (this *table) tagValue(col *column, idx int, rec *record) *pubsub {
 // Deletes tag value for a particular record
 func (this *table) deleteTag(rec *record, col *column) *pubsub {
 	lnk := &rec.links[col.tagIndex]
-	if lnk.tg != nil {
-		switch removeTag(lnk.tg) {
-		case removeTagLast:
-			col.tagmap.removeTag(rec.getValue(col.ordinal))
-		case removeTagSlide:
-			// we need to retag the slided record
-			slidedRecord := this.records[lnk.tg.idx]
-			if slidedRecord != nil {
-				slidedRecord.links[col.tagIndex].tg = lnk.tg
-			}
+	val := rec.getValue(col.ordinal)
+	if col.multiValue {
+		values := col.tagValues(val)
+		val = values[0]
+		for idx := range lnk.more {
+			this.untagValue(col, values[idx+1], &lnk.more[idx])
 		}
 	}
+	this.untagValue(col, val, lnk)
 	ret := lnk.pubsub
 	lnk.clear()
 	return ret
 }
 
+// Removes tag of the link from the value tags.
+func (this *table) untagValue(col *column, val string, lnk *link) {
+	if lnk.tg == nil {
+		return
+	}
+	// next tag slides into the head
+	next := lnk.tg.next
+	switch removeTag(lnk.tg) {
+	case removeTagLast:
+		col.tagmap.removeTag(val)
+	case removeTagSlide:
+		// we need to retag the slided record
+		slidedRecord := this.records[lnk.tg.idx]
+		if slidedRecord != nil {
+			slidedRecord.links[col.tagIndex].retag(next, lnk.tg)
+		}
+	}
+}
+
 // INSERT sql statement
 func (this *table) setReturningColumns(ret *returningColumns) (response, *[]*column) {
 	if !ret.use {
@@ -428,7 +891,7 @@ func (this *table) setReturningColumns(ret *returningColumns) (response, *[]*col
 	for idx, colName := range ret.cols {
 		col := this.getColumn(colName)
 		if col == nil {
-			return newErrorResponse("operation failed, returning column " + colName + " does not exist"), nil
+			return newErrorResponseWithCode(errorCodeInvalidColumn, "operation failed, returning column "+colName+" does not exist"), nil
 		}
 		columns[idx] = col
 	}
@@ -442,6 +905,9 @@ func (this *table) sqlInsert(req *sqlInsertRequest) response {
 }
 
 func (this *table) sqlInsertHelper(req *sqlInsertRequest, action string, back bool) response {
+	if errres := this.validateColumnLimit(columnValueNames(req.colVals)); errres != nil {
+		return errres
+	}
 	rec, id := this.prepareRecord()
 	// validate unique keys constrain
 	cols := make([]*column, len(req.colVals))
@@ -451,7 +917,11 @@ func (this *table) sqlInsertHelper(req *sqlInsertRequest, action string, back bo
 		if col.isKey() && col.keyContainsValue(colVal.val) {
 			//remove created columns
 			this.removeColumns(originalColLen)
-			return newErrorResponse("insert failed due to duplicate column key:" + colVal.col + " value:" + colVal.val)
+			return newErrorResponseWithCode(errorCodeDuplicateKey, "insert failed due to duplicate column key:"+colVal.col+" value:"+colVal.val)
+		}
+		if !col.isValidValue(colVal.val) {
+			this.removeColumns(originalColLen)
+			return newErrorResponseWithCode(errorCodeInvalidValue, "insert failed due to invalid "+col.dataType.String()+" column:"+colVal.col+" value:"+colVal.val)
 		}
 		cols[idx] = col
 	}
@@ -464,7 +934,14 @@ func (this *table) sqlInsertHelper(req *sqlInsertRequest, action string, back bo
 	}
 	// ready to insert
 	this.bindRecord(cols, req.colVals, rec, id)
+	this.rangeRecord(rec, id)
 	this.addNewRecord(rec, back)
+	if req.ttl > 0 {
+		this.expireAfter(rec, req.ttl, time.Now())
+	}
+	if this.tx != nil {
+		this.tx.logUndo(func() { this.removeNewRecord(rec) })
+	}
 	res := &sqlActionDataResponse{action: action}
 	this.prepareSelectResponse(&res.sqlSelectResponse, retCols, 1)
 	this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
@@ -476,6 +953,70 @@ func (this *table) sqlPush(req *sqlPushRequest) response {
 	return this.sqlInsertHelper(&req.sqlInsertRequest, "push", !req.front)
 }
 
+// COPY sql statement
+
+// sqlCopy inserts all rows of bulk load at once.
+// Every row is validated before the first record is inserted so that either all
+// or none of the rows are loaded, range indexes are updated once all records are in place.
+func (this *table) sqlCopy(req *sqlCopyRequest) response {
+	if errres := this.validateColumnLimit(req.cols); errres != nil {
+		return errres
+	}
+	originalColLen := len(this.colSlice)
+	cols := make([]*column, len(req.cols))
+	for idx, name := range req.cols {
+		cols[idx], _ = this.getAddColumn(name)
+	}
+	// validate values and unique keys constrain
+	keys := make(map[*column]map[string]bool)
+	for _, values := range req.rows {
+		for idx, val := range values {
+			col := cols[idx]
+			if !col.isValidValue(val) {
+				this.removeColumns(originalColLen)
+				return newErrorResponseWithCode(errorCodeInvalidValue, "copy failed due to invalid "+col.dataType.String()+" column:"+col.name+" value:"+val)
+			}
+			if !col.isKey() {
+				continue
+			}
+			if keys[col] == nil {
+				keys[col] = make(map[string]bool, len(req.rows))
+			}
+			if keys[col][val] || col.keyContainsValue(val) {
+				this.removeColumns(originalColLen)
+				return newErrorResponseWithCode(errorCodeDuplicateKey, "copy failed due to duplicate column key:"+col.name+" value:"+val)
+			}
+			keys[col][val] = true
+		}
+	}
+	// ready to insert
+	res := &sqlCopyResponse{rows: len(req.rows)}
+	if len(req.rows) == 0 {
+		return res
+	}
+	colVals := make([]*columnValue, len(cols))
+	for idx, col := range cols {
+		colVals[idx] = &columnValue{col: col.name}
+	}
+	records := make([]*record, len(req.rows))
+	res.fromId = len(this.records)
+	for i, values := range req.rows {
+		rec, id := this.prepareRecord()
+		for idx, val := range values {
+			colVals[idx].val = val
+		}
+		this.bindRecord(cols, colVals, rec, id)
+		this.addNewRecord(rec, true)
+		records[i] = rec
+		res.toId = id
+	}
+	for i, rec := range records {
+		this.rangeRecord(rec, res.fromId+i)
+		this.onInsert(rec)
+	}
+	return res
+}
+
 // SELECT sql statement
 
 func (this *table) copyRecordsToSqlSelectResponse(res *sqlSelectResponse, records []*record, columns []*column) {
@@ -522,90 +1063,562 @@ func (this *table) sqlSelect(req *sqlSelectRequest) response {
 	if errResponse != nil {
 		return errResponse
 	}
+	if req.isAggregate() {
+		return this.sqlSelectGroupBy(req, records)
+	}
+	if errres := this.validateColumnLimit(req.tableColumns()); errres != nil {
+		return errres
+	}
 	// precreate columns
 	var columns []*column
 	if len(req.cols) > 0 {
-		columns = make([]*column, 0, cap(req.cols))
-		for _, colName := range req.cols {
+		columns = make([]*column, 0, cap(req.cols)+len(this.colSlice))
+		if req.allColumns {
+			if errres := this.validateAllColumnsNames(req); errres != nil {
+				return errres
+			}
+			columns = append(columns, this.colSlice...)
+		}
+		for idx, colName := range req.cols {
+			if operands := req.concats[idx]; operands != nil {
+				if alias := req.getAlias(idx); alias != "" {
+					colName = alias
+				}
+				columns = append(columns, this.concatColumn(colName, operands))
+				continue
+			}
 			col, _ := this.getAddColumn(colName)
+			if alias := req.getAlias(idx); alias != "" {
+				// aliased column refers to the same record values
+				col = &column{name: alias, ordinal: col.ordinal, dataType: col.dataType}
+			}
 			columns = append(columns, col)
 		}
 	}
+	// total is counted before ordering, which may stop once limit is reached
+	total := 0
+	countTotal := req.limit.use || req.limit.offset > 0 || this.maxRows(req) > 0
+	if countTotal && !req.distinct {
+		total = this.countRecords(records, &req.filter)
+	}
+	if req.orderBy.use {
+		records = this.orderRecords(records, req)
+	}
+	if req.distinct {
+		records = distinctRecords(records, columns)
+		total = len(records)
+	}
+	records = limitRecords(records, &req.limit)
 	//
 	var res sqlSelectResponse
+	records = truncateRecords(&res, records, req, total, this.maxRows(req))
 	this.copyRecordsToSqlSelectResponse(&res, records, columns)
 	return &res
 }
 
-// PEEK
-func (this *table) sqlPeek(req *sqlPeekRequest) response {
-	var rec *record
-	if req.front {
-		rec = this.first
-	} else {
-		rec = this.last
-	}
-	// precreate columns
-	var columns []*column
-	if len(req.cols) > 0 {
-		columns = make([]*column, 0, cap(req.cols))
-		for _, colName := range req.cols {
-			col, _ := this.getAddColumn(colName)
-			columns = append(columns, col)
+// Validates that columns listed after select * do not repeat table columns.
+func (this *table) validateAllColumnsNames(req *sqlSelectRequest) response {
+	for idx, colName := range req.cols {
+		if alias := req.getAlias(idx); alias != "" {
+			colName = alias
+		}
+		if this.getColumn(colName) != nil {
+			return newErrorResponseWithCode(errorCodeInvalidColumn, "column "+colName+" collides with a column selected by *")
 		}
-	} else {
-		columns = this.colSlice
 	}
-	res := newPeekResponse()
-	this.prepareSelectResponse(&res.sqlSelectResponse, &columns, 1)
-	if rec != nil {
-		this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
+	return nil
+}
+
+// Returns computed select column that concatenates operand values.
+// Columns that do not exist contribute empty values and are not created.
+func (this *table) concatColumn(name string, operands []sqlConcatOperand) *column {
+	col := &column{name: name, concat: make([]concatOperand, len(operands))}
+	for idx, operand := range operands {
+		if operand.column {
+			col.concat[idx].col = this.getColumn(operand.val)
+		} else {
+			col.concat[idx].val = operand.val
+		}
 	}
-	return res
+	return col
 }
 
-// UPDATE sql statement
-// Processes sql update requesthis.
-// On success returns sqlUpdateResponse.
-func (this *table) sqlUpdate(req *sqlUpdateRequest) response {
-	records, errResponse := this.getRecordsBySqlFilter(req.filter)
-	if errResponse != nil {
-		return errResponse
+// Returns maximum number of rows select request can return, 0 means no limit.
+// Records selected into another table are not capped.
+func (this *table) maxRows(req *sqlSelectRequest) int {
+	if len(req.into) > 0 {
+		return 0
 	}
-	res := newUpdateResponse()
-	var onlyRecord *record
-	l := len(records)
-	switch l {
-	case 0:
-		return res
-	case 1:
-		onlyRecord = records[0]
+	return config.SELECT_MAX_ROWS
+}
+
+// Returns number of records that matched the filter.
+func (this *table) countRecords(records []*record, filter *sqlFilter) int {
+	if len(filter.col) == 0 {
+		return int(this.count)
 	}
-	// validate duplicate keys
-	cols := make([]*column, len(req.colVals)+1)
-	originalColLen := len(this.colSlice)
-	cols[0] = this.colSlice[0]
-	for idx, colVal := range req.colVals {
-		col, _ := this.getAddColumn(colVal.col)
-		if col.isKey() && col.keyContainsValue(colVal.val) {
-			if onlyRecord == nil || onlyRecord != this.getRecordsByTag(colVal.val, col)[0] {
-				//remove created columns
-				this.removeColumns(originalColLen)
-				return newErrorResponse("update failed due to duplicate column key:" + colVal.col + " value:" + colVal.val)
-			}
+	count := 0
+	for _, rec := range records {
+		if rec != nil {
+			count++
 		}
-		cols[idx+1] = col
 	}
-	// validate returning columns
+	return count
+}
+
+// Caps records at maxRows and reports on the response when records
+// were left out either by limit clause or by the cap, along with number of matched records.
+func truncateRecords(res *sqlSelectResponse, records []*record, req *sqlSelectRequest, total int, maxRows int) []*record {
+	if maxRows > 0 {
+		count := 0
+		for idx, rec := range records {
+			if rec == nil {
+				continue
+			}
+			if count == maxRows {
+				res.truncated = truncatedByCap
+				res.total = total
+				return records[:idx]
+			}
+			count++
+		}
+	}
+	if (req.limit.use || req.limit.offset > 0) && len(records) < total {
+		res.truncated = truncatedByLimit
+		res.total = total
+	}
+	return records
+}
+
+// Orders records by order by columns of the select request.
+// Records with equal values are ordered by the next column and keep their
+// relative order when all values are equal.
+// Unfiltered records are already in id order, so ordering by id walks
+// the records without sorting and stops once limit is reached.
+func (this *table) orderRecords(records []*record, req *sqlSelectRequest) []*record {
+	var keys []orderKey
+	var first *column
+	for orderBy := &req.orderBy; orderBy != nil; orderBy = orderBy.then {
+		// non existing column has no values to order by
+		if col := this.getColumn(orderBy.col); col != nil {
+			if first == nil {
+				first = col
+			}
+			keys = append(keys, orderKey{ordinal: col.ordinal, desc: orderBy.desc})
+		}
+	}
+	if len(keys) == 0 {
+		return records
+	}
+	if first.typ == columnTypeId && len(req.filter.col) == 0 && this.uuids == nil {
+		// ids are unique, following columns do not change the order,
+		// records are kept in id order unless ids are random uuids
+		if !keys[0].desc {
+			return records
+		}
+		count := -1
+		if req.limit.use && !req.distinct {
+			count = req.limit.offset + req.limit.count
+		}
+		return reverseRecords(records, count)
+	}
+	ordered := make([]*record, 0, len(records))
+	for _, rec := range records {
+		if rec != nil {
+			ordered = append(ordered, rec)
+		}
+	}
+	sort.Stable(&recordsByValues{records: ordered, keys: keys})
+	return ordered
+}
+
+// Returns at most count records in reverse order skipping deleted records,
+// negative count returns all records.
+func reverseRecords(records []*record, count int) []*record {
+	capacity := len(records)
+	if count >= 0 && count < capacity {
+		capacity = count
+	}
+	reversed := make([]*record, 0, capacity)
+	for idx := len(records) - 1; idx >= 0 && len(reversed) != count; idx-- {
+		if records[idx] != nil {
+			reversed = append(reversed, records[idx])
+		}
+	}
+	return reversed
+}
+
+// recordsByValue sorts records by column values.
+type recordsByValue struct {
+	records []*record
+	ordinal int
+	desc    bool
+}
+
+func (this *recordsByValue) Len() int {
+	return len(this.records)
+}
+
+func (this *recordsByValue) Swap(i, j int) {
+	this.records[i], this.records[j] = this.records[j], this.records[i]
+}
+
+func (this *recordsByValue) Less(i, j int) bool {
+	c := compareValues(this.records[i].getValue(this.ordinal), this.records[j].getValue(this.ordinal))
+	if this.desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// orderKey is a column ordinal and direction records are ordered by.
+type orderKey struct {
+	ordinal int
+	desc    bool
+}
+
+// recordsByValues sorts records by values of several columns,
+// records with equal values are compared by the next column.
+type recordsByValues struct {
+	records []*record
+	keys    []orderKey
+}
+
+func (this *recordsByValues) Len() int {
+	return len(this.records)
+}
+
+func (this *recordsByValues) Swap(i, j int) {
+	this.records[i], this.records[j] = this.records[j], this.records[i]
+}
+
+func (this *recordsByValues) Less(i, j int) bool {
+	for _, key := range this.keys {
+		c := compareValues(this.records[i].getValue(key.ordinal), this.records[j].getValue(key.ordinal))
+		if c == 0 {
+			continue
+		}
+		if key.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// Returns records with unique values across all passed columns
+// preserving the order of the first occurrence.
+func distinctRecords(records []*record, columns []*column) []*record {
+	unique := make([]*record, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	var key []byte
+	for _, rec := range records {
+		if rec == nil {
+			continue
+		}
+		key = appendRecordValuesKey(key[:0], rec, columns)
+		if !seen[string(key)] {
+			seen[string(key)] = true
+			unique = append(unique, rec)
+		}
+	}
+	return unique
+}
+
+// Processes select into request by forwarding selected records to the
+// destination table, which sends back the response.
+func (this *table) sqlSelectInto(req *sqlSelectRequest, sender *responseSender) {
+	selected := this.sqlSelect(req)
+	res, ok := selected.(*sqlSelectResponse)
+	if !ok {
+		this.send(sender, selected)
+		return
+	}
+	if this.dataSrv == nil {
+		this.send(sender, newErrorResponse("select into is not supported"))
+		return
+	}
+	into := newSqlSelectIntoRequest(req.into, res)
+	into.streaming = req.isStreaming()
+	item := &requestItem{
+		header: newNetHeader(0, this.requestId),
+		req:    into,
+		sender: sender,
+	}
+	// the data service may itself be blocked forwarding to this table,
+	// hand off the copy so the table event loop never waits on it
+	go this.dataSrv.acceptRequest(item)
+}
+
+// Inserts records copied by select into statement.
+// Columns are created even if there are no records to copy.
+// Copy stops at the first record that violates unique key constraint.
+func (this *table) sqlSelectIntoCopy(req *sqlSelectIntoRequest) response {
+	if errres := this.validateColumnLimit(req.cols); errres != nil {
+		return errres
+	}
+	for _, col := range req.cols {
+		this.getAddColumn(col)
+	}
+	res := &sqlActionDataResponse{action: "into"}
+	for _, values := range req.values {
+		var insert sqlInsertRequest
+		insert.colVals = make([]*columnValue, len(req.cols))
+		for idx, col := range req.cols {
+			insert.colVals[idx] = &columnValue{col: col, val: values[idx]}
+		}
+		if e, ok := this.sqlInsert(&insert).(*errorResponse); ok {
+			return e
+		}
+		res.rows++
+	}
+	return res
+}
+
+// Appends record values of passed columns to the key.
+// Length prefixed values make the key unambiguous.
+func appendRecordValuesKey(key []byte, rec *record, columns []*column) []byte {
+	for _, col := range columns {
+		val := col.valueOf(rec)
+		key = strconv.AppendInt(key, int64(len(val)), 10)
+		key = append(key, ':')
+		key = append(key, val...)
+	}
+	return key
+}
+
+// Groups records by group by columns and counts records in every group.
+// Returns one row per group in order of the first occurrence,
+// records with empty group by value form their own group.
+func (this *table) sqlSelectGroupBy(req *sqlSelectRequest, records []*record) response {
+	if errres := this.validateColumnLimit(req.groupBy); errres != nil {
+		return errres
+	}
+	groupColumns := make([]*column, 0, len(req.groupBy))
+	for _, colName := range req.groupBy {
+		col, _ := this.getAddColumn(colName)
+		groupColumns = append(groupColumns, col)
+	}
+	// result columns refer to values of group records
+	columns := make([]*column, len(req.cols))
+	sources := make([]*column, len(req.cols))
+	for idx, colName := range req.cols {
+		columns[idx] = &column{name: colName, ordinal: idx}
+		if colName == sqlCountStar {
+			columns[idx].name = "count"
+		} else {
+			sources[idx] = this.getColumn(colName)
+		}
+		if alias := req.getAlias(idx); alias != "" {
+			columns[idx].name = alias
+		}
+	}
+	groups := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	counts := make([]int, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+	groupIndex := make(map[string]int)
+	var key []byte
+	for _, rec := range records {
+		if rec == nil {
+			continue
+		}
+		key = appendRecordValuesKey(key[:0], rec, groupColumns)
+		if idx, contains := groupIndex[string(key)]; contains {
+			counts[idx]++
+			continue
+		}
+		group := &record{
+			values: make([]string, len(columns)),
+		}
+		for idx, col := range sources {
+			if col != nil {
+				group.values[idx] = rec.getValue(col.ordinal)
+			}
+		}
+		groupIndex[string(key)] = len(groups)
+		groups = append(groups, group)
+		counts = append(counts, 1)
+	}
+	// count without group by always returns a row
+	if len(req.groupBy) == 0 && len(groups) == 0 {
+		groups = append(groups, &record{values: make([]string, len(columns))})
+		counts = append(counts, 0)
+	}
+	for idx, group := range groups {
+		for ordinal, source := range sources {
+			if source == nil {
+				group.values[ordinal] = strconv.Itoa(counts[idx])
+			}
+		}
+	}
+	total := len(groups)
+	groups = limitRecords(groups, &req.limit)
+	var res sqlSelectResponse
+	groups = truncateRecords(&res, groups, req, total, this.maxRows(req))
+	this.copyRecordsToSqlSelectResponse(&res, groups, columns)
+	return &res
+}
+
+// Skips offset records and returns at most limit records.
+// Deleted records are not counted.
+func limitRecords(records []*record, limit *sqlLimit) []*record {
+	if !limit.use && limit.offset == 0 {
+		return records
+	}
+	capacity := len(records)
+	if limit.use && limit.count < capacity {
+		capacity = limit.count
+	}
+	limited := make([]*record, 0, capacity)
+	skip := limit.offset
+	for _, rec := range records {
+		if limit.use && len(limited) == limit.count {
+			break
+		}
+		if rec == nil {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		limited = append(limited, rec)
+	}
+	return limited
+}
+
+// PEEK
+func (this *table) sqlPeek(req *sqlPeekRequest) response {
+	var rec *record
+	if req.front {
+		rec = this.first
+	} else {
+		rec = this.last
+	}
+	if errres := this.validateColumnLimit(req.cols); errres != nil {
+		return errres
+	}
+	// precreate columns
+	var columns []*column
+	if len(req.cols) > 0 {
+		columns = make([]*column, 0, cap(req.cols))
+		for _, colName := range req.cols {
+			col, _ := this.getAddColumn(colName)
+			columns = append(columns, col)
+		}
+	} else {
+		columns = this.colSlice
+	}
+	res := newPeekResponse()
+	this.prepareSelectResponse(&res.sqlSelectResponse, &columns, 1)
+	if rec != nil {
+		this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
+	}
+	return res
+}
+
+// UPDATE sql statement
+// Processes sql update requesthis.
+// On success returns sqlUpdateResponse.
+func (this *table) sqlUpdate(req *sqlUpdateRequest) response {
+	records, errResponse := this.getRecordsBySqlFilter(req.filter)
+	if errResponse != nil {
+		return errResponse
+	}
+	if req.useVersion {
+		records = versionRecords(records, req.version)
+	}
+	return this.updateRecords(records, req)
+}
+
+// Returns records that have not changed since the expected version.
+func versionRecords(records []*record, version int) []*record {
+	matched := make([]*record, 0, len(records))
+	for _, rec := range records {
+		if rec != nil && rec.version == version {
+			matched = append(matched, rec)
+		}
+	}
+	return matched
+}
+
+// Updates records with column values of the update request.
+func (this *table) updateRecords(records []*record, req *sqlUpdateRequest) response {
+	res := newUpdateResponse()
+	var onlyRecord *record
+	l := len(records)
+	switch l {
+	case 0:
+		return res
+	case 1:
+		onlyRecord = records[0]
+	}
+	if errres := this.validateColumnLimit(columnValueNames(req.colVals)); errres != nil {
+		return errres
+	}
+	// validate duplicate keys
+	cols := make([]*column, len(req.colVals)+1)
+	originalColLen := len(this.colSlice)
+	cols[0] = this.colSlice[0]
+	for idx, colVal := range req.colVals {
+		col, _ := this.getAddColumn(colVal.col)
+		if req.increments[idx] != nil {
+			if col.isKey() {
+				this.removeColumns(originalColLen)
+				return newErrorResponseWithCode(errorCodeInvalidValue, "update failed, key column:"+colVal.col+" can not be incremented")
+			}
+			// incremented values are validated per record
+			cols[idx+1] = col
+			continue
+		}
+		if col.isKey() && col.keyContainsValue(colVal.val) {
+			if onlyRecord == nil || onlyRecord != this.getRecordsByTag(colVal.val, col)[0] {
+				//remove created columns
+				this.removeColumns(originalColLen)
+				return newErrorResponseWithCode(errorCodeDuplicateKey, "update failed due to duplicate column key:"+colVal.col+" value:"+colVal.val)
+			}
+		}
+		if !col.isValidValue(colVal.val) {
+			this.removeColumns(originalColLen)
+			return newErrorResponseWithCode(errorCodeInvalidValue, "update failed due to invalid "+col.dataType.String()+" column:"+colVal.col+" value:"+colVal.val)
+		}
+		cols[idx+1] = col
+	}
+	// validate returning columns
 	errres, retCols := this.setReturningColumns(&(req.returningColumns))
 	if errres != nil {
 		return errres
 	}
+	// compute incremented values before any record is updated
+	var values [][]*columnValue
+	if len(req.increments) > 0 {
+		values = make([][]*columnValue, l)
+		for idx, rec := range records {
+			if rec == nil {
+				continue
+			}
+			var errres response
+			values[idx], errres = this.incrementValues(cols[1:], req, rec)
+			if errres != nil {
+				this.removeColumns(originalColLen)
+				return errres
+			}
+		}
+	}
 	// all is valid ready to update
 	this.prepareSelectResponse(&res.sqlSelectResponse, retCols, l)
-	for _, rec := range records {
+	for idx, rec := range records {
 		if rec != nil {
-			ra := this.updateRecord(cols[1:], req.colVals, rec, int(rec.id()))
+			colVals := req.colVals
+			if values != nil {
+				colVals = values[idx]
+			}
+			if this.tx != nil {
+				this.logUndoUpdate(cols[1:], rec)
+			}
+			matched := this.matchFilteredSubscriptions(rec)
+			before := this.eventValues(rec)
+			ra := this.updateRecord(cols[1:], colVals, rec, int(rec.id()))
+			this.logEvent(eventActionUpdate, cols, before, this.eventValues(rec))
 			if hasWhatToRemove(ra) {
 				this.onRemove(ra.removed, rec)
 			}
@@ -614,10 +1627,84 @@ func (this *table) sqlUpdate(req *sqlUpdateRequest) response {
 				added = &ra.added
 				this.onAdd(ra.added, rec)
 			}
+			rec.version++
 			this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
-			this.onUpdate(cols, rec, added)
+			this.onUpdate(cols, rec, added, matched)
 		}
 	}
+	if onlyRecord != nil {
+		res.version = onlyRecord.version
+		res.useVersion = true
+	}
+	return res
+}
+
+// Returns column values of the update request with incremented values
+// computed from the record.
+func (this *table) incrementValues(cols []*column, req *sqlUpdateRequest, rec *record) ([]*columnValue, response) {
+	colVals := make([]*columnValue, len(req.colVals))
+	for idx, colVal := range req.colVals {
+		colVals[idx] = colVal
+		inc := req.increments[idx]
+		if inc == nil {
+			continue
+		}
+		source := this.getColumn(inc.source)
+		if source == nil {
+			return nil, newErrorResponseWithCode(errorCodeInvalidColumn, "update failed, column:"+inc.source+" does not exist")
+		}
+		current := rec.getValue(source.ordinal)
+		val, ok := inc.apply(current)
+		if !ok {
+			return nil, newErrorResponseWithCode(errorCodeInvalidValue, "update failed due to non numeric column:"+inc.source+" value:"+current)
+		}
+		if !cols[idx].isValidValue(val) {
+			return nil, newErrorResponseWithCode(errorCodeInvalidValue, "update failed due to invalid "+cols[idx].dataType.String()+" column:"+colVal.col+" value:"+val)
+		}
+		colVals[idx] = &columnValue{col: colVal.col, val: val}
+	}
+	return colVals, nil
+}
+
+// UPSERT sql statement
+
+// Processes sql upsert request.
+// Updates the record that matches key column values of the request,
+// inserts new record when no record matches.
+// Returns sqlActionDataResponse with insert or update action.
+func (this *table) sqlUpsert(req *sqlUpsertRequest) response {
+	var found *record
+	hasKey := false
+	for _, colVal := range req.colVals {
+		col := this.getColumn(colVal.col)
+		if col == nil || !col.isKey() {
+			continue
+		}
+		hasKey = true
+		records := this.getRecordsByTag(colVal.val, col)
+		if len(records) == 0 {
+			continue
+		}
+		if found != nil && found != records[0] {
+			return newErrorResponse("upsert failed, key values match more than one record")
+		}
+		found = records[0]
+	}
+	if !hasKey {
+		return newErrorResponse("upsert failed, expected at least one key column")
+	}
+	if found == nil {
+		return this.sqlInsert(&req.sqlInsertRequest)
+	}
+	update := &sqlUpdateRequest{
+		returningColumns: req.returningColumns,
+		colVals:          req.colVals,
+	}
+	res := this.updateRecords([]*record{found}, update)
+	// ttl of upserted record starts again
+	if _, failed := res.(*errorResponse); req.ttl > 0 && !failed {
+		this.expireAfter(found, req.ttl, time.Now())
+	}
 	return res
 }
 
@@ -640,10 +1727,204 @@ func (this *table) sqlDelete(req *sqlDeleteRequest) response {
 	for _, rec := range records {
 		if rec != nil {
 			this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
+			this.onDelete(rec)
+			if this.tx != nil {
+				this.logUndoDelete(rec)
+			}
+			this.deleteRecord(rec)
+			// record deleted by transaction is freed once the transaction commits
+			if this.tx == nil {
+				rec.free()
+			}
+		}
+	}
+	return res
+}
+
+// EXPIRY
+
+// Sets record to expire ttl seconds from now.
+func (this *table) expireAfter(rec *record, ttl int, now time.Time) {
+	rec.expires = now.Add(time.Duration(ttl) * time.Second).UnixNano()
+	if this.nextExpiry == 0 || rec.expires < this.nextExpiry {
+		this.nextExpiry = rec.expires
+	}
+}
+
+// Deletes records that expired by now and publishes delete to subscribers.
+// Returns number of expired records.
+func (this *table) expireRecords(now time.Time) int {
+	deadline := now.UnixNano()
+	if this.nextExpiry == 0 || deadline < this.nextExpiry {
+		return 0
+	}
+	this.nextExpiry = 0
+	expired := 0
+	for rec := this.first; rec != nil; {
+		next := rec.next
+		if rec.expires != 0 && rec.expires <= deadline {
 			this.onDelete(rec)
 			this.deleteRecord(rec)
 			rec.free()
+			expired++
+		} else if rec.expires != 0 && (this.nextExpiry == 0 || rec.expires < this.nextExpiry) {
+			this.nextExpiry = rec.expires
 		}
+		rec = next
+	}
+	return expired
+}
+
+// TRANSACTION
+
+// publication is a response held back until the transaction commits.
+type publication struct {
+	sub *subscription
+	res response
+}
+
+// tableTransaction is an undo log and held back publications of a transaction in progress.
+type tableTransaction struct {
+	undo      []func()
+	published []publication
+	deleted   []*record
+	seq       uint64 // sequence number of the last change before the transaction
+}
+
+func (this *tableTransaction) logUndo(undo func()) {
+	this.undo = append(this.undo, undo)
+}
+
+// Processes sql transaction request.
+// Statements are applied in order, when any of them fails changes made by
+// previous statements are undone and subscribers see nothing.
+// Subscribers see changes of committed transaction as a single batch.
+func (this *table) sqlTransaction(req *sqlTransactionRequest) response {
+	tx := &tableTransaction{seq: this.seq}
+	this.tx = tx
+	for _, stmt := range req.requests {
+		if errres, ok := this.sqlTransactionStatement(stmt).(*errorResponse); ok {
+			this.tx = nil
+			for idx := len(tx.undo) - 1; idx >= 0; idx-- {
+				tx.undo[idx]()
+			}
+			// changes never happened for subscribers
+			this.seq = tx.seq
+			this.events.discardAfter(tx.seq)
+			return newErrorResponseWithCode(errres.code, "transaction rolled back: "+errres.msg)
+		}
+	}
+	this.tx = nil
+	for _, rec := range tx.deleted {
+		rec.free()
+	}
+	for _, pub := range tx.published {
+		this.publish(pub.sub, pub.res)
+	}
+	return newOkResponse("commit")
+}
+
+func (this *table) sqlTransactionStatement(req request) response {
+	switch req.(type) {
+	case *sqlInsertRequest:
+		return this.sqlInsert(req.(*sqlInsertRequest))
+	case *sqlUpsertRequest:
+		return this.sqlUpsert(req.(*sqlUpsertRequest))
+	case *sqlUpdateRequest:
+		return this.sqlUpdate(req.(*sqlUpdateRequest))
+	case *sqlDeleteRequest:
+		return this.sqlDelete(req.(*sqlDeleteRequest))
+	}
+	return newErrorResponse("statement is not supported in a transaction")
+}
+
+// Removes record inserted by the transaction so that its id is reused.
+func (this *table) removeNewRecord(rec *record) {
+	id := rec.id()
+	this.deleteRecord(rec)
+	if id == len(this.records)-1 {
+		this.records = this.records[:id]
+	}
+}
+
+// Logs restoring of record values that are about to be updated.
+func (this *table) logUndoUpdate(cols []*column, rec *record) {
+	values := make([]*columnValue, len(cols))
+	for idx, col := range cols {
+		values[idx] = &columnValue{col: col.name, val: rec.getValue(col.ordinal)}
+	}
+	version := rec.version
+	this.tx.logUndo(func() {
+		this.updateRecord(cols, values, rec, rec.id())
+		rec.version = version
+	})
+}
+
+// Logs restoring of record that is about to be deleted.
+func (this *table) logUndoDelete(rec *record) {
+	var tagged []*column
+	for _, col := range this.tagedColumns {
+		if col.tagIndex < len(rec.links) && rec.links[col.tagIndex].tg != nil {
+			tagged = append(tagged, col)
+		}
+	}
+	this.tx.deleted = append(this.tx.deleted, rec)
+	this.tx.logUndo(func() { this.restoreRecord(rec, tagged) })
+}
+
+// Puts deleted record back to the table with its tags and range values.
+func (this *table) restoreRecord(rec *record, tagged []*column) {
+	id := rec.id()
+	this.records[id] = rec
+	this.count++
+	if this.uuids != nil {
+		this.uuids[rec.idAsString()] = rec
+	}
+	if rec.prev != nil {
+		rec.prev.next = rec
+	} else {
+		this.first = rec
+	}
+	if rec.next != nil {
+		rec.next.prev = rec
+	} else {
+		this.last = rec
+	}
+	for _, col := range tagged {
+		this.tagValue(col, id, rec)
+	}
+	this.rangeRecord(rec, id)
+}
+
+// TRUNCATE sql statement
+
+// Processes sql truncate request by removing all records and resetting indexes.
+// Columns and subscriptions are preserved, record ids start from 0 again.
+// On success returns sqlActionDataResponse with number of removed records.
+func (this *table) sqlTruncate(req *sqlTruncateRequest) response {
+	res := newTruncateResponse()
+	res.rows = int(this.count)
+	if this.count > 0 {
+		this.onTruncate()
+	}
+	for _, rec := range this.records {
+		if rec != nil {
+			rec.free()
+		}
+	}
+	this.records = make([]*record, 0, config.TABLE_RECORDS_CAPACITY)
+	this.first = nil
+	this.last = nil
+	this.count = 0
+	if this.uuids != nil {
+		this.uuids = make(map[string]*record)
+	}
+	this.nextExpiry = 0
+	for _, col := range this.tagedColumns {
+		col.tagmap.removeTags()
+	}
+	for _, col := range this.rangeColumns {
+		col.ranges.clear()
 	}
 	return res
 }
@@ -656,126 +1937,470 @@ func (this *table) sqlPop(req *sqlPopRequest) response {
 	} else {
 		rec = this.last
 	}
-	// validate returning columns
-	errres, retCols := this.setReturningColumns(&(req.returningColumns))
-	if errres != nil {
-		return errres
+	// validate returning columns
+	errres, retCols := this.setReturningColumns(&(req.returningColumns))
+	if errres != nil {
+		return errres
+	}
+	res := newPopResponse()
+	if rec != nil {
+		this.prepareSelectResponse(&res.sqlSelectResponse, retCols, 1)
+		this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
+		this.onDelete(rec)
+		this.deleteRecord(rec)
+		rec.free()
+	}
+	return res
+}
+
+// Key sql statement
+
+// Processes sql key requesthis.
+// On success returns sqlOkResponse.
+func (this *table) sqlKey(req *sqlKeyRequest) response {
+	// key is already defined for this column
+	col := this.getColumn(req.column)
+	if col != nil && col.isIndexed() {
+		return newErrorResponse("key or tag already defined for column:" + req.column)
+	}
+	if errres := this.validateColumnLimit([]string{req.column}); errres != nil {
+		return errres
+	}
+	// new column on existing records
+	if col == nil && len(this.records) > 0 {
+		return newErrorResponse("can not define key for non existant column due to possible duplicates")
+	}
+	// new column no records
+	if col != nil {
+		unique := make(map[string]int, cap(this.records))
+		// check if there are duplicates
+		for idx, rec := range this.records {
+			if rec != nil {
+				val := rec.getValue(col.ordinal)
+				if _, contains := unique[val]; contains {
+					return newErrorResponse("can not define key due to possible duplicates in existing records")
+				}
+				unique[val] = idx
+			}
+		}
+	}
+	//
+	this.tagOrKeyColumn(req.column, columnTypeKey)
+	return newOkResponse("key")
+}
+
+// TAG sql statement
+
+func (this *table) tagOrKeyColumn(c string, coltyp columnType) {
+	col, _ := this.getAddColumn(c)
+	this.tagedColumns = append(this.tagedColumns, col)
+	col.makeTags(len(this.tagedColumns))
+	col.typ = coltyp
+	// tag existing values
+	for idx, rec := range this.records {
+		if rec != nil {
+			this.tagValue(col, idx, rec)
+		}
+	}
+}
+
+// Processes sql tag requesthis.
+// On success returns sqlOkResponse.
+func (this *table) sqlTag(req *sqlTagRequest) response {
+	// tag is already defined for this column
+	col := this.getColumn(req.column)
+	if col != nil && col.isIndexed() {
+		return newErrorResponse("key or tag already defined for column:" + req.column)
+	}
+	if errres := this.validateColumnLimit([]string{req.column}); errres != nil {
+		return errres
+	}
+	col, _ = this.getAddColumn(req.column)
+	col.multiValue = req.multiValue
+	this.tagOrKeyColumn(req.column, columnTypeTag)
+	return newOkResponse("tag")
+}
+
+// RANGE sql statement
+
+// Processes sql create table request by defining columns and their data types.
+// Table must not have any columns besides id, unless if not exists was specified
+// in which case existing table is left untouched.
+// On success returns sqlOkResponse.
+func (this *table) sqlCreate(req *sqlCreateRequest) response {
+	if len(this.colSlice) > 1 {
+		if req.ifNotExists {
+			return newOkResponse("create")
+		}
+		return newErrorResponseWithCode(errorCodeTableExists, "table "+this.name+" already exists")
+	}
+	defined := make(map[string]bool, len(req.columns))
+	for _, def := range req.columns {
+		if defined[def.name] {
+			return newErrorResponse("duplicate column " + def.name)
+		}
+		defined[def.name] = true
+		if def.name == "id" && def.dataType != columnDataTypeString && def.dataType != columnDataTypeInt {
+			return newErrorResponse("id column must be of int type")
+		}
+		if def.name == "id" && def.dataType == columnDataTypeInt && req.idFormat == idFormatUuid {
+			return newErrorResponse("id column of table with uuid ids must be of string type")
+		}
+	}
+	if req.idFormat == idFormatUuid && len(this.records) > 0 {
+		return newErrorResponse("id format of table " + this.name + " with records can not be changed")
+	}
+	names := make([]string, len(req.columns))
+	for idx, def := range req.columns {
+		names[idx] = def.name
+	}
+	if errres := this.validateColumnLimit(names); errres != nil {
+		return errres
+	}
+	for _, def := range req.columns {
+		if def.name == "id" {
+			continue
+		}
+		col, _ := this.getAddColumn(def.name)
+		col.dataType = def.dataType
+		col.declared = true
+	}
+	if req.idFormat == idFormatUuid {
+		this.uuids = make(map[string]*record)
+	}
+	return newOkResponse("create")
+}
+
+// SAVE and LOAD sql statements
+
+// Processes sql save request by writing table snapshot to the file.
+// On success returns sqlActionDataResponse with number of saved records.
+func (this *table) sqlSave(req *sqlSaveRequest) response {
+	path, err := snapshotPath(req.file)
+	if err != nil {
+		return newErrorResponse(err.Error())
+	}
+	rows, err := this.saveSnapshot(path)
+	if err != nil {
+		return newErrorResponse("save failed: " + err.Error())
+	}
+	res := newSaveResponse()
+	res.rows = rows
+	return res
+}
+
+// Processes sql load request by reading table snapshot from the file.
+// Columns, data types and indexes of the snapshot are added to the table,
+// records keep their ids and order.
+// On success returns sqlActionDataResponse with number of loaded records.
+func (this *table) sqlLoad(req *sqlLoadRequest) response {
+	path, err := snapshotPath(req.file)
+	if err != nil {
+		return newErrorResponse(err.Error())
+	}
+	snap, err := readSnapshotFile(path)
+	if err != nil {
+		return newErrorResponse("load failed: " + err.Error())
+	}
+	if this.count > 0 && !req.replace {
+		return newErrorResponseWithCode(errorCodeTableNotEmpty, "table "+this.name+" is not empty, use load with replace")
+	}
+	if errres := this.validateSnapshot(snap); errres != nil {
+		return errres
+	}
+	if this.count > 0 {
+		this.sqlTruncate(nil)
+	}
+	// columns
+	ordinals := make([]int, len(snap.columns))
+	for idx, def := range snap.columns[1:] {
+		col, _ := this.getAddColumn(def.name)
+		ordinals[idx+1] = col.ordinal
+		if def.declared && !col.declared {
+			col.dataType = def.dataType
+			col.declared = true
+		}
+		if (def.typ == columnTypeKey || def.typ == columnTypeTag) && !col.isIndexed() {
+			col.multiValue = def.multiValue
+			this.tagOrKeyColumn(def.name, def.typ)
+		}
+		if def.ranged && col.ranges == nil {
+			col.ranges = newRangeIndex()
+			this.rangeColumns = append(this.rangeColumns, col)
+		}
+	}
+	// records, uuid tables keep saved ids and place records in snapshot order
+	maxId := len(snap.records) - 1
+	if this.uuids == nil {
+		maxId = -1
+		for _, values := range snap.records {
+			id, _ := strconv.Atoi(values[0])
+			if id > maxId {
+				maxId = id
+			}
+		}
+	}
+	this.records = make([]*record, maxId+1, maxId+1+config.TABLE_RECORDS_CAPACITY)
+	loaded := make([]*record, len(snap.records))
+	for i, values := range snap.records {
+		id := i
+		if this.uuids == nil {
+			id, _ = strconv.Atoi(values[0])
+		}
+		rec := newRecord(len(this.colSlice), id)
+		if this.uuids != nil {
+			rec.setValue(0, values[0])
+		}
+		rec.links = make([]link, len(this.tagedColumns)+1)
+		for idx, val := range values[1:] {
+			rec.setValue(ordinals[idx+1], val)
+		}
+		rec.prev = this.last
+		this.restoreRecord(rec, this.tagedColumns)
+		loaded[i] = rec
 	}
-	res := newPopResponse()
-	if rec != nil {
-		this.prepareSelectResponse(&res.sqlSelectResponse, retCols, 1)
-		this.addRecordToSelectResponse(&res.sqlSelectResponse, rec)
-		this.onDelete(rec)
-		this.deleteRecord(rec)
-		rec.free()
+	for _, rec := range loaded {
+		this.onInsert(rec)
 	}
+	res := newLoadResponse()
+	res.rows = len(loaded)
 	return res
 }
 
-// Key sql statement
-
-// Processes sql key requesthis.
-// On success returns sqlOkResponse.
-func (this *table) sqlKey(req *sqlKeyRequest) response {
-	// key is already defined for this column
-	col := this.getColumn(req.column)
-	if col != nil && col.isIndexed() {
-		return newErrorResponse("key or tag already defined for column:" + req.column)
+// Validates that snapshot records can be loaded into the table.
+// Record ids must be unique, values must match data types and keys must be unique.
+func (this *table) validateSnapshot(snap *snapshot) response {
+	if len(snap.columns) == 0 || snap.columns[0].name != "id" {
+		return newErrorResponse("load failed: snapshot does not have id column")
 	}
-	// new column on existing records
-	if col == nil && len(this.records) > 0 {
-		return newErrorResponse("can not define key for non existant column due to possible duplicates")
+	names := make([]string, len(snap.columns))
+	for idx, def := range snap.columns {
+		names[idx] = def.name
 	}
-	// new column no records
-	if col != nil {
-		unique := make(map[string]int, cap(this.records))
-		// check if there are duplicates
-		for idx, rec := range this.records {
-			if rec != nil {
-				val := rec.getValue(col.ordinal)
-				if _, contains := unique[val]; contains {
-					return newErrorResponse("can not define key due to possible duplicates in existing records")
-				}
-				unique[val] = idx
+	if errres := this.validateColumnLimit(names); errres != nil {
+		return errres
+	}
+	for idx, def := range snap.columns[1:] {
+		check := &column{name: def.name, dataType: columnDataTypeString}
+		if def.declared {
+			check.dataType = def.dataType
+		}
+		key := def.typ == columnTypeKey
+		if col := this.getColumn(def.name); col != nil {
+			if col.declared {
+				check.dataType = col.dataType
+			}
+			// existing index is kept
+			key = col.isKey() || key && !col.isIndexed()
+		}
+		unique := make(map[string]bool)
+		for _, values := range snap.records {
+			val := values[idx+1]
+			if !check.isValidValue(val) {
+				return newErrorResponseWithCode(errorCodeInvalidValue, "load failed due to invalid "+check.dataType.String()+" column:"+def.name+" value:"+val)
+			}
+			if key && unique[val] {
+				return newErrorResponseWithCode(errorCodeDuplicateKey, "load failed due to duplicate column key:"+def.name+" value:"+val)
 			}
+			unique[val] = true
 		}
 	}
-	//
-	this.tagOrKeyColumn(req.column, columnTypeKey)
-	return newOkResponse("key")
+	if this.uuids != nil {
+		// table with uuid ids keeps any unique id
+		uuids := make(map[string]bool, len(snap.records))
+		for _, values := range snap.records {
+			if values[0] == "" || uuids[values[0]] {
+				return newErrorResponse("load failed due to invalid record id:" + values[0])
+			}
+			uuids[values[0]] = true
+		}
+		return nil
+	}
+	ids := make(map[int]bool, len(snap.records))
+	maxId := len(snap.records)*snapshotMaxIdFactor + config.TABLE_RECORDS_CAPACITY
+	for _, values := range snap.records {
+		id, err := strconv.Atoi(values[0])
+		if err != nil || id < 0 || ids[id] {
+			return newErrorResponse("load failed due to invalid record id:" + values[0])
+		}
+		if id > maxId {
+			return newErrorResponse("load failed: corrupted table snapshot: record id " + values[0] + " is out of range")
+		}
+		ids[id] = true
+	}
+	return nil
 }
 
-// TAG sql statement
+// DESCRIBE sql statement
+
+// Number of records sampled to infer data type of undeclared column.
+const describeSampleSize = 100
+
+// Processes sql describe request.
+// Returns sqlDescribeResponse with column name, data type, whether the type
+// was declared and index of every column.
+func (this *table) sqlDescribe(req *sqlDescribeRequest) response {
+	res := new(sqlDescribeResponse)
+	res.columns = []*column{
+		{name: "column", ordinal: 0},
+		{name: "type", ordinal: 1},
+		{name: "declared", ordinal: 2},
+		{name: "index", ordinal: 3},
+	}
+	res.records = make([]*record, 0, len(this.colSlice))
+	for _, col := range this.colSlice {
+		dataType := col.dataType
+		declared := col.declared || col.typ == columnTypeId
+		if col.typ == columnTypeId {
+			dataType = columnDataTypeInt
+		} else if !declared {
+			dataType = this.inferDataType(col)
+		}
+		rec := &record{
+			values: []string{col.name, dataType.String(), strconv.FormatBool(declared), describeIndex(col)},
+		}
+		res.records = append(res.records, rec)
+	}
+	return res
+}
 
-func (this *table) tagOrKeyColumn(c string, coltyp columnType) {
-	col, _ := this.getAddColumn(c)
-	this.tagedColumns = append(this.tagedColumns, col)
-	col.makeTags(len(this.tagedColumns))
-	col.typ = coltyp
-	// tag existing values
-	for idx, rec := range this.records {
-		if rec != nil {
-			this.tagValue(col, idx, rec)
+// Infers data type of column from sampled values.
+// The narrowest type that fits every non empty value is returned, string when there are no values.
+func (this *table) inferDataType(col *column) columnDataType {
+	isInt, isFloat, isBool := true, true, true
+	sampled := 0
+	for _, rec := range this.records {
+		if sampled == describeSampleSize {
+			break
+		}
+		if rec == nil {
+			continue
+		}
+		val := rec.getValue(col.ordinal)
+		if val == "" {
+			continue
 		}
+		sampled++
+		if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+			isInt = false
+		}
+		if _, err := strconv.ParseFloat(val, 64); err != nil {
+			isFloat = false
+		}
+		if _, err := strconv.ParseBool(val); err != nil {
+			isBool = false
+		}
+	}
+	switch {
+	case sampled == 0:
+		return columnDataTypeString
+	case isInt:
+		return columnDataTypeInt
+	case isFloat:
+		return columnDataTypeFloat
+	case isBool:
+		return columnDataTypeBool
 	}
+	return columnDataTypeString
 }
 
-// Processes sql tag requesthis.
+// Returns comma separated indexes defined on the column, empty when there are none.
+func describeIndex(col *column) string {
+	index := ""
+	switch col.typ {
+	case columnTypeId:
+		index = "id"
+	case columnTypeKey:
+		index = "key"
+	case columnTypeTag:
+		index = "tag"
+	}
+	if col.ranges != nil {
+		if index != "" {
+			index += ","
+		}
+		index += "range"
+	}
+	return index
+}
+
+// Processes sql range request by defining sorted index on the column.
 // On success returns sqlOkResponse.
-func (this *table) sqlTag(req *sqlTagRequest) response {
-	// tag is already defined for this column
-	col := this.getColumn(req.column)
-	if col != nil && col.isIndexed() {
-		return newErrorResponse("key or tag already defined for column:" + req.column)
+func (this *table) sqlRange(req *sqlRangeRequest) response {
+	if errres := this.validateColumnLimit([]string{req.column}); errres != nil {
+		return errres
 	}
-	//
-	this.tagOrKeyColumn(req.column, columnTypeTag)
-	return newOkResponse("tag")
+	col, _ := this.getAddColumn(req.column)
+	if col.typ == columnTypeId {
+		return newErrorResponse("can not define range for id column")
+	}
+	if col.ranges != nil {
+		return newErrorResponse("range already defined for column:" + req.column)
+	}
+	col.ranges = newRangeIndex()
+	this.rangeColumns = append(this.rangeColumns, col)
+	// index existing values
+	for id, rec := range this.records {
+		if rec != nil {
+			col.ranges.add(rec.getValue(col.ordinal), id, rec)
+		}
+	}
+	return newOkResponse("range")
 }
 
 // SUBSCRIBE sql statement
 
-func (this *table) newSubscription(sender *responseSender) *subscription {
-	val := atomic.AddUint64(&subid, 1)
-	sub := newSubscription(sender, val)
-	this.subscriptions.add(sender.connectionId, sub)
+// Creates new subscription.
+// Subscription of table pattern shares pubsubid assigned by the data service.
+func (this *table) newSubscription(req *sqlSubscribeRequest) *subscription {
+	val := req.pubsubid
+	if val == 0 {
+		val = atomic.AddUint64(&subid, 1)
+	}
+	sub := newSubscription(req.sender, val)
+	if len(req.pattern) > 0 {
+		sub.table = this.name
+	}
+	this.subscriptions.add(req.sender.connectionId, sub)
 	return sub
 }
 
-func (this *table) subscribeToTable(sender *responseSender, skip bool) (*subscription, []*record) {
-	sub := this.newSubscription(sender)
+func (this *table) subscribeToTable(req *sqlSubscribeRequest) (*subscription, []*record) {
+	sub := this.newSubscription(req)
 	this.pubsub.add(sub)
-	this.send(sender, newSubscribeResponse(sub))
+	this.send(req.sender, newSubscribeResponse(sub))
 	var records []*record
-	if !skip {
+	if !req.skip {
 		records = this.records
 	}
 	return sub, records
 }
 
-func (this *table) subscribeToKeyOrTag(col *column, val string, sender *responseSender, skip bool) (*subscription, []*record) {
-	sub := this.newSubscription(sender)
+func (this *table) subscribeToKeyOrTag(col *column, req *sqlSubscribeRequest) (*subscription, []*record) {
+	sub := this.newSubscription(req)
+	val := req.filter.val
 	var records []*record
-	if !skip {
+	if !req.skip {
 		records = this.getRecordsByTag(val, col)
 	}
 	col.tagmap.getAddTagItem(val).pubsub.add(sub)
-	this.send(sender, newSubscribeResponse(sub))
+	this.send(req.sender, newSubscribeResponse(sub))
 	return sub, records
 }
 
-func (this *table) subscribeToId(id string, sender *responseSender, skip bool) (*subscription, []*record) {
+func (this *table) subscribeToId(req *sqlSubscribeRequest) (*subscription, []*record) {
+	id := req.filter.val
 	records := this.getRecordById(id)
 	if len(records) > 0 {
-		sub := this.newSubscription(sender)
+		sub := this.newSubscription(req)
 		records[0].addSubscription(sub)
-		this.send(sender, newSubscribeResponse(sub))
-		if skip {
+		this.send(req.sender, newSubscribeResponse(sub))
+		if req.skip {
 			records = nil
 		}
 		return sub, records
 	}
-	this.send(sender, newErrorResponse("id: "+id+" does not exist"))
+	this.send(req.sender, newErrorResponseWithCode(errorCodeRecordNotFound, "id: "+id+" does not exist"))
 	return nil, nil
 }
 
@@ -788,37 +2413,167 @@ func (this *table) send(sender *responseSender, res response) {
 	sender.send(res)
 }
 
-func (this *table) subscribe(col *column, val string, sender *responseSender, skip bool) (*subscription, []*record) {
+// Sends the response to the subscriber.
+// Response is stamped with sequence number of the last change unless it already has one.
+// Responses of a transaction in progress are held back until commit.
+// Returns false when the subscription is no longer active.
+func (this *table) publish(sub *subscription, res response) bool {
+	if sequenced, ok := res.(sequencedResponse); ok && sequenced.getSeq() == 0 {
+		sequenced.setSeq(this.seq)
+	}
+	if this.tx != nil {
+		this.tx.published = append(this.tx.published, publication{sub: sub, res: res})
+		return true
+	}
+	if !sub.active() {
+		return false
+	}
+	if queued, ok := res.(queuedResponse); ok && config.SUBSCRIPTION_QUEUE_SIZE > 0 {
+		if sub.queue.size() >= config.SUBSCRIPTION_QUEUE_SIZE {
+			return this.onOverflow(sub)
+		}
+		sub.overflowed = false
+		sub.queue.enqueue()
+		queued.setQueue(sub.queue)
+	}
+	return sub.sender.send(res)
+}
+
+// Applies overflow policy to the subscription whose subscriber does not keep up
+// with published events.
+func (this *table) onOverflow(sub *subscription) bool {
+	if config.SUBSCRIPTION_OVERFLOW_POLICY == overflowPolicyCoalesce {
+		// events are skipped until subscriber catches up, it is notified once
+		if sub.overflowed {
+			return true
+		}
+		sub.overflowed = true
+		return sub.sender.send(newSubscriptionOverflowResponse(sub.id, false))
+	}
+	sender := sub.sender
+	logWarn("dropping subscription:", sub.id, "due to overflow; connection:", sender.connectionId)
+	this.subscriptions.deactivate(sender.connectionId, sub.id)
+	sender.send(newSubscriptionOverflowResponse(sub.id, true))
+	return false
+}
+
+func (this *table) subscribe(col *column, req *sqlSubscribeRequest) (*subscription, []*record) {
 	if col == nil {
-		return this.subscribeToTable(sender, skip)
+		return this.subscribeToTable(req)
 	}
 	switch col.typ {
 	case columnTypeKey:
-		return this.subscribeToKeyOrTag(col, val, sender, skip)
+		return this.subscribeToKeyOrTag(col, req)
 	case columnTypeTag:
-		return this.subscribeToKeyOrTag(col, val, sender, skip)
+		return this.subscribeToKeyOrTag(col, req)
 	case columnTypeId:
-		return this.subscribeToId(val, sender, skip)
+		return this.subscribeToId(req)
 	}
-	this.send(sender, newErrorResponse("Unexpected logical error"))
+	this.send(req.sender, newErrorResponse("Unexpected logical error"))
 	return nil, nil
 }
 
-// Processes sql subscribe requesthis.
-// Does not return anything, responses are send directly to response this.
-func (this *table) sqlSubscribe(req *sqlSubscribeRequest) {
+// subscriptionFilter is or filter of table subscription.
+// It is evaluated against every inserted, updated and deleted record of the table.
+type subscriptionFilter struct {
+	filters []*sqlFilter
+	cols    []*column
+}
+
+// Returns true if the record matches any of the filters.
+func (this *subscriptionFilter) matches(rec *record) bool {
+	for idx, f := range this.filters {
+		if filterMatches(f, this.cols[idx], rec.getValue(this.cols[idx].ordinal)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribes to records that match any of or filters.
+// Records that start or stop matching the filters on update are added or removed.
+func (this *table) subscribeToOrFilter(req *sqlSubscribeRequest) (*subscription, []*record) {
+	filter := new(subscriptionFilter)
+	for f := &req.filter; f != nil; f = f.or {
+		if f.between || f.not {
+			this.send(req.sender, newErrorResponseWithCode(errorCodeInvalidFilter, "can not subscribe with between or not filter"))
+			return nil, nil
+		}
+		col := this.getColumn(f.col)
+		if col == nil {
+			this.send(req.sender, newErrorResponseWithCode(errorCodeInvalidColumn, "unknown column: "+f.col))
+			return nil, nil
+		}
+		filter.filters = append(filter.filters, f)
+		filter.cols = append(filter.cols, col)
+	}
+	var records []*record
+	if !req.skip {
+		var errResponse response
+		records, errResponse = this.getRecordsBySqlFilter(req.filter)
+		if errResponse != nil {
+			this.send(req.sender, errResponse)
+			return nil, nil
+		}
+	}
+	sub := this.newSubscription(req)
+	sub.filter = filter
+	this.pubsub.add(sub)
+	this.send(req.sender, newSubscribeResponse(sub))
+	return sub, records
+}
+
+// Validates the filter and subscribes.
+// Sends error and returns nil subscription when the filter is not valid.
+func (this *table) subscribeBySqlFilter(req *sqlSubscribeRequest) (*subscription, []*record) {
+	// case insensitive equality is evaluated on every change the same way as or filter
+	if req.filter.or != nil || req.filter.nocase {
+		return this.subscribeToOrFilter(req)
+	}
 	// validate
 	errRes, col := this.validateSqlFilter(req.filter)
 	if errRes != nil {
 		this.send(req.sender, errRes)
+		return nil, nil
+	}
+	if req.filter.between {
+		this.send(req.sender, newErrorResponseWithCode(errorCodeInvalidFilter, "can not subscribe with between filter"))
+		return nil, nil
+	}
+	if req.filter.not {
+		this.send(req.sender, newErrorResponseWithCode(errorCodeInvalidFilter, "can not subscribe with not filter"))
+		return nil, nil
+	}
+	return this.subscribe(col, req)
+}
+
+// Processes sql subscribe requesthis.
+// Does not return anything, responses are send directly to response this.
+func (this *table) sqlSubscribe(req *sqlSubscribeRequest) {
+	sub, records := this.subscribeBySqlFilter(req)
+	if sub == nil {
+		return
+	}
+	sub.columns = req.columns
+	sub.thresholds = req.thresholds
+	if req.skip {
 		return
 	}
-	// subscribe
-	sub, records := this.subscribe(col, req.filter.val, req.sender, req.skip)
-	if sub != nil && len(records) > 0 && this.count > 0 {
+	if req.since != nil {
+		if events, ok := this.events.since(*req.since, this.seq); ok {
+			this.replayEvents(sub, &req.filter, events)
+			this.publishActionComplete(sub)
+			return
+		}
+		// changes subscriber missed are no longer available
+		this.publishActionResync(sub)
+	}
+	if len(records) > 0 && this.count > 0 {
 		// publish initial action add
 		this.publishActionAdd(sub, records)
 	}
+	// initial records are sent, even when there were none
+	this.publishActionComplete(sub)
 }
 
 // PUBSUB helpers
@@ -826,6 +2581,9 @@ type publishAction func(thisbl *table, sub *subscription, rec *record) bool
 
 func (this *table) visitSubscriptions(rec *record, publishActionFunc publishAction) {
 	f := func(sub *subscription) bool {
+		if sub.filter != nil && !sub.filter.matches(rec) {
+			return true
+		}
 		return publishActionFunc(this, sub, rec)
 	}
 	this.pubsub.visit(f)
@@ -833,75 +2591,300 @@ func (this *table) visitSubscriptions(rec *record, publishActionFunc publishActi
 		if lnk.pubsub != nil {
 			lnk.pubsub.visit(f)
 		}
+		for _, more := range lnk.more {
+			more.pubsub.visit(f)
+		}
 	}
 }
 
 func (this *table) publishActionAdd(sub *subscription, records []*record) bool {
 	res := new(sqlActionAddResponse)
 	res.pubsubid = sub.id
+	res.table = sub.table
 	this.copyRecordsToSqlSelectResponse(&res.sqlSelectResponse, records, nil)
-	return sub.sender.send(res)
+	return this.publish(sub, res)
+}
+
+func (this *table) publishActionComplete(sub *subscription) bool {
+	res := new(sqlActionCompleteResponse)
+	res.pubsubid = sub.id
+	res.table = sub.table
+	return this.publish(sub, res)
+}
+
+func (this *table) publishActionResync(sub *subscription) bool {
+	res := new(sqlActionResyncResponse)
+	res.pubsubid = sub.id
+	res.table = sub.table
+	return this.publish(sub, res)
+}
+
+// Publishes logged changes to the subscription as if it was subscribed when they happened.
+// Updates of records that started or stopped matching the filter are published as add or remove.
+// Replayed events carry sequence numbers of the changes, thresholds are not applied to them.
+func (this *table) replayEvents(sub *subscription, filter *sqlFilter, events []*tableEvent) {
+	var col *column
+	if sub.filter == nil && len(filter.col) > 0 {
+		col = this.getColumn(filter.col)
+	}
+	matches := func(rec *record) bool {
+		switch {
+		case rec == nil:
+			return false
+		case sub.filter != nil:
+			return sub.filter.matches(rec)
+		case col != nil:
+			return filterMatches(filter, col, rec.getValue(col.ordinal))
+		}
+		return true
+	}
+	for _, ev := range events {
+		before, after := matches(ev.before), matches(ev.after)
+		var res response
+		var data *sqlPubSubResponse
+		switch {
+		case before && after:
+			if !sub.watchesAny(ev.cols) {
+				continue
+			}
+			update := newSqlActionUpdateResponse(sub.id, ev.cols, ev.after)
+			res, data = update, &update.sqlPubSubResponse
+		case after && ev.action == eventActionInsert:
+			insert := new(sqlActionInsertResponse)
+			res, data = insert, &insert.sqlPubSubResponse
+		case after:
+			add := new(sqlActionAddResponse)
+			res, data = add, &add.sqlPubSubResponse
+		case before && ev.action == eventActionDelete:
+			del := new(sqlActionDeleteResponse)
+			res, data = del, &del.sqlPubSubResponse
+		case before:
+			remove := new(sqlActionRemoveResponse)
+			res, data = remove, &remove.sqlPubSubResponse
+		default:
+			continue
+		}
+		if data.records == nil {
+			rec := ev.after
+			if !after {
+				rec = ev.before
+			}
+			this.copyRecordToSqlSelectResponse(&data.sqlSelectResponse, rec)
+		}
+		data.pubsubid = sub.id
+		data.table = sub.table
+		data.seq = ev.seq
+		if !this.publish(sub, res) {
+			return
+		}
+	}
 }
 
 func publishActionInsert(this *table, sub *subscription, rec *record) bool {
 	res := new(sqlActionInsertResponse)
 	res.pubsubid = sub.id
+	res.table = sub.table
 	this.copyRecordToSqlSelectResponse(&res.sqlSelectResponse, rec)
-	return sub.sender.send(res)
+	return this.publish(sub, res)
+}
+
+func (this *table) publishActionDeleteRecords(sub *subscription, records []*record) bool {
+	res := new(sqlActionDeleteResponse)
+	res.pubsubid = sub.id
+	res.table = sub.table
+	this.copyRecordsToSqlSelectResponse(&res.sqlSelectResponse, records, nil)
+	return this.publish(sub, res)
 }
 
 func publishActionDelete(this *table, sub *subscription, rec *record) bool {
+	sub.forget(rec)
 	res := new(sqlActionDeleteResponse)
 	res.pubsubid = sub.id
+	res.table = sub.table
 	this.copyRecordToSqlSelectResponse(&res.sqlSelectResponse, rec)
-	return sub.sender.send(res)
+	return this.publish(sub, res)
 }
 
 func (this *table) onInsert(rec *record) {
+	this.logEvent(eventActionInsert, nil, nil, this.eventValues(rec))
 	this.visitSubscriptions(rec, publishActionInsert)
 }
 
 func (this *table) onDelete(rec *record) {
+	this.logEvent(eventActionDelete, nil, this.eventValues(rec), nil)
 	this.visitSubscriptions(rec, publishActionDelete)
 }
 
+// Returns copy of record values for the event log, nil when the log is disabled.
+func (this *table) eventValues(rec *record) *record {
+	if !this.events.enabled() {
+		return nil
+	}
+	return copyRecordValues(rec)
+}
+
+// Assigns next sequence number to the record change and logs it for resuming subscribers.
+// before and after are copies of record values before and after the change.
+func (this *table) logEvent(action string, cols []*column, before *record, after *record) {
+	this.seq++
+	if this.events.enabled() {
+		this.events.add(&tableEvent{
+			seq:    this.seq,
+			action: action,
+			cols:   cols,
+			before: before,
+			after:  after,
+		})
+	}
+}
+
+// Publishes delete of all records in batches per subscription.
+// Truncate is a single change, logged events are forgotten
+// and subscribers resuming from before the truncate have to resync.
+func (this *table) onTruncate() {
+	this.seq++
+	this.events.clear()
+	// table subscriptions
+	this.pubsub.visit(func(sub *subscription) bool {
+		if sub.filter == nil {
+			return this.publishActionDeleteRecords(sub, this.records)
+		}
+		records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+		for _, rec := range this.records {
+			if rec != nil && sub.filter.matches(rec) {
+				records = append(records, rec)
+			}
+		}
+		if len(records) == 0 {
+			return true
+		}
+		return this.publishActionDeleteRecords(sub, records)
+	})
+	// key and tag subscriptions
+	for _, col := range this.tagedColumns {
+		for _, item := range col.tagmap.tags {
+			if item.head == nil || !item.pubsub.hasSubscriptions() {
+				continue
+			}
+			records := make([]*record, 0, config.TABLE_GET_RECORDS_BY_TAG_CAPACITY)
+			for tg := item.head; tg != nil; tg = tg.next {
+				records = append(records, this.records[tg.idx])
+			}
+			item.pubsub.visit(func(sub *subscription) bool {
+				return this.publishActionDeleteRecords(sub, records)
+			})
+		}
+	}
+	// id subscriptions
+	for _, rec := range this.records {
+		if rec != nil && rec.links[0].pubsub != nil {
+			rec.links[0].pubsub.visit(func(sub *subscription) bool {
+				return publishActionDelete(this, sub, rec)
+			})
+		}
+	}
+}
+
+// Publishes remove to subscriptions whose filter the updated record no longer matches.
 func (this *table) onRemove(pubsubs []*pubsub, rec *record) {
 	visitor := func(sub *subscription) bool {
+		sub.forget(rec)
 		res := new(sqlActionRemoveResponse)
 		res.pubsubid = sub.id
+		res.table = sub.table
 		this.copyRecordToSqlSelectResponse(&res.sqlSelectResponse, rec)
-		return sub.sender.send(res)
+		return this.publish(sub, res)
 	}
 	for _, pubsub := range pubsubs {
 		pubsub.visit(visitor)
 	}
 }
 
+// Publishes add to subscriptions whose filter the updated record now matches.
+// Such subscriptions do not receive update for the same change.
 func (this *table) onAdd(added map[*pubsub]int, rec *record) {
 	visitor := func(sub *subscription) bool {
 		res := new(sqlActionAddResponse)
 		res.pubsubid = sub.id
+		res.table = sub.table
 		this.copyRecordToSqlSelectResponse(&res.sqlSelectResponse, rec)
-		return sub.sender.send(res)
+		return this.publish(sub, res)
 	}
 	for pubsub, _ := range added {
 		pubsub.visit(visitor)
 	}
 }
 
-func (this *table) onUpdate(cols []*column, rec *record, added *map[*pubsub]int) {
+// Returns filtered table subscriptions the record matches before it is updated.
+func (this *table) matchFilteredSubscriptions(rec *record) map[*subscription]bool {
+	var matched map[*subscription]bool
+	this.pubsub.visit(func(sub *subscription) bool {
+		if sub.filter != nil && sub.filter.matches(rec) {
+			if matched == nil {
+				matched = make(map[*subscription]bool)
+			}
+			matched[sub] = true
+		}
+		return true
+	})
+	return matched
+}
+
+// Publishes add, remove or update to filtered table subscription
+// depending on whether the record matched the filter before and after the change.
+func (this *table) publishFilteredUpdate(sub *subscription, rec *record, before bool, update pubsubVisitor) bool {
+	after := sub.filter.matches(rec)
+	switch {
+	case before && after:
+		return update(sub)
+	case after:
+		res := new(sqlActionAddResponse)
+		res.pubsubid = sub.id
+		res.table = sub.table
+		this.copyRecordToSqlSelectResponse(&res.sqlSelectResponse, rec)
+		return this.publish(sub, res)
+	case before:
+		sub.forget(rec)
+		res := new(sqlActionRemoveResponse)
+		res.pubsubid = sub.id
+		res.table = sub.table
+		this.copyRecordToSqlSelectResponse(&res.sqlSelectResponse, rec)
+		return this.publish(sub, res)
+	}
+	return true
+}
+
+// Publishes update to subscriptions whose filter the record matched before and after the change.
+// matched are filtered table subscriptions the record matched before the change.
+func (this *table) onUpdate(cols []*column, rec *record, added *map[*pubsub]int, matched map[*subscription]bool) {
 	visitor := func(sub *subscription) bool {
+		if !sub.watchesAny(cols) {
+			return true
+		}
+		if sub.thresholds != nil && !sub.exceedsThresholds(cols, rec) {
+			return true
+		}
 		res := newSqlActionUpdateResponse(sub.id, cols, rec)
-		return sub.sender.send(res)
+		res.table = sub.table
+		return this.publish(sub, res)
+	}
+	this.pubsub.visit(func(sub *subscription) bool {
+		if sub.filter != nil {
+			return this.publishFilteredUpdate(sub, rec, matched[sub], visitor)
+		}
+		return visitor(sub)
+	})
+	visit := func(pubsub *pubsub) {
+		// ignore updates for record that was just added
+		if pubsub == nil || added != nil && (*added)[pubsub] != 0 {
+			return
+		}
+		pubsub.visit(visitor)
 	}
-	this.pubsub.visit(visitor)
 	for _, lnk := range rec.links {
-		if lnk.pubsub != nil {
-			// ignore updates for record that was just added
-			if added != nil && (*added)[lnk.pubsub] != 0 {
-				continue
-			}
-			lnk.pubsub.visit(visitor)
+		visit(lnk.pubsub)
+		for _, more := range lnk.more {
+			visit(more.pubsub)
 		}
 	}
 }
@@ -912,7 +2895,16 @@ func (this *table) onUpdate(cols []*column, rec *record, added *map[*pubsub]int)
 func (this *table) sqlUnsubscribe(req *sqlUnsubscribeRequest) response {
 	// validate
 	if len(req.filter.col) > 0 && req.filter.col != "pubsubid" {
-		return newErrorResponse("Invalid filter expected pubsubid but got " + req.filter.col)
+		return newErrorResponseWithCode(errorCodeInvalidFilter, "Invalid filter expected pubsubid but got "+req.filter.col)
+	}
+	if req.filter.between {
+		return newErrorResponseWithCode(errorCodeInvalidFilter, "Invalid filter between is not supported for unsubscribe")
+	}
+	if req.filter.not {
+		return newErrorResponseWithCode(errorCodeInvalidFilter, "Invalid filter not is not supported for unsubscribe")
+	}
+	if req.filter.or != nil {
+		return newErrorResponseWithCode(errorCodeInvalidFilter, "Invalid filter or is not supported for unsubscribe")
 	}
 	// unsubscribe by pubsubid for a given connection
 	res := new(sqlUnsubscribeResponse)
@@ -927,7 +2919,7 @@ func (this *table) sqlUnsubscribe(req *sqlUnsubscribeRequest) response {
 		}
 	} else {
 		// unsubscribe all subscriptions for a given connection
-		res.unsubscribed = this.subscriptions.deactivateAll(req.connectionId)
+		res.unsubscribed = len(this.subscriptions.deactivateAll(req.connectionId))
 	}
 	return res
 }
@@ -937,6 +2929,13 @@ func (this *table) sqlUnsubscribe(req *sqlUnsubscribeRequest) response {
 func (this *table) run() {
 	this.quit.Join()
 	defer this.quit.Leave()
+	// sweeps expired records while the table is idle
+	var sweep <-chan time.Time
+	if config.WAIT_MILLISECOND_TTL_SWEEP > 0 {
+		ticker := time.NewTicker(config.WAIT_MILLISECOND_TTL_SWEEP * time.Millisecond)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
 	for {
 		select {
 		case item := <-this.requests:
@@ -946,6 +2945,11 @@ func (this *table) run() {
 			}
 			this.requestId = item.getRequestId()
 			this.onSqlRequest(item.req, item.sender)
+			this.updateStats()
+		case now := <-sweep:
+			if this.expireRecords(now) > 0 {
+				this.updateStats()
+			}
 		case <-this.quit.GetChan():
 			debug("table quit")
 			return
@@ -954,22 +2958,34 @@ func (this *table) run() {
 }
 
 func (this *table) onSqlRequest(req request, sender *responseSender) {
+	// expired records are never part of results even before they are swept
+	this.expireRecords(time.Now())
 	this.streaming = req.isStreaming()
+	columns := len(this.colSlice)
+	defer this.publishAddedColumns(columns)
 	switch req.(type) {
 	case *sqlInsertRequest:
 		this.onSqlInsert(req.(*sqlInsertRequest), sender)
 	case *sqlPushRequest:
 		this.onSqlPush(req.(*sqlPushRequest), sender)
+	case *sqlCopyRequest:
+		this.onSqlCopy(req.(*sqlCopyRequest), sender)
 	case *sqlSelectRequest:
 		this.onSqlSelect(req.(*sqlSelectRequest), sender)
+	case *sqlSelectIntoRequest:
+		this.onSqlSelectInto(req.(*sqlSelectIntoRequest), sender)
 	case *sqlPeekRequest:
 		this.onSqlPeek(req.(*sqlPeekRequest), sender)
 	case *sqlPopRequest:
 		this.onSqlPop(req.(*sqlPopRequest), sender)
 	case *sqlUpdateRequest:
 		this.onSqlUpdate(req.(*sqlUpdateRequest), sender)
+	case *sqlUpsertRequest:
+		this.onSqlUpsert(req.(*sqlUpsertRequest), sender)
 	case *sqlDeleteRequest:
 		this.onSqlDelete(req.(*sqlDeleteRequest), sender)
+	case *sqlTruncateRequest:
+		this.onSqlTruncate(req.(*sqlTruncateRequest), sender)
 	case *sqlSubscribeRequest:
 		this.onSqlSubscribe(req.(*sqlSubscribeRequest), sender)
 	case *mysqlSubscribeRequest:
@@ -978,10 +2994,35 @@ func (this *table) onSqlRequest(req request, sender *responseSender) {
 		this.onSqlUnsubscribe(req.(*sqlUnsubscribeRequest), sender)
 	case *mysqlUnsubscribeRequest:
 		this.onMysqlUnsubscribe(req.(*mysqlUnsubscribeRequest), sender)
+	case *sqlUnsubscribeAllRequest:
+		this.onSqlUnsubscribeAll(req.(*sqlUnsubscribeAllRequest), sender)
 	case *sqlKeyRequest:
 		this.onSqlKey(req.(*sqlKeyRequest), sender)
 	case *sqlTagRequest:
 		this.onSqlTag(req.(*sqlTagRequest), sender)
+	case *sqlRangeRequest:
+		this.onSqlRange(req.(*sqlRangeRequest), sender)
+	case *sqlDescribeRequest:
+		this.onSqlDescribe(req.(*sqlDescribeRequest), sender)
+	case *sqlSaveRequest:
+		this.onSqlSave(req.(*sqlSaveRequest), sender)
+	case *sqlLoadRequest:
+		this.onSqlLoad(req.(*sqlLoadRequest), sender)
+	case *sqlCreateRequest:
+		this.onSqlCreate(req.(*sqlCreateRequest), sender)
+	case *sqlTransactionRequest:
+		this.onSqlTransaction(req.(*sqlTransactionRequest), sender)
+	}
+}
+
+// Notifies schema subscribers about columns added by the request.
+// Columns added by a failed request are already removed and are not published.
+func (this *table) publishAddedColumns(columns int) {
+	if this.dataSrv == nil {
+		return
+	}
+	for idx := columns; idx < len(this.colSlice); idx++ {
+		this.dataSrv.schema.publish("alter", this.name, this.colSlice[idx].name)
 	}
 }
 
@@ -995,10 +3036,22 @@ func (this *table) onSqlPush(req *sqlPushRequest, sender *responseSender) {
 	this.send(sender, res)
 }
 
+func (this *table) onSqlCopy(req *sqlCopyRequest, sender *responseSender) {
+	this.send(sender, this.sqlCopy(req))
+}
+
 func (this *table) onSqlSelect(req *sqlSelectRequest, sender *responseSender) {
+	if len(req.into) > 0 {
+		this.sqlSelectInto(req, sender)
+		return
+	}
 	this.send(sender, this.sqlSelect(req))
 }
 
+func (this *table) onSqlSelectInto(req *sqlSelectIntoRequest, sender *responseSender) {
+	this.send(sender, this.sqlSelectIntoCopy(req))
+}
+
 func (this *table) onSqlPeek(req *sqlPeekRequest, sender *responseSender) {
 	this.send(sender, this.sqlPeek(req))
 }
@@ -1011,10 +3064,18 @@ func (this *table) onSqlUpdate(req *sqlUpdateRequest, sender *responseSender) {
 	this.send(sender, this.sqlUpdate(req))
 }
 
+func (this *table) onSqlUpsert(req *sqlUpsertRequest, sender *responseSender) {
+	this.send(sender, this.sqlUpsert(req))
+}
+
 func (this *table) onSqlDelete(req *sqlDeleteRequest, sender *responseSender) {
 	this.send(sender, this.sqlDelete(req))
 }
 
+func (this *table) onSqlTruncate(req *sqlTruncateRequest, sender *responseSender) {
+	this.send(sender, this.sqlTruncate(req))
+}
+
 func (this *table) onSqlSubscribe(req *sqlSubscribeRequest, sender *responseSender) {
 	req.sender = sender
 	this.sqlSubscribe(req)
@@ -1032,6 +3093,11 @@ func (this *table) onSqlUnsubscribe(req *sqlUnsubscribeRequest, sender *response
 	this.send(sender, this.sqlUnsubscribe(req))
 }
 
+// Removes all subscriptions of the connection and reports them to the unsubscribe all tally.
+func (this *table) onSqlUnsubscribeAll(req *sqlUnsubscribeAllRequest, sender *responseSender) {
+	req.tally.done(this.subscriptions.deactivateAll(sender.connectionId))
+}
+
 func (this *table) onMysqlUnsubscribe(req *mysqlUnsubscribeRequest, sender *responseSender) {
 	info("onMysqlUnsubscribe:", req.getTableName())
 	sqlReq := new(sqlUnsubscribeRequest)
@@ -1046,3 +3112,27 @@ func (this *table) onSqlKey(req *sqlKeyRequest, sender *responseSender) {
 func (this *table) onSqlTag(req *sqlTagRequest, sender *responseSender) {
 	this.send(sender, this.sqlTag(req))
 }
+
+func (this *table) onSqlRange(req *sqlRangeRequest, sender *responseSender) {
+	this.send(sender, this.sqlRange(req))
+}
+
+func (this *table) onSqlSave(req *sqlSaveRequest, sender *responseSender) {
+	this.send(sender, this.sqlSave(req))
+}
+
+func (this *table) onSqlLoad(req *sqlLoadRequest, sender *responseSender) {
+	this.send(sender, this.sqlLoad(req))
+}
+
+func (this *table) onSqlDescribe(req *sqlDescribeRequest, sender *responseSender) {
+	this.send(sender, this.sqlDescribe(req))
+}
+
+func (this *table) onSqlCreate(req *sqlCreateRequest, sender *responseSender) {
+	this.send(sender, this.sqlCreate(req))
+}
+
+func (this *table) onSqlTransaction(req *sqlTransactionRequest, sender *responseSender) {
+	this.send(sender, this.sqlTransaction(req))
+}
@@ -19,6 +19,10 @@ package server
 import "testing"
 import "strconv"
 import "reflect"
+import "strings"
+import "io/ioutil"
+import "os"
+import "time"
 
 func validateTableRecordsCount(t *testing.T, tbl *table, expected int) {
 	val := tbl.getRecordCount()
@@ -132,6 +136,104 @@ func TestTableSqlInsert(t *testing.T) {
 	validateSqlInsertResponse(t, res)
 }
 
+func TestTableSqlInsertReturning(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 12) ")
+	// returning * includes generated id and columns the insert did not set
+	res, ok := insertHelper(tbl, " insert into stocks (ticker) values (MSFT) returning * ").(*sqlActionDataResponse)
+	if !ok {
+		t.Fatalf("table insert error: expected sqlActionDataResponse")
+	}
+	validateSqlSelect(t, &res.sqlSelectResponse, 1, 3)
+	rec := res.records[0]
+	if rec.getValue(0) != "1" || rec.getValue(1) != "MSFT" || rec.getValue(2) != "" {
+		t.Errorf("table insert error: unexpected returned record %v", rec.values)
+	}
+	// returning columns
+	res = insertHelper(tbl, " insert into stocks (ticker, bid) values (ORCL, 40) returning id, bid ").(*sqlActionDataResponse)
+	validateSqlSelect(t, &res.sqlSelectResponse, 1, 2)
+	if res.records[0].getValue(0) != "2" || res.records[0].getValue(1) != "40" {
+		t.Errorf("table insert error: unexpected returned record %v", res.records[0].values)
+	}
+	// without returning only the action is reported
+	res = insertHelper(tbl, " insert into stocks (ticker) values (AAPL) ").(*sqlActionDataResponse)
+	validateSqlSelect(t, &res.sqlSelectResponse, 0, 0)
+}
+
+func TestTableSqlInsertTtl(t *testing.T) {
+	tbl := newTable("cache")
+	validateOkResponse(t, tagHelper(tbl, "tag cache key"))
+	insertHelper(tbl, " insert into cache (key) values (k1) ttl 60 ")
+	insertHelper(tbl, " insert into cache (key) values (k2) ")
+	insertHelper(tbl, " insert into cache (key) values (k1) ttl 120 ")
+	now := time.Now()
+	if tbl.expireRecords(now) != 0 {
+		t.Errorf("table expiry error: records expired before their ttl")
+	}
+	// first record expires and its tag is removed
+	if tbl.expireRecords(now.Add(90*time.Second)) != 1 {
+		t.Errorf("table expiry error: expected one expired record")
+	}
+	if tbl.count != 2 {
+		t.Errorf("table expiry error: expected 2 records but got %d", tbl.count)
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from cache where key = k1 "), 1, 2)
+	// records without ttl never expire
+	if tbl.expireRecords(now.Add(time.Hour)) != 1 || tbl.nextExpiry != 0 {
+		t.Errorf("table expiry error: expected last record with ttl to expire")
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from cache "), 1, 2)
+}
+
+// COPY
+
+func copyHelper(t *table, sqlCopy string) response {
+	pc := newTokens()
+	lex(sqlCopy, pc)
+	req := parse(pc).(*sqlCopyRequest)
+	return t.sqlCopy(req)
+}
+
+func TestTableSqlCopy(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (ORCL, 10) ")
+	res := copyHelper(tbl, " copy into stocks (ticker, bid) from stream (IBM, 12) (MSFT, 37) (AAPL, 100) ")
+	if x, ok := res.(*sqlCopyResponse); !ok {
+		t.Fatalf("table copy error: expected sqlCopyResponse but got %T", res)
+	} else if x.rows != 3 || x.fromId != 1 || x.toId != 3 {
+		t.Errorf("table copy error: unexpected rows:%d fromid:%d toid:%d", x.rows, x.fromId, x.toId)
+	}
+	sel := selectHelper(tbl, " select * from stocks where ticker = MSFT ").(*sqlSelectResponse)
+	if len(sel.records) != 1 || sel.records[0].getValue(0) != "2" {
+		t.Errorf("table copy error: expected MSFT with id 2")
+	}
+	// duplicate key within the rows or with existing record fails the whole copy
+	validateErrorCode(t, copyHelper(tbl, " copy into stocks (ticker) from stream (GOOG) (GOOG) "), errorCodeDuplicateKey)
+	validateErrorCode(t, copyHelper(tbl, " copy into stocks (ticker, sector) from stream (YHOO, tech) (IBM, tech) "), errorCodeDuplicateKey)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 4, 3)
+	//
+	res = copyHelper(tbl, " copy into stocks (ticker) from stream ")
+	if x, ok := res.(*sqlCopyResponse); !ok || x.rows != 0 {
+		t.Errorf("table copy error: expected empty copy")
+	}
+}
+
+func BenchmarkTableSqlCopy(b *testing.B) {
+	rows := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, "(IBM, 12, 14.5645)")
+	}
+	pc := newTokens()
+	lex(" copy into stocks (ticker, bid, ask) from stream "+strings.Join(rows, " "), pc)
+	req := parse(pc).(*sqlCopyRequest)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tbl := newTable("stocks")
+		tbl.sqlCopy(req)
+	}
+}
+
 func BenchmarkTableSqlInser(b *testing.B) {
 	tbl := newTable("stocks")
 	for i := 0; i < b.N; i++ {
@@ -193,6 +295,529 @@ func TestTableSqlSelect1(t *testing.T) {
 	validateSqlSelect(t, res, 2, 6)
 }
 
+func TestTableSqlSelectLimitOffset(t *testing.T) {
+	tbl := newTable("stocks")
+	for i := 0; i < 10; i++ {
+		insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, "+strconv.Itoa(i)+") ")
+	}
+	deleteHelper(tbl, " delete from stocks where id = 1 ")
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks limit 3 "), 3, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks limit 0 "), 0, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks offset 7 "), 2, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks limit 5 offset 8 "), 1, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks limit 5 offset 100 "), 0, 3)
+	// deleted record is skipped
+	res := selectHelper(tbl, " select bid from stocks limit 2 offset 1 ").(*sqlSelectResponse)
+	if len(res.records) != 2 || res.records[0].getValue(0) != "2" || res.records[1].getValue(0) != "3" {
+		t.Errorf("unexpected records for limit 2 offset 1")
+	}
+}
+
+func validateTruncated(t *testing.T, res response, rows int, truncated string, total int) {
+	x, ok := res.(*sqlSelectResponse)
+	if !ok {
+		t.Fatalf("table select error: expected sqlSelectResponse but got %T", res)
+	}
+	if len(x.records) != rows || x.truncated != truncated || x.total != total {
+		t.Errorf("table select error: expected rows:%d truncated:%q total:%d but got rows:%d truncated:%q total:%d",
+			rows, truncated, total, len(x.records), x.truncated, x.total)
+	}
+}
+
+func TestTableSqlSelectTruncated(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	for i := 0; i < 10; i++ {
+		insertHelper(tbl, " insert into stocks (ticker, sector) values (T"+strconv.Itoa(i)+", tech) ")
+	}
+	deleteHelper(tbl, " delete from stocks where ticker = T0 ")
+	validateTruncated(t, selectHelper(tbl, " select * from stocks "), 9, "", 0)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks limit 9 "), 9, "", 0)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks limit 5 "), 5, truncatedByLimit, 9)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks order by id desc limit 3 "), 3, truncatedByLimit, 9)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks where sector = tech limit 5 offset 6 "), 3, truncatedByLimit, 9)
+	validateTruncated(t, selectHelper(tbl, " select distinct sector from stocks limit 5 "), 1, "", 0)
+	// server cap
+	config.SELECT_MAX_ROWS = 4
+	defer func() { config.SELECT_MAX_ROWS = 0 }()
+	validateTruncated(t, selectHelper(tbl, " select * from stocks "), 4, truncatedByCap, 9)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks limit 2 "), 2, truncatedByLimit, 9)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks limit 6 "), 4, truncatedByCap, 9)
+	validateTruncated(t, selectHelper(tbl, " select * from stocks where sector = tech "), 4, truncatedByCap, 9)
+	validateTruncated(t, selectHelper(tbl, " select sector, count(*) from stocks group by sector "), 1, "", 0)
+	// truncated response reports it to the client
+	bytes, _ := selectHelper(tbl, " select * from stocks ").toNetworkReadyJSON()
+	if !strings.Contains(string(bytes), `"truncated":"cap","total":9`) {
+		t.Errorf("table select error: expected truncated response but got %s", string(bytes))
+	}
+}
+
+func TestTableSqlSelectOrderBy(t *testing.T) {
+	tbl := newTable("stocks")
+	for i := 0; i < 10; i++ {
+		insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, "+strconv.Itoa(9-i)+") ")
+	}
+	deleteHelper(tbl, " delete from stocks where id = 8 ")
+	// reverse primary index
+	res := selectHelper(tbl, " select id from stocks order by id desc limit 3 ").(*sqlSelectResponse)
+	if len(res.records) != 3 || res.records[0].getValue(0) != "9" || res.records[1].getValue(0) != "7" || res.records[2].getValue(0) != "6" {
+		t.Errorf("unexpected records for order by id desc limit 3")
+	}
+	res = selectHelper(tbl, " select id from stocks order by id desc limit 2 offset 2 ").(*sqlSelectResponse)
+	if len(res.records) != 2 || res.records[0].getValue(0) != "6" || res.records[1].getValue(0) != "5" {
+		t.Errorf("unexpected records for order by id desc limit 2 offset 2")
+	}
+	res = selectHelper(tbl, " select id from stocks order by id asc limit 1 ").(*sqlSelectResponse)
+	if len(res.records) != 1 || res.records[0].getValue(0) != "0" {
+		t.Errorf("unexpected records for order by id asc limit 1")
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks order by id desc "), 9, 3)
+	// values are compared as numbers
+	res = selectHelper(tbl, " select bid from stocks order by bid limit 3 ").(*sqlSelectResponse)
+	if len(res.records) != 3 || res.records[0].getValue(0) != "0" || res.records[1].getValue(0) != "2" || res.records[2].getValue(0) != "3" {
+		t.Errorf("unexpected records for order by bid limit 3")
+	}
+	res = selectHelper(tbl, " select bid from stocks order by bid desc limit 1 ").(*sqlSelectResponse)
+	if len(res.records) != 1 || res.records[0].getValue(0) != "9" {
+		t.Errorf("unexpected records for order by bid desc limit 1")
+	}
+}
+
+func TestTableSqlSelectOrderByColumns(t *testing.T) {
+	tbl := newTable("stocks")
+	for _, values := range []string{"NYSE, IBM, 9", "NASDAQ, MSFT, 100", "NYSE, JPM, 100", "NASDAQ, AAPL, 20", "NYSE, GS, 9", "NASDAQ, ORCL, 100"} {
+		insertHelper(tbl, " insert into stocks (exchange, ticker, price) values ("+values+") ")
+	}
+	tickers := func(sql string) string {
+		res := selectHelper(tbl, sql).(*sqlSelectResponse)
+		joined := ""
+		for _, rec := range res.records {
+			joined += rec.getValue(0) + " "
+		}
+		return joined
+	}
+	// ties of the first column are ordered by the next one in its own direction,
+	// records equal in every column keep id order
+	expected := map[string]string{
+		" select ticker from stocks order by exchange asc, price desc ":                 "MSFT ORCL AAPL JPM IBM GS ",
+		" select ticker from stocks order by exchange desc, price ":                     "IBM GS JPM AAPL MSFT ORCL ",
+		" select ticker from stocks order by price desc, exchange, ticker desc ":        "ORCL MSFT JPM AAPL IBM GS ",
+		" select ticker from stocks order by missing, price, ticker limit 3 ":           "GS IBM AAPL ",
+		" select ticker from stocks order by id desc, price ":                           "ORCL GS AAPL JPM MSFT IBM ",
+		" select ticker from stocks where not exchange = NYSE order by price, id desc ": "AAPL ORCL MSFT ",
+	}
+	for sql, want := range expected {
+		if got := tickers(sql); got != want {
+			t.Errorf("unexpected records for%sexpected %s but got %s", sql, want, got)
+		}
+	}
+}
+
+func TestTableSqlSelectRadixValues(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks flags"))
+	insertHelper(tbl, " insert into stocks (ticker, flags) values (IBM, 0xFF) ")
+	insertHelper(tbl, " insert into stocks (ticker, flags) values (MSFT, 0b1010) ")
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where flags = 255 "), 1, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where flags = 0b11111111 "), 1, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where flags = 0xa "), 1, 3)
+}
+
+func TestTableSqlSelectBetween(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 9) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (MSFT, 100) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (ORCL, 150.5) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (JPM, 200) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (GS, 201) ")
+	// numeric comparison, bounds are inclusive
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 100 and 200 "), 3, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 5 and 10 "), 1, 3)
+	// reversed range matches nothing
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 200 and 100 "), 0, 3)
+	// string comparison
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker between I and N "), 3, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where id between 1 and 2 "), 2, 3)
+	validateErrorResponse(t, selectHelper(tbl, " select * from stocks where ask between 1 and 2 "))
+	// update and delete
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set ask = 1 where bid between 150 and 250 "), 3)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where ask between 1 and 1 "), 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 2, 4)
+	// subscribe is not supported
+	res, _ := subscribeHelper(tbl, " subscribe * from stocks where bid between 1 and 2 ")
+	validateErrorResponse(t, res)
+}
+
+func TestTableSqlSelectNot(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 9, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (MSFT, 100, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (JPM, 200, FIN) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (GS, 201) ")
+	// key, tag, id and non indexed column
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not ticker = IBM "), 3, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not sector = TECH "), 2, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not id = 01 "), 3, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not bid = 100 "), 3, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not bid between 100 and 200 "), 2, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not not sector = TECH "), 2, 4)
+	validateErrorResponse(t, selectHelper(tbl, " select * from stocks where not ask = 1 "))
+	validateErrorResponse(t, selectHelper(tbl, " select * from stocks where not not bid = 1 "))
+	// update and delete
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 0 where not sector = FIN "), 3)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where not bid = 0 "), 1)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 3, 4)
+	// subscribe is not supported
+	res, _ := subscribeHelper(tbl, " subscribe * from stocks where not sector = TECH ")
+	validateErrorResponse(t, res)
+}
+
+func TestTableSqlSelectNocase(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, createHelper(tbl, "create table stocks (ticker, name, bid int)"))
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	insertHelper(tbl, " insert into stocks (ticker, name, bid) values (IBM, 'Ibm Corp', 9) ")
+	insertHelper(tbl, " insert into stocks (ticker, name, bid) values (ibm2, 'IBM CORP', 100) ")
+	insertHelper(tbl, " insert into stocks (ticker, name, bid) values (MSFT, Microsoft, 200) ")
+	// key and non indexed column are scanned
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = ibm collate nocase "), 1, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where name = 'ibm corp' collate nocase "), 2, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where name = 'ibm corp' collate nocase or ticker = MSFT "), 3, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not name = microsoft collate nocase "), 2, 4)
+	// update and delete
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 0 where ticker = IBM2 collate nocase "), 1)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where name = MICROSOFT collate nocase "), 1)
+	// numeric columns ignore collation
+	filter := sqlFilter{nocase: true}
+	if filter.ignoresCase(tbl.getColumn("bid")) || !filter.ignoresCase(tbl.getColumn("name")) {
+		t.Errorf("table select error: collation should apply to string columns only")
+	}
+	// subscription compares values case insensitively
+	res, sender := subscribeHelper(tbl, " subscribe * from stocks where ticker = msft collate nocase ")
+	validateSqlSubscribeResponse(t, res)
+	validateActionComplete(t, []*responseSender{sender})
+	insertHelper(tbl, " insert into stocks (ticker, name, bid) values (Msft, Microsoft, 200) ")
+	validateActionInsert(t, []*responseSender{sender})
+}
+
+func TestTableSqlSelectIdIn(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 9) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (MSFT, 100) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (JPM, 200) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (GS, 201) ")
+	ids := []string{}
+	for rec := tbl.first; rec != nil; rec = rec.next {
+		ids = append(ids, rec.idAsString())
+	}
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where id in ("+ids[2]+") "), 1)
+	// records are returned in id order, deleted, missing and repeated ids are skipped
+	res := selectHelper(tbl, " select ticker from stocks where id in ("+ids[3]+", 100, "+ids[0]+", "+ids[2]+", "+ids[0]+", abc) ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 2, 1)
+	if res.records[0].getValue(0) != "IBM" || res.records[1].getValue(0) != "GS" {
+		t.Errorf("table select error: unexpected records")
+	}
+	// non indexed columns are scanned
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid in (9, 100, 200) "), 2, 3)
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 0 where ticker in (IBM, MSFT) "), 2)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where id in ("+ids[0]+", "+ids[1]+") "), 2)
+}
+
+func TestTableSqlSelectQueryTimeout(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	for i := 0; i < 3000; i++ {
+		insertHelper(tbl, " insert into stocks (ticker, bid) values (T"+strconv.Itoa(i)+", 1) ")
+	}
+	timeout := time.Nanosecond
+	for _, sql := range []string{
+		" select * from stocks where not ticker = T1 ",
+		" select * from stocks where ticker = t1 collate nocase ",
+		" select * from stocks where ticker = T1 or bid = 2 ",
+	} {
+		pc := newTokens()
+		lex(sql, pc)
+		req := parse(pc).(*sqlSelectRequest)
+		req.filter.queryTimeout = &timeout
+		validateErrorCode(t, tbl.sqlSelect(req), errorCodeQueryTimeout)
+	}
+	// index lookups do not scan
+	pc := newTokens()
+	lex(" select * from stocks where ticker = T1 ", pc)
+	req := parse(pc).(*sqlSelectRequest)
+	req.filter.queryTimeout = &timeout
+	validateSqlSelect(t, tbl.sqlSelect(req), 1, 3)
+	// aborted update does not change any record
+	pc = newTokens()
+	lex(" update stocks set bid = 2 where not ticker = T1 ", pc)
+	update := parse(pc).(*sqlUpdateRequest)
+	update.filter.queryTimeout = &timeout
+	validateErrorCode(t, tbl.sqlUpdate(update), errorCodeQueryTimeout)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = T1 or bid = 2 "), 1, 3)
+}
+
+func TestTableSqlSelectBetweenQueryTimeout(t *testing.T) {
+	tbl := newTable("stocks")
+	for i := 0; i < 3000; i++ {
+		insertHelper(tbl, " insert into stocks (ticker, bid) values (T"+strconv.Itoa(i)+", "+strconv.Itoa(i)+") ")
+	}
+	sess := new(session)
+	if res := sess.set("timeout", "1"); res != nil {
+		t.Fatalf("session error: %T", res)
+	}
+	// millisecond is too long to expire reliably during the scan
+	*sess.queryTimeout = time.Nanosecond
+	pc := newTokens()
+	lex(" select * from stocks where bid between 10 and 20 ", pc)
+	req := parse(pc).(*sqlSelectRequest)
+	sess.apply(req)
+	validateErrorCode(t, tbl.sqlSelect(req), errorCodeQueryTimeout)
+	// range index lookups do not scan
+	validateOkResponse(t, rangeHelper(tbl, "range stocks bid"))
+	pc = newTokens()
+	lex(" select * from stocks where bid between 10 and 20 ", pc)
+	req = parse(pc).(*sqlSelectRequest)
+	sess.apply(req)
+	validateSqlSelect(t, tbl.sqlSelect(req), 11, 3)
+}
+
+func TestTableSqlSelectOr(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 9, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (MSFT, 100, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (JPM, 200, FIN) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (GS, 201) ")
+	// index lookups are merged in id order without duplicates
+	res := selectHelper(tbl, " select ticker from stocks where ticker = JPM or sector = TECH or id = 1 ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 3, 1)
+	if res.records[0].getValue(0) != "IBM" || res.records[1].getValue(0) != "MSFT" || res.records[2].getValue(0) != "JPM" {
+		t.Errorf("table select error: unexpected order of records")
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = ORCL or sector = ENERGY "), 0, 4)
+	// non indexed conditions are scanned
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = IBM or bid = 201 "), 2, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 100 and 200 or ticker = GS "), 3, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not sector = TECH or ticker = IBM "), 3, 4)
+	validateErrorCode(t, selectHelper(tbl, " select * from stocks where ticker = IBM or ask = 1 "), errorCodeInvalidColumn)
+	// update and delete
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 0 where ticker = GS or sector = FIN "), 2)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where bid = 0 or ticker = IBM "), 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 1, 4)
+	// subscribe
+	res2, sender := subscribeHelper(tbl, " subscribe * from stocks where sector = TECH or sector = FIN ")
+	sub := validateSqlSubscribeResponse(t, res2)
+	validateSqlActionAddResponse(t, sender, sub.pubsubid, 1)
+}
+
+func BenchmarkTableSqlSelectOr(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		tbl := newTable("stocks")
+		keyHelper(tbl, "key stocks ticker")
+		tagHelper(tbl, "tag stocks sector")
+		for i := 0; i < size; i++ {
+			insertHelper(tbl, " insert into stocks (ticker, sector, bid) values (T"+strconv.Itoa(i)+", S"+strconv.Itoa(i%1000)+", "+strconv.Itoa(i%1000)+") ")
+		}
+		// bid mirrors sector so both queries return the same records,
+		// index is used for sector and records are scanned for bid
+		for _, cond := range [][2]string{{"sector", "S2"}, {"bid", "2"}} {
+			pc := newTokens()
+			lex(" select * from stocks where ticker = T1 or "+cond[0]+" = "+cond[1], pc)
+			req := parse(pc).(*sqlSelectRequest)
+			b.Run(cond[0]+"/"+strconv.Itoa(size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tbl.sqlSelect(req)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTableSqlSelectIdIn(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		tbl := newTable("stocks")
+		for i := 0; i < size; i++ {
+			insertHelper(tbl, " insert into stocks (ticker, seq) values (T"+strconv.Itoa(i)+", "+strconv.Itoa(i)+") ")
+		}
+		// seq mirrors id so both queries return the same records,
+		// ids are looked up directly and records are scanned for seq
+		list := "(" + strconv.Itoa(size/2) + ", 1, " + strconv.Itoa(size-1) + ", 7, 42)"
+		for _, col := range []string{"id", "seq"} {
+			pc := newTokens()
+			lex(" select * from stocks where "+col+" in "+list, pc)
+			req := parse(pc).(*sqlSelectRequest)
+			b.Run(col+"/"+strconv.Itoa(size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tbl.sqlSelect(req)
+				}
+			})
+		}
+	}
+}
+
+func TestTableSqlRange(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 150) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (MSFT, 100) ")
+	validateOkResponse(t, rangeHelper(tbl, " range stocks bid "))
+	validateErrorResponse(t, rangeHelper(tbl, " range stocks bid "))
+	validateErrorResponse(t, rangeHelper(tbl, " range stocks id "))
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (ORCL, 120) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (JPM, 300) ")
+	// records come back ordered by value
+	res := selectHelper(tbl, " select ticker from stocks where bid between 100 and 200 ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 3, 1)
+	if res.records[0].getValue(0) != "MSFT" || res.records[1].getValue(0) != "ORCL" || res.records[2].getValue(0) != "IBM" {
+		t.Errorf("table range error: unexpected order of records")
+	}
+	// update and delete keep index in sync
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 250 where id = 1 "), 1)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 100 and 200 "), 2, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 200 and 300 "), 2, 3)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where bid between 290 and 310 "), 1)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 0 and 1000 "), 3, 3)
+	truncateHelper(tbl, " truncate table stocks ")
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where bid between 0 and 1000 "), 0, 3)
+}
+
+func TestTableSqlSelectGroupBy(t *testing.T) {
+	tbl := newTable("stocks")
+	validateSqlSelect(t, selectHelper(tbl, " select count(*) from stocks "), 1, 1)
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (IBM, NYSE) ")
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (MSFT, NASDAQ) ")
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (JPM, NYSE) ")
+	insertHelper(tbl, " insert into stocks (ticker) values (ORCL) ")
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (GS, NYSE) ")
+	deleteHelper(tbl, " delete from stocks where id = 4 ")
+	res := selectHelper(tbl, " select exchange, count(*) from stocks group by exchange ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 3, 2)
+	expected := [][]string{{"NYSE", "2"}, {"NASDAQ", "1"}, {"", "1"}}
+	for idx, values := range expected {
+		rec := res.records[idx]
+		if rec.getValue(0) != values[0] || rec.getValue(1) != values[1] {
+			t.Errorf("table group by error: expected %v but got %v", values, rec.values)
+		}
+	}
+	if res.columns[1].name != "count" {
+		t.Errorf("table group by error: expected count column but got %s", res.columns[1].name)
+	}
+	// count only
+	res = selectHelper(tbl, " select count(*) from stocks ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 1, 1)
+	if res.records[0].getValue(0) != "4" {
+		t.Errorf("table count error: expected 4 but got %s", res.records[0].getValue(0))
+	}
+	// group by without count and limit applied to groups
+	validateSqlSelect(t, selectHelper(tbl, " select exchange from stocks group by exchange limit 2 "), 2, 1)
+	validateSqlSelect(t, selectHelper(tbl, " select count(*), exchange from stocks group by exchange, ticker "), 4, 2)
+}
+
+func TestTableSqlSelectAs(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, price, exchange) values (IBM, 120, NYSE) ")
+	insertHelper(tbl, " insert into stocks (ticker, price, exchange) values (MSFT, 40, NASDAQ) ")
+	res := selectHelper(tbl, " select price as p, ticker as symbol from stocks where id = 1 ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 1, 2)
+	if res.columns[0].name != "p" || res.columns[1].name != "symbol" {
+		t.Errorf("table select error: expected aliased columns but got %s %s", res.columns[0].name, res.columns[1].name)
+	}
+	if res.records[0].getValue(0) != "40" || res.records[0].getValue(1) != "MSFT" {
+		t.Errorf("table select error: unexpected values %v", res.records[0].values)
+	}
+	// table columns are not renamed
+	if tbl.getColumn("price") == nil || tbl.getColumn("p") != nil {
+		t.Errorf("table select error: alias should not alter table columns")
+	}
+	// aggregates
+	res = selectHelper(tbl, " select exchange as market, count(*) as total from stocks group by exchange ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 2, 2)
+	if res.columns[0].name != "market" || res.columns[1].name != "total" {
+		t.Errorf("table group by error: expected aliased columns but got %s %s", res.columns[0].name, res.columns[1].name)
+	}
+}
+
+func TestTableSqlSelectConcat(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (IBM, NYSE) ")
+	insertHelper(tbl, " insert into stocks (ticker) values (MSFT) ")
+	res := selectHelper(tbl, " select ticker || ' @ ' || exchange as label, ticker from stocks ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 2, 2)
+	if res.columns[0].name != "label" {
+		t.Errorf("table select error: expected label column but got %s", res.columns[0].name)
+	}
+	// missing values are empty
+	if res.records[0].getValue(0) != "IBM @ NYSE" || res.records[1].getValue(0) != "MSFT @ " {
+		t.Errorf("table select error: unexpected concatenation %v %v", res.records[0].values, res.records[1].values)
+	}
+	// computed column is not added to the table, non existing operand column is not created
+	res = selectHelper(tbl, " select ticker || sector from stocks where id = 0 ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 1, 1)
+	if res.columns[0].name != "ticker || sector" || res.records[0].getValue(0) != "IBM" {
+		t.Errorf("table select error: unexpected concatenation %s %v", res.columns[0].name, res.records[0].values)
+	}
+	if tbl.getColumn("label") != nil || tbl.getColumn("sector") != nil || tbl.getColumn("ticker || sector") != nil {
+		t.Errorf("table select error: concatenation should not alter table columns")
+	}
+	// distinct compares computed values
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (IBM, NYSE) ")
+	validateSqlSelect(t, selectHelper(tbl, " select distinct ticker || exchange from stocks "), 2, 1)
+}
+
+func TestTableSqlSelectStarColumns(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, exchange) values (IBM, NYSE) ")
+	res := selectHelper(tbl, " select *, ticker || ' @ ' || exchange as label, ticker as symbol from stocks ").(*sqlSelectResponse)
+	// id, ticker, exchange followed by computed columns in order
+	validateSqlSelect(t, res, 1, 5)
+	if res.columns[3].name != "label" || res.columns[4].name != "symbol" {
+		t.Errorf("table select error: expected label and symbol but got %s %s", res.columns[3].name, res.columns[4].name)
+	}
+	if res.records[0].getValue(1) != "IBM" || res.records[0].getValue(3) != "IBM @ NYSE" || res.records[0].getValue(4) != "IBM" {
+		t.Errorf("table select error: unexpected values %v", res.records[0].values)
+	}
+	// columns selected after * are not part of *
+	res = selectHelper(tbl, " select *, sector from stocks ").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 1, 4)
+	// computed names can not collide with table columns
+	validateErrorCode(t, selectHelper(tbl, " select *, exchange || ticker as ticker from stocks "), errorCodeInvalidColumn)
+	validateErrorCode(t, selectHelper(tbl, " select *, exchange from stocks "), errorCodeInvalidColumn)
+}
+
+func TestTableSqlSelectDistinct(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, sector, exchange) values (IBM, TECH, NYSE) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector, exchange) values (MSFT, TECH, NASDAQ) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector, exchange) values (ORCL, TECH, NASDAQ) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector, exchange) values (JPM, FIN, NYSE) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector, exchange) values (GS, FIN, NYSE) ")
+	deleteHelper(tbl, " delete from stocks where id = 0 ")
+	validateSqlSelect(t, selectHelper(tbl, " select distinct exchange from stocks "), 2, 1)
+	validateSqlSelect(t, selectHelper(tbl, " select distinct sector, exchange from stocks "), 2, 2)
+	validateSqlSelect(t, selectHelper(tbl, " select distinct ticker from stocks "), 4, 1)
+	// limit and offset apply to distinct rows
+	res := selectHelper(tbl, " select distinct exchange from stocks offset 1 ").(*sqlSelectResponse)
+	if len(res.records) != 1 || res.records[0].getValue(0) != "NYSE" {
+		t.Errorf("unexpected records for distinct offset 1")
+	}
+	// values are compared across columns unambiguously
+	insertHelper(tbl, " insert into stocks (ticker, sector) values (AB, C) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector) values (A, BC) ")
+	validateSqlSelect(t, selectHelper(tbl, " select distinct ticker, sector from stocks "), 6, 2)
+}
+
+// RANGE
+
+func rangeHelper(t *table, sqlRange string) response {
+	pc := newTokens()
+	lex(sqlRange, pc)
+	req := parse(pc).(*sqlRangeRequest)
+	return t.sqlRange(req)
+}
+
 // UPDATE
 
 func updateHelper(t *table, sqlUpdate string) response {
@@ -248,8 +873,8 @@ func TestTableSqlUpdate(t *testing.T) {
 	validateSqlUpdate(t, res, 1)
 	res = selectHelper(tbl, " select * from stocks where ticker = JPM ")
 	validateSqlSelect(t, res, 1, 5)
-	//res = selectHelper(tbl, " select * from stocks where ticker = IBM ")
-	//validateSqlSelect(t, res, 0, 5)
+	res = selectHelper(tbl, " select * from stocks where ticker = IBM ")
+	validateSqlSelect(t, res, 0, 5)
 	// create tag for sector
 	res = tagHelper(tbl, "tag stocks sector")
 	validateOkResponse(t, res)
@@ -270,6 +895,344 @@ func TestTableSqlUpdate(t *testing.T) {
 
 }
 
+func TestTableSqlUpdateVersion(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 12) ")
+	res := updateHelper(tbl, " update stocks set bid = 13 where id = 0 and version = 0 ")
+	validateSqlUpdate(t, res, 1)
+	if x := res.(*sqlActionDataResponse); !x.useVersion || x.version != 1 {
+		t.Errorf("table update error: expected version 1 but got %d", x.version)
+	}
+	// stale version does not update
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 14 where id = 0 and version = 0 "), 0)
+	sel := selectHelper(tbl, " select bid from stocks ").(*sqlSelectResponse)
+	if sel.records[0].getValue(0) != "13" {
+		t.Errorf("table update error: stale update changed bid to %s", sel.records[0].getValue(0))
+	}
+	// every update increments version
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 14 "), 1)
+	res = updateHelper(tbl, " update stocks set bid = 15 where id = 0 and version = 2 ")
+	validateSqlUpdate(t, res, 1)
+	if x := res.(*sqlActionDataResponse); x.version != 3 {
+		t.Errorf("table update error: expected version 3 but got %d", x.version)
+	}
+}
+
+func TestTableSqlUpdateIncrement(t *testing.T) {
+	tbl := newTable("stats")
+	validateOkResponse(t, keyHelper(tbl, "key stats page"))
+	insertHelper(tbl, " insert into stats (page, hits, ratio) values (home, 10, 0.5) ")
+	insertHelper(tbl, " insert into stats (page, hits, ratio) values (about, 20, 1) ")
+	validateSqlUpdate(t, updateHelper(tbl, " update stats set hits = hits + 1, ratio = ratio - 0.25 "), 2)
+	sel := selectHelper(tbl, " select hits, ratio from stats ").(*sqlSelectResponse)
+	if sel.records[0].getValue(0) != "11" || sel.records[0].getValue(1) != "0.25" || sel.records[1].getValue(0) != "21" || sel.records[1].getValue(1) != "0.75" {
+		t.Errorf("table update error: unexpected incremented values")
+	}
+	// value of another column
+	validateSqlUpdate(t, updateHelper(tbl, " update stats set total = hits + 100 where page = home "), 1)
+	sel = selectHelper(tbl, " select total from stats where page = home ").(*sqlSelectResponse)
+	if sel.records[0].getValue(0) != "111" {
+		t.Errorf("table update error: expected total 111 but got %s", sel.records[0].getValue(0))
+	}
+	// non numeric value fails and no record is updated
+	updateHelper(tbl, " update stats set hits = abc where page = about ")
+	validateErrorCode(t, updateHelper(tbl, " update stats set hits = hits + 1 "), errorCodeInvalidValue)
+	sel = selectHelper(tbl, " select hits from stats where page = home ").(*sqlSelectResponse)
+	if sel.records[0].getValue(0) != "11" {
+		t.Errorf("table update error: failed increment changed hits to %s", sel.records[0].getValue(0))
+	}
+	validateErrorCode(t, updateHelper(tbl, " update stats set hits = nothing + 1 "), errorCodeInvalidColumn)
+	validateErrorCode(t, updateHelper(tbl, " update stats set page = page + 1 "), errorCodeInvalidValue)
+}
+
+func TestTableSqlSubscribeColumns(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, price, qty, note) values (IBM, 12, 100, a) ")
+	res, sender := subscribeHelper(tbl, "subscribe price, qty from stocks")
+	validateSqlSubscribeResponse(t, res)
+	senders := []*responseSender{sender}
+	validateSqlActionAddResponse(t, sender, res.(*sqlSubscribeResponse).pubsubid, 1)
+	validateActionComplete(t, senders)
+	// unwatched column
+	updateHelper(tbl, " update stocks set note = b ")
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T for unwatched column update", res)
+	}
+	// watched column
+	updateHelper(tbl, " update stocks set note = c, qty = 200 ")
+	validateActionUpdate(t, senders)
+	// inserts and deletes are always published
+	insertHelper(tbl, " insert into stocks (ticker) values (MSFT) ")
+	if _, ok := sender.tryRecv().(*sqlActionInsertResponse); !ok {
+		t.Errorf("table subscribe error: expected insert")
+	}
+	deleteHelper(tbl, " delete from stocks where id = 1 ")
+	validateActionDelete(t, senders)
+}
+
+func TestTableSqlSubscribeDeltaThreshold(t *testing.T) {
+	tbl := newTable("ticks")
+	insertHelper(tbl, " insert into ticks (ticker, price, note) values (IBM, 12, a) ")
+	res, sender := subscribeHelper(tbl, "subscribe * from ticks having delta(price) > 0.5")
+	validateSqlSubscribeResponse(t, res)
+	senders := []*responseSender{sender}
+	validateSqlActionAddResponse(t, sender, res.(*sqlSubscribeResponse).pubsubid, 1)
+	validateActionComplete(t, senders)
+	// first update is always published
+	updateHelper(tbl, " update ticks set price = 12.1 ")
+	validateActionUpdate(t, senders)
+	// changes below threshold are suppressed and do not move the last published value
+	updateHelper(tbl, " update ticks set price = 12.4 ")
+	updateHelper(tbl, " update ticks set price = 12.5 ")
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T for change below threshold", res)
+	}
+	updateHelper(tbl, " update ticks set price = 12.7 ")
+	validateActionUpdate(t, senders)
+	// columns without threshold and non numeric values are always published
+	updateHelper(tbl, " update ticks set note = b ")
+	validateActionUpdate(t, senders)
+	updateHelper(tbl, " update ticks set price = n/a ")
+	validateActionUpdate(t, senders)
+}
+
+func TestTableUnknownColumnFilter(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 12) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (MSFT, 13) ")
+	res := selectHelper(tbl, " select * from stocks where tickr = IBM ")
+	validateErrorCode(t, res, errorCodeInvalidColumn)
+	if x, ok := res.(*errorResponse); ok && x.msg != "unknown column: tickr" {
+		t.Errorf("table select error: unexpected message %s", x.msg)
+	}
+	validateErrorCode(t, updateHelper(tbl, " update stocks set bid = 1 where tickr = IBM "), errorCodeInvalidColumn)
+	// lenient columns are empty in every record
+	defer func(strict bool) { config.WHERE_STRICT_COLUMNS = strict }(config.WHERE_STRICT_COLUMNS)
+	config.WHERE_STRICT_COLUMNS = false
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where tickr = IBM "), 0, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not tickr = IBM "), 2, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where tickr = IBM or ticker = MSFT "), 1, 3)
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 1 where tickr = IBM "), 0)
+}
+
+func TestTableColumnLimit(t *testing.T) {
+	defer func(max int) { config.TABLE_MAX_COLUMNS = max }(config.TABLE_MAX_COLUMNS)
+	config.TABLE_MAX_COLUMNS = 3
+	tbl := newTable("stocks")
+	validateSqlInsertResponse(t, insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 12) "))
+	// existing columns are still usable
+	validateSqlInsertResponse(t, insertHelper(tbl, " insert into stocks (bid, ticker) values (13, MSFT) "))
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set bid = 14 "), 2)
+	res := insertHelper(tbl, " insert into stocks (ticker, ask) values (JPM, 15) ")
+	validateErrorCode(t, res, errorCodeLimitExceeded)
+	if x, ok := res.(*errorResponse); ok && !strings.Contains(x.msg, "maximum number of columns") {
+		t.Errorf("table error: limit is not named in %s", x.msg)
+	}
+	validateErrorCode(t, updateHelper(tbl, " update stocks set ask = 15 "), errorCodeLimitExceeded)
+	validateErrorCode(t, selectHelper(tbl, " select ask from stocks "), errorCodeLimitExceeded)
+	validateErrorCode(t, tagHelper(tbl, " tag stocks sector "), errorCodeLimitExceeded)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 2, 3)
+}
+
+func TestTableErrorCodes(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 12) ")
+	validateErrorCode(t, insertHelper(tbl, " insert into stocks (ticker) values (IBM) "), errorCodeDuplicateKey)
+	validateErrorCode(t, updateHelper(tbl, " update stocks set ask = 13 where bid = 12 "), errorCodeInvalidFilter)
+	validateErrorCode(t, selectHelper(tbl, " select * from stocks where sector = TECH "), errorCodeInvalidColumn)
+}
+
+func validateErrorCode(t *testing.T, res response, code errorCode) {
+	x, ok := res.(*errorResponse)
+	if !ok {
+		t.Errorf("expected error response with code %s but got %T", code, res)
+		return
+	}
+	if x.code != code {
+		t.Errorf("expected error code %s but got %s", code, x.code)
+	}
+	validateResponseJSON(t, res)
+}
+
+func validateOverflow(t *testing.T, res response, dropped bool) {
+	x, ok := res.(*subscriptionOverflowResponse)
+	if !ok {
+		t.Errorf("table subscribe error: expected subscription overflow but got %T", res)
+		return
+	}
+	if x.dropped != dropped {
+		t.Errorf("table subscribe error: expected dropped %t", dropped)
+	}
+	validateResponseJSON(t, res)
+}
+
+func TestTableSubscriptionOverflow(t *testing.T) {
+	defer func(size int, policy string) {
+		config.SUBSCRIPTION_QUEUE_SIZE = size
+		config.SUBSCRIPTION_OVERFLOW_POLICY = policy
+	}(config.SUBSCRIPTION_QUEUE_SIZE, config.SUBSCRIPTION_OVERFLOW_POLICY)
+	config.SUBSCRIPTION_QUEUE_SIZE = 2
+	// slow subscriber is dropped
+	config.SUBSCRIPTION_OVERFLOW_POLICY = overflowPolicyDrop
+	tbl := newTable("stocks")
+	_, sender := subscribeHelper(tbl, " subscribe * from stocks ")
+	sender.tryRecv() // action complete
+	for i := 0; i < 3; i++ {
+		insertHelper(tbl, " insert into stocks (ticker) values (IBM) ")
+	}
+	sender.tryRecv()
+	sender.tryRecv()
+	validateOverflow(t, sender.tryRecv(), true)
+	insertHelper(tbl, " insert into stocks (ticker) values (IBM) ")
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T for dropped subscription", res)
+	}
+	// events are skipped until slow subscriber catches up
+	config.SUBSCRIPTION_OVERFLOW_POLICY = overflowPolicyCoalesce
+	tbl = newTable("stocks")
+	_, sender = subscribeHelper(tbl, " subscribe * from stocks ")
+	sender.tryRecv() // action complete
+	for i := 0; i < 4; i++ {
+		insertHelper(tbl, " insert into stocks (ticker) values (IBM) ")
+	}
+	sender.tryRecv()
+	sender.tryRecv()
+	validateOverflow(t, sender.tryRecv(), false)
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T for skipped event", res)
+	}
+	insertHelper(tbl, " insert into stocks (ticker) values (IBM) ")
+	if _, ok := sender.tryRecv().(*sqlActionInsertResponse); !ok {
+		t.Errorf("table subscribe error: expected insert after subscriber caught up")
+	}
+}
+
+func transactionHelper(t *table, statements ...string) response {
+	tx := new(sqlTransactionRequest)
+	for _, sql := range statements {
+		pc := newTokens()
+		lex(sql, pc)
+		tx.add(parse(pc))
+	}
+	return t.sqlTransaction(tx)
+}
+
+func TestTableSqlTransaction(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 12, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (JPM, 40, FIN) ")
+	res, sender := subscribeHelper(tbl, " subscribe * from stocks ")
+	validateSqlSubscribeResponse(t, res)
+	senders := []*responseSender{sender}
+	validateSqlActionAddResponse(t, sender, res.(*sqlSubscribeResponse).pubsubid, 2)
+	validateActionComplete(t, senders)
+	// failed statement undoes previous statements
+	res = transactionHelper(tbl,
+		" insert into stocks (ticker, bid, sector) values (MSFT, 13, TECH) ",
+		" update stocks set bid = 14, sector = FIN where ticker = IBM ",
+		" delete from stocks where ticker = JPM ",
+		" insert into stocks (ticker) values (MSFT) ")
+	validateErrorCode(t, res, errorCodeDuplicateKey)
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table transaction error: unexpected %T published for rolled back transaction", res)
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 2, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = JPM "), 1, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = MSFT "), 0, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where sector = TECH "), 1, 4)
+	sel := selectHelper(tbl, " select bid from stocks where ticker = IBM ").(*sqlSelectResponse)
+	if sel.records[0].getValue(0) != "12" {
+		t.Errorf("table transaction error: expected bid 12 but got %s", sel.records[0].getValue(0))
+	}
+	// committed transaction is published at once
+	res = transactionHelper(tbl,
+		" insert into stocks (ticker, bid, sector) values (MSFT, 13, TECH) ",
+		" update stocks set bid = 14 where ticker = IBM ",
+		" delete from stocks where ticker = JPM ")
+	validateOkResponse(t, res)
+	if _, ok := sender.tryRecv().(*sqlActionInsertResponse); !ok {
+		t.Errorf("table transaction error: expected insert")
+	}
+	validateActionUpdate(t, senders)
+	validateActionDelete(t, senders)
+	// id of rolled back insert is reused
+	sel = selectHelper(tbl, " select id from stocks where ticker = MSFT ").(*sqlSelectResponse)
+	if sel.records[0].getValue(0) != "2" {
+		t.Errorf("table transaction error: expected id 2 but got %s", sel.records[0].getValue(0))
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where sector = TECH "), 2, 4)
+}
+
+func TestTableSqlUpdateMultipleColumns(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 12, sec1) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (MSFT, 13, sec1) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (ORCL, 14, sec1) ")
+	// move the head of sec1 tag list and change the key in the same statement
+	res := updateHelper(tbl, " update stocks set ticker = JPM, bid = 100, sector = sec2 where ticker = IBM ")
+	validateSqlUpdate(t, res, 1)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = IBM "), 0, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where ticker = JPM "), 1, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where sector = sec1 "), 2, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where sector = sec2 "), 1, 4)
+	if n := tbl.getTagedColumnValuesCount("sector", "sec1"); n != 2 {
+		t.Errorf("expected 2 tagged records for sec1 but got %d", n)
+	}
+	rec := tbl.getRecord(0)
+	validateRecordValue(t, rec, tbl.getColumn("bid").ordinal, "100")
+	// update all records in a tag
+	res = updateHelper(tbl, " update stocks set bid = 1, sector = sec2 where sector = sec1 ")
+	validateSqlUpdate(t, res, 2)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where sector = sec1 "), 0, 4)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where sector = sec2 "), 3, 4)
+}
+
+// UPSERT
+
+func upsertHelper(t *table, sqlUpsert string) response {
+	pc := newTokens()
+	lex(sqlUpsert, pc)
+	req := parse(pc).(*sqlUpsertRequest)
+	return t.sqlUpsert(req)
+}
+
+func validateSqlUpsertAction(t *testing.T, res response, action string) {
+	switch res.(type) {
+	case *sqlActionDataResponse:
+		x := res.(*sqlActionDataResponse)
+		if x.action != action {
+			t.Errorf("table upsert error: expected action %s but got %s", action, x.action)
+		}
+	default:
+		t.Errorf("table upsert error: invalid response type expected sqlActionDataResponse")
+	}
+}
+
+func TestTableSqlUpsert(t *testing.T) {
+	tbl := newTable("stocks")
+	// key column is required
+	validateErrorResponse(t, upsertHelper(tbl, " upsert into stocks (ticker, bid) values (IBM, 12) "))
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateErrorResponse(t, upsertHelper(tbl, " upsert into stocks (bid) values (12) "))
+	// insert
+	validateSqlUpsertAction(t, upsertHelper(tbl, " upsert into stocks (ticker, bid) values (IBM, 12) "), "insert")
+	validateSqlUpsertAction(t, upsertHelper(tbl, " upsert into stocks (ticker, bid) values (MSFT, 13) "), "insert")
+	// update
+	validateSqlUpsertAction(t, upsertHelper(tbl, " upsert into stocks (ticker, bid) values (IBM, 14) "), "update")
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 2, 3)
+	validateRecordValue(t, tbl.getRecord(0), tbl.getColumn("bid").ordinal, "14")
+	// key values that match different records
+	validateOkResponse(t, keyHelper(tbl, "key stocks bid"))
+	validateErrorResponse(t, upsertHelper(tbl, " upsert into stocks (ticker, bid) values (IBM, 13) "))
+	validateRecordValue(t, tbl.getRecord(0), tbl.getColumn("bid").ordinal, "14")
+}
+
 // DELETE
 
 func deleteHelper(t *table, sqlDelete string) response {
@@ -317,6 +1280,52 @@ func TestTableSqlDelete(t *testing.T) {
 	validateSqlSelect(t, res, 0, 4)
 }
 
+// Deletes records by id from tables of different sizes.
+// Id filter resolves to a direct records slice lookup
+// so the time per delete does not grow with the table size.
+func BenchmarkTableSqlDeleteById(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			tbl := newTable("stocks")
+			pc := newTokens()
+			lex(" insert into stocks (ticker, bid) values (IBM, 12) ", pc)
+			insert := parse(pc).(*sqlInsertRequest)
+			for i := 0; i < size+b.N; i++ {
+				tbl.sqlInsert(insert)
+			}
+			requests := make([]*sqlDeleteRequest, b.N)
+			for i := range requests {
+				requests[i] = new(sqlDeleteRequest)
+				requests[i].filter.addFilter("id", strconv.Itoa(size+i))
+			}
+			b.ResetTimer()
+			for _, req := range requests {
+				tbl.sqlDelete(req)
+			}
+		})
+	}
+}
+
+func BenchmarkTableSqlSelectOrderByDesc(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		tbl := newTable("stocks")
+		for i := 0; i < size; i++ {
+			insertHelper(tbl, " insert into stocks (ticker, seq) values (IBM, "+strconv.Itoa(i)+") ")
+		}
+		// seq mirrors id so both queries return the same records
+		for _, col := range []string{"id", "seq"} {
+			pc := newTokens()
+			lex(" select * from stocks order by "+col+" desc limit 50 ", pc)
+			req := parse(pc).(*sqlSelectRequest)
+			b.Run(col+"/"+strconv.Itoa(size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tbl.sqlSelect(req)
+				}
+			})
+		}
+	}
+}
+
 // KEY
 
 func keyHelper(t *table, sqlKey string) response {
@@ -484,6 +1493,223 @@ func TestTableSqlTag(t *testing.T) {
 	validateSqlSelect(t, res, 0, 5)
 }
 
+func TestTableSqlTagMultiValue(t *testing.T) {
+	tbl := newTable("stocks")
+	insertHelper(tbl, " insert into stocks (ticker, labels) values (IBM, 'urgent,tech') ")
+	// existing values are tagged
+	validateOkResponse(t, tagHelper(tbl, "tag stocks labels multi"))
+	insertHelper(tbl, " insert into stocks (ticker, labels) values (MSFT, 'tech urgent') ")
+	insertHelper(tbl, " insert into stocks (ticker, labels) values (ORCL, 'tech, tech') ")
+	insertHelper(tbl, " insert into stocks (ticker) values (JPM) ")
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where labels = urgent "), 2, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where labels = tech "), 3, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where not labels = urgent "), 2, 3)
+	if tbl.getTagedColumnValuesCount("labels", "tech") != 3 {
+		t.Errorf("invalid taged column values")
+	}
+	// removing the head of tag list retags the remaining records
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where id = 0 "), 1)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where labels = urgent "), 1, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where labels = tech "), 2, 3)
+	// update retags record
+	validateSqlUpdate(t, updateHelper(tbl, " update stocks set labels = 'urgent' where id = 2 "), 1)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where labels = urgent "), 2, 3)
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks where labels = tech "), 1, 3)
+	validateSqlDelete(t, deleteHelper(tbl, " delete from stocks where labels = urgent "), 2)
+	if tbl.getTagedColumnValuesCount("labels", "urgent") != 0 || tbl.getTagedColumnValuesCount("labels", "tech") != 0 {
+		t.Errorf("invalid taged column values")
+	}
+	validateSqlSelect(t, selectHelper(tbl, " select * from stocks "), 1, 3)
+}
+
+func TestTableActionAddOnMultiValueTagUpdate(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks labels multi"))
+	insertHelper(tbl, " insert into stocks (ticker, labels) values (IBM, tech) ")
+	res, sender := subscribeHelper(tbl, "subscribe * from stocks where labels = urgent")
+	validateSqlSubscribeResponse(t, res)
+	senders := []*responseSender{sender}
+	validateActionComplete(t, senders)
+	// value added
+	updateHelper(tbl, "update stocks set labels = 'tech,urgent' where id = 0")
+	validateActionAdd(t, senders)
+	// other values changed
+	updateHelper(tbl, "update stocks set labels = 'urgent,new' where id = 0")
+	validateActionUpdate(t, senders)
+	// value removed
+	updateHelper(tbl, "update stocks set labels = 'new' where id = 0")
+	validateActionRemove(t, senders)
+}
+
+func TestTableSubscribeFilterMembership(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	res, sender := subscribeHelper(tbl, "subscribe * from stocks where sector = TECH")
+	validateSqlSubscribeResponse(t, res)
+	senders := []*responseSender{sender}
+	validateActionComplete(t, senders)
+	insertHelper(tbl, " insert into stocks (ticker, sector, bid) values (JPM, FIN, 40) ")
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T for record outside of filter", res)
+	}
+	// enters the filtered set
+	updateHelper(tbl, " update stocks set sector = TECH where id = 0 ")
+	validateActionAdd(t, senders)
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T after add", res)
+	}
+	// still matches
+	updateHelper(tbl, " update stocks set bid = 41 where id = 0 ")
+	validateActionUpdate(t, senders)
+	// leaves the filtered set
+	updateHelper(tbl, " update stocks set sector = FIN, bid = 42 where id = 0 ")
+	validateActionRemove(t, senders)
+	updateHelper(tbl, " update stocks set bid = 43 where id = 0 ")
+	deleteHelper(tbl, " delete from stocks where id = 0 ")
+	if res := sender.tryRecv(); res != nil {
+		t.Errorf("table subscribe error: unexpected %T for record that left filter", res)
+	}
+}
+
+func TestTableSubscribeOrFilter(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, sector, bid) values (IBM, TECH, 10) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector, bid) values (JPM, FIN, 20) ")
+	// non indexed columns can be combined with indexed ones
+	res, sender := subscribeHelper(tbl, "subscribe * from stocks where sector = FIN or bid = 30")
+	sub := validateSqlSubscribeResponse(t, res)
+	senders := []*responseSender{sender}
+	validateSqlActionAddResponse(t, sender, sub.pubsubid, 1)
+	validateActionComplete(t, senders)
+	// inserts and deletes are published only for matching records
+	insertHelper(tbl, " insert into stocks (ticker, sector, bid) values (MSFT, TECH, 30) ")
+	validateActionInsert(t, senders)
+	insertHelper(tbl, " insert into stocks (ticker, sector, bid) values (ORCL, TECH, 40) ")
+	validateNoResponse(t, sender)
+	deleteHelper(tbl, " delete from stocks where id = 3 ")
+	validateNoResponse(t, sender)
+	// enters, stays in and leaves the filtered set
+	updateHelper(tbl, " update stocks set bid = 30 where id = 0 ")
+	validateActionAdd(t, senders)
+	updateHelper(tbl, " update stocks set sector = FIN where id = 0 ")
+	validateActionUpdate(t, senders)
+	updateHelper(tbl, " update stocks set sector = TECH, bid = 11 where id = 0 ")
+	validateActionRemove(t, senders)
+	updateHelper(tbl, " update stocks set bid = 12 where id = 0 ")
+	validateNoResponse(t, sender)
+	deleteHelper(tbl, " delete from stocks where id = 1 ")
+	validateActionDelete(t, senders)
+	// truncate publishes delete of matching records only
+	truncateHelper(tbl, " truncate table stocks ")
+	res = sender.tryRecv()
+	if del, ok := res.(*sqlActionDeleteResponse); !ok || len(del.records) != 1 {
+		t.Errorf("table subscribe error: expected delete of single record but got %T", res)
+	}
+	// invalid filters
+	res, _ = subscribeHelper(tbl, "subscribe * from stocks where sector = FIN or ask = 1")
+	validateErrorCode(t, res, errorCodeInvalidColumn)
+	res, _ = subscribeHelper(tbl, "subscribe * from stocks where sector = FIN or bid between 1 and 2")
+	validateErrorCode(t, res, errorCodeInvalidFilter)
+}
+
+// Receives pubsub response of expected type and validates its sequence number.
+func validateSeq(t *testing.T, sender *responseSender, expected response, seq uint64) {
+	res := sender.tryRecv()
+	if reflect.TypeOf(res) != reflect.TypeOf(expected) {
+		t.Errorf("table seq error: expected %T but got %T", expected, res)
+		return
+	}
+	validateResponseJSON(t, res)
+	if got := res.(sequencedResponse).getSeq(); got != seq {
+		t.Errorf("table seq error: expected %T seq %d but got %d", res, seq, got)
+	}
+}
+
+func TestTableSubscribeSeq(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	_, sender := subscribeHelper(tbl, "subscribe * from stocks")
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 0)
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (IBM, 12) ")
+	validateSeq(t, sender, new(sqlActionInsertResponse), 1)
+	updateHelper(tbl, " update stocks set bid = 13 where id = 0 ")
+	validateSeq(t, sender, new(sqlActionUpdateResponse), 2)
+	insertHelper(tbl, " insert into stocks (ticker, bid) values (JPM, 40) ")
+	validateSeq(t, sender, new(sqlActionInsertResponse), 3)
+	deleteHelper(tbl, " delete from stocks where id = 1 ")
+	validateSeq(t, sender, new(sqlActionDeleteResponse), 4)
+	// rolled back transaction does not consume sequence numbers
+	res := transactionHelper(tbl,
+		" insert into stocks (ticker) values (MSFT) ",
+		" insert into stocks (ticker) values (MSFT) ")
+	validateErrorCode(t, res, errorCodeDuplicateKey)
+	validateNoResponse(t, sender)
+	res = transactionHelper(tbl,
+		" insert into stocks (ticker) values (MSFT) ",
+		" update stocks set bid = 14 where id = 0 ")
+	validateOkResponse(t, res)
+	validateSeq(t, sender, new(sqlActionInsertResponse), 5)
+	validateSeq(t, sender, new(sqlActionUpdateResponse), 6)
+	// initial actions carry sequence number of the last change
+	_, sender = subscribeHelper(tbl, "subscribe * from stocks")
+	validateSeq(t, sender, new(sqlActionAddResponse), 6)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 6)
+	truncateHelper(tbl, " truncate table stocks ")
+	validateSeq(t, sender, new(sqlActionDeleteResponse), 7)
+}
+
+func TestTableSubscribeSince(t *testing.T) {
+	defer func(size int) { config.TABLE_EVENT_BUFFER_SIZE = size }(config.TABLE_EVENT_BUFFER_SIZE)
+	config.TABLE_EVENT_BUFFER_SIZE = 3
+	tbl := newTable("stocks")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, sector) values (IBM, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, sector) values (JPM, FIN) ")
+	updateHelper(tbl, " update stocks set sector = TECH where id = 1 ")
+	// changes since are replayed as the subscriber would have seen them
+	res, sender := subscribeHelper(tbl, "subscribe * from stocks where sector = TECH since 1")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionAddResponse), 3)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 3)
+	validateNoResponse(t, sender)
+	res, sender = subscribeHelper(tbl, "subscribe * from stocks where sector = FIN since 1")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionInsertResponse), 2)
+	validateSeq(t, sender, new(sqlActionRemoveResponse), 3)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 3)
+	// nothing was missed
+	res, sender = subscribeHelper(tbl, "subscribe * from stocks since 3")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 3)
+	validateNoResponse(t, sender)
+	// oldest changes are evicted
+	deleteHelper(tbl, " delete from stocks where id = 0 ")
+	res, sender = subscribeHelper(tbl, "subscribe * from stocks since 0")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionResyncResponse), 4)
+	validateSeq(t, sender, new(sqlActionAddResponse), 4)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 4)
+	res, sender = subscribeHelper(tbl, "subscribe * from stocks since 1")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionInsertResponse), 2)
+	validateSeq(t, sender, new(sqlActionUpdateResponse), 3)
+	validateSeq(t, sender, new(sqlActionDeleteResponse), 4)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 4)
+	// sequence number the table has not reached yet
+	res, sender = subscribeHelper(tbl, "subscribe * from stocks since 100")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionResyncResponse), 4)
+	validateSeq(t, sender, new(sqlActionAddResponse), 4)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 4)
+	// truncate can not be replayed
+	truncateHelper(tbl, " truncate table stocks ")
+	res, sender = subscribeHelper(tbl, "subscribe * from stocks since 4")
+	validateSqlSubscribeResponse(t, res)
+	validateSeq(t, sender, new(sqlActionResyncResponse), 5)
+	validateSeq(t, sender, new(sqlActionCompleteResponse), 5)
+}
+
 func TestTableSqlTagBugCreateTagCrash(t *testing.T) {
 	var res response
 	tbl := newTable("stocks")
@@ -567,6 +1793,18 @@ func validateSqlActionAddResponse(t *testing.T, sender *responseSender, pubsubid
 	}
 }
 
+func validateActionComplete(t *testing.T, senders []*responseSender) {
+	for _, sender := range senders {
+		res := sender.tryRecv()
+		switch res.(type) {
+		case *sqlActionCompleteResponse:
+			validateResponseJSON(t, res)
+		default:
+			t.Errorf("table subscribe error: invalid response type expected sqlActionCompleteResponse but got %T", res)
+		}
+	}
+}
+
 func validateNoResponse(t *testing.T, sender *responseSender) {
 	res := sender.tryRecv()
 	if res != nil {
@@ -591,6 +1829,7 @@ func TestTableSqlSubscribe1(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks ")
 	sub := validateSqlSubscribeResponse(t, res)
 	validateSqlActionAddResponse(t, sender, sub.pubsubid, 1)
+	validateActionComplete(t, []*responseSender{sender})
 
 	//skip
 	res, sender = subscribeHelper(tbl, "subscribe skip * from stocks ")
@@ -601,6 +1840,7 @@ func TestTableSqlSubscribe1(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where ticker = IBM")
 	sub = validateSqlSubscribeResponse(t, res)
 	validateSqlActionAddResponse(t, sender, sub.pubsubid, 1)
+	validateActionComplete(t, []*responseSender{sender})
 
 	//skip
 	res, sender = subscribeHelper(tbl, "subscribe skip * from stocks where ticker = IBM")
@@ -611,6 +1851,7 @@ func TestTableSqlSubscribe1(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where sector = TECH")
 	sub = validateSqlSubscribeResponse(t, res)
 	validateSqlActionAddResponse(t, sender, sub.pubsubid, 1)
+	validateActionComplete(t, []*responseSender{sender})
 
 	//skip
 	res, sender = subscribeHelper(tbl, "subscribe skip * from stocks where sector = TECH")
@@ -621,6 +1862,7 @@ func TestTableSqlSubscribe1(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where id = 0")
 	sub = validateSqlSubscribeResponse(t, res)
 	validateSqlActionAddResponse(t, sender, sub.pubsubid, 1)
+	validateActionComplete(t, []*responseSender{sender})
 
 	//skip
 	res, sender = subscribeHelper(tbl, "subscribe skip * from stocks where id = 0")
@@ -630,11 +1872,11 @@ func TestTableSqlSubscribe1(t *testing.T) {
 	// subscribe to non existing valid key
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where ticker = MSFT")
 	validateSqlSubscribeResponse(t, res)
-	validateNoResponse(t, sender)
+	validateActionComplete(t, []*responseSender{sender})
 	// subscribe to non existing valid tag
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where sector = FIN")
 	validateSqlSubscribeResponse(t, res)
-	validateNoResponse(t, sender)
+	validateActionComplete(t, []*responseSender{sender})
 	// subscribe to non existing invalid key/tag
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where invalidkey = somevalue")
 	validateErrorResponse(t, res)
@@ -689,6 +1931,7 @@ func TestTableActionInsert(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where sector = TECH")
 	senders = append(senders, sender)
 	validateSqlSubscribeResponse(t, res)
+	validateActionComplete(t, senders)
 
 	// insert record
 	res = insertHelper(tbl, " insert into stocks (ticker, bid, ask, sector) values (IBM, 12, 14.56, TECH) ")
@@ -704,12 +1947,12 @@ func TestTableActionInsert(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where ticker = MSFT")
 	senders = append(senders, sender)
 	validateSqlSubscribeResponse(t, res)
-	validateNoResponse(t, sender)
+	validateActionComplete(t, []*responseSender{sender})
 	// subscribe to non existing valid tag
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where sector = FIN")
 	senders = append(senders, sender)
 	validateSqlSubscribeResponse(t, res)
-	validateNoResponse(t, sender)
+	validateActionComplete(t, []*responseSender{sender})
 
 	// insert record
 	res = insertHelper(tbl, " insert into stocks (ticker, bid, ask, sector) values (MSFT, 12, 14.56, FIN) ")
@@ -719,6 +1962,27 @@ func TestTableActionInsert(t *testing.T) {
 	validateActionInsert(t, senders)
 }
 
+func TestTableActionInsertSkip(t *testing.T) {
+	senders := make([]*responseSender, 0)
+	tbl := newTable("stocks")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 12, TECH) ")
+	// skip initial records but receive subsequent events
+	for _, sql := range []string{
+		"subscribe skip * from stocks",
+		"subscribe skip * from stocks where sector = TECH"} {
+		res, sender := subscribeHelper(tbl, sql)
+		validateSqlSubscribeResponse(t, res)
+		validateNoResponse(t, sender)
+		senders = append(senders, sender)
+	}
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (MSFT, 13, TECH) ")
+	validateActionInsert(t, senders)
+	for _, sender := range senders {
+		validateNoResponse(t, sender)
+	}
+}
+
 func clearResponses(senders []*responseSender) {
 	for _, sender := range senders {
 		sender.tryRecv()
@@ -834,6 +2098,7 @@ func TestTableActionUpdate(t *testing.T) {
 	validateSqlSubscribeResponse(t, res)
 
 	validateActionAdd(t, senders)
+	validateActionComplete(t, senders)
 
 	// update
 	updateHelper(tbl, "update stocks set bid = 120, ask = 121 where ticker = IBM")
@@ -865,6 +2130,7 @@ func TestTableActionAddOnKeyUpdate(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where ticker = MSFT")
 	senders = append(senders, sender)
 	validateSqlSubscribeResponse(t, res)
+	validateActionComplete(t, senders)
 
 	updateHelper(tbl, "update stocks set ticker = MSFT where ticker = IBM")
 	validateActionAdd(t, senders)
@@ -895,6 +2161,7 @@ func TestgTableActionAddOnTagUpdate(t *testing.T) {
 	res, sender = subscribeHelper(tbl, "subscribe * from stocks where sector = FIN")
 	senders = append(senders, sender)
 	validateSqlSubscribeResponse(t, res)
+	validateActionComplete(t, senders)
 
 	updateHelper(tbl, "update stocks set ticker = MSFT where sector = FIN")
 	validateActionAdd(t, senders)
@@ -923,6 +2190,7 @@ func TestIssue34(t *testing.T) {
 
 	updateHelper(tbl, "update stocks set sector = NEWVALUE")
 	validateActionAdd(t, senders)
+	validateActionComplete(t, senders)
 	validateActionUpdate(t, senders)
 }
 
@@ -960,6 +2228,7 @@ func TestTableActionDelete1(t *testing.T) {
 	validateSqlSubscribeResponse(t, res)
 
 	validateActionAdd(t, senders)
+	validateActionComplete(t, senders)
 
 	// delete all records
 	deleteHelper(tbl, " delete from stocks ")
@@ -995,6 +2264,7 @@ func TestTableActionDelete2(t *testing.T) {
 	validateSqlSubscribeResponse(t, res)
 
 	validateActionAdd(t, senders)
+	validateActionComplete(t, senders)
 
 	// delete all records
 	deleteHelper(tbl, " delete from stocks ")
@@ -1029,6 +2299,7 @@ func TestTableActionRemove(t *testing.T) {
 	validateSqlSubscribeResponse(t, res)
 
 	validateActionAdd(t, senders)
+	validateActionComplete(t, senders)
 
 	// update recore to generate acion remove
 	res = updateHelper(tbl, " update stocks set ticker = GS, sector = FIN where ticker = IBM ")
@@ -1038,6 +2309,295 @@ func TestTableActionRemove(t *testing.T) {
 
 }
 
+// CREATE
+
+func createHelper(t *table, sqlCreate string) response {
+	pc := newTokens()
+	lex(sqlCreate, pc)
+	req := parse(pc).(*sqlCreateRequest)
+	return t.sqlCreate(req)
+}
+
+func TestTableSqlCreate(t *testing.T) {
+	tbl := newTable("stocks")
+	validateErrorResponse(t, createHelper(tbl, "create table stocks (ticker, ticker int)"))
+	validateErrorResponse(t, createHelper(tbl, "create table stocks (id float)"))
+	validateOkResponse(t, createHelper(tbl, "create table stocks (id int, ticker, bid float, qty int, active bool)"))
+	validateErrorResponse(t, createHelper(tbl, "create table stocks (ask float)"))
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks"), 0, 5)
+	// typed values
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into stocks (ticker, bid, qty, active) values (IBM, 12.5, 100, true)"))
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into stocks (ticker, bid, sector) values (MSFT, -1e3, TECH)"))
+	validateErrorResponse(t, insertHelper(tbl, "insert into stocks (ticker, qty, exchange) values (JPM, 1.5, NYSE)"))
+	validateErrorResponse(t, insertHelper(tbl, "insert into stocks (ticker, bid) values (JPM, abc)"))
+	validateErrorResponse(t, insertHelper(tbl, "insert into stocks (ticker, active) values (JPM, maybe)"))
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks"), 2, 6)
+	// update
+	validateSqlUpdate(t, updateHelper(tbl, "update stocks set qty = 200 where id = 0"), 1)
+	validateErrorResponse(t, updateHelper(tbl, "update stocks set qty = lots where id = 0"))
+	validateRecordValue(t, tbl.getRecord(0), tbl.getColumn("qty").ordinal, "200")
+	// boolean literals are stored in lower case
+	validateSqlUpdate(t, updateHelper(tbl, "update stocks set active = FALSE where id = 0"), 1)
+	validateRecordValue(t, tbl.getRecord(0), tbl.getColumn("active").ordinal, "false")
+	validateOkResponse(t, tagHelper(tbl, "tag stocks active"))
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks where active = false"), 1, 6)
+}
+
+func TestTableSqlCreateIfNotExists(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, createHelper(tbl, "create table if not exists stocks (ticker, bid float)"))
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into stocks (ticker, bid) values (IBM, 12.5)"))
+	// existing table and its records are left as is
+	validateOkResponse(t, createHelper(tbl, "create table if not exists stocks (name, qty int)"))
+	validateErrorCode(t, createHelper(tbl, "create table stocks (name, qty int)"), errorCodeTableExists)
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks"), 1, 3)
+	if tbl.getColumn("qty") != nil {
+		t.Errorf("table create error: existing table should not be changed")
+	}
+}
+
+func TestTableSqlCreateBlob(t *testing.T) {
+	tbl := newTable("files")
+	validateOkResponse(t, createHelper(tbl, "create table files (name, data blob)"))
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into files (name, data) values (a, x'00ff10')"))
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into files (name, data) values (b, 'AP8Q')"))
+	validateErrorResponse(t, insertHelper(tbl, "insert into files (name, data) values (c, 'not base64')"))
+	// blobs are stored and compared in canonical base64 encoding
+	validateRecordValue(t, tbl.getRecord(0), tbl.getColumn("data").ordinal, "AP8Q")
+	validateOkResponse(t, tagHelper(tbl, "tag files data"))
+	validateSqlSelect(t, selectHelper(tbl, "select * from files where data = base64'AP8Q'"), 2, 3)
+	validateSqlSelect(t, selectHelper(tbl, "select * from files where data = x'00FF10'"), 2, 3)
+	validateSqlUpdate(t, updateHelper(tbl, "update files set data = x'' where id = 1"), 1)
+	validateSqlSelect(t, selectHelper(tbl, "select * from files where data = x'00ff10'"), 1, 3)
+}
+
+func TestTableSqlSelectOrderByUuid(t *testing.T) {
+	tbl := newTable("users")
+	validateOkResponse(t, createHelper(tbl, "create table users (name) with id = uuid"))
+	for i := 0; i < 20; i++ {
+		insertHelper(tbl, "insert into users (name) values (user"+strconv.Itoa(i)+")")
+	}
+	for _, desc := range []bool{false, true} {
+		sql := "select id from users order by id"
+		if desc {
+			sql += " desc"
+		}
+		res := selectHelper(tbl, sql).(*sqlSelectResponse)
+		validateSqlSelect(t, res, 20, 1)
+		for i := 1; i < len(res.records); i++ {
+			prev, id := res.records[i-1].getValue(0), res.records[i].getValue(0)
+			if cmp := compareValues(prev, id); desc && cmp < 0 || !desc && cmp > 0 {
+				t.Errorf("table select error: uuid %s is out of order after %s", id, prev)
+			}
+		}
+	}
+}
+
+func TestTableSqlCreateUuid(t *testing.T) {
+	tbl := newTable("users")
+	validateErrorResponse(t, createHelper(tbl, "create table users (id int, name) with id = uuid"))
+	validateOkResponse(t, createHelper(tbl, "create table users (name) with id = uuid"))
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into users (name) values (john)"))
+	validateSqlInsertResponse(t, insertHelper(tbl, "insert into users (name) values (jane)"))
+	john := tbl.getRecord(0).idAsString()
+	jane := tbl.getRecord(1).idAsString()
+	if len(john) != 36 || john[14] != '4' || john[8] != '-' || john == jane {
+		t.Fatalf("table create error: expected unique uuid ids but got %s %s", john, jane)
+	}
+	// records are looked up by uuid, quoted since it may start like 0b radix literal
+	res := selectHelper(tbl, "select name from users where id = '"+jane+"'").(*sqlSelectResponse)
+	validateSqlSelect(t, res, 1, 1)
+	if res.records[0].getValue(0) != "jane" {
+		t.Errorf("table select error: expected jane but got %s", res.records[0].getValue(0))
+	}
+	validateSqlSelect(t, selectHelper(tbl, "select * from users where id = 1"), 0, 2)
+	validateSqlUpdate(t, updateHelper(tbl, "update users set name = janet where id = '"+jane+"'"), 1)
+	validateSqlDelete(t, deleteHelper(tbl, "delete from users where id = '"+john+"'"), 1)
+	validateSqlSelect(t, selectHelper(tbl, "select * from users where id = '"+john+"'"), 0, 2)
+	validateSqlSelect(t, selectHelper(tbl, "select * from users"), 1, 2)
+	// uuid ids are kept by save and load
+	defer func(dir string) { config.SNAPSHOT_DIR = dir }(config.SNAPSHOT_DIR)
+	dir, err := ioutil.TempDir("", "pubsubsql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config.SNAPSHOT_DIR = dir
+	saveHelper(tbl, "save table users to 'users.snapshot'")
+	loaded := newTable("users")
+	validateOkResponse(t, createHelper(loaded, "create table users (name) with id = uuid"))
+	loadHelper(loaded, "load table users from 'users.snapshot'")
+	validateSqlSelect(t, selectHelper(loaded, "select * from users where id = '"+jane+"'"), 1, 2)
+	validateSqlInsertResponse(t, insertHelper(loaded, "insert into users (name) values (jack)"))
+	validateSqlSelect(t, selectHelper(loaded, "select * from users"), 2, 2)
+	// sequential ids are the default
+	tbl = newTable("stocks")
+	validateOkResponse(t, createHelper(tbl, "create table stocks (ticker) with id = sequential"))
+	insertHelper(tbl, "insert into stocks (ticker) values (IBM)")
+	validateRecordValue(t, tbl.getRecord(0), 0, "0")
+}
+
+// SAVE and LOAD
+
+func saveHelper(t *table, sqlSave string) response {
+	pc := newTokens()
+	lex(sqlSave, pc)
+	req := parse(pc).(*sqlSaveRequest)
+	return t.sqlSave(req)
+}
+
+func loadHelper(t *table, sqlLoad string) response {
+	pc := newTokens()
+	lex(sqlLoad, pc)
+	req := parse(pc).(*sqlLoadRequest)
+	return t.sqlLoad(req)
+}
+
+func TestTableSqlSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pubsubsql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(dir string) { config.SNAPSHOT_DIR = dir }(config.SNAPSHOT_DIR)
+	config.SNAPSHOT_DIR = dir
+	//
+	tbl := newTable("stocks")
+	validateOkResponse(t, createHelper(tbl, "create table stocks (ticker, bid float)"))
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	validateOkResponse(t, rangeHelper(tbl, "range stocks bid"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 12, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (JPM, 40, FIN) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector, note) values (MSFT, 30, TECH, 'a b') ")
+	deleteHelper(tbl, " delete from stocks where ticker = JPM ")
+	validateSqlDelete(t, saveHelper(tbl, " save table stocks to stocks.snap "), 2)
+	// temporary file is renamed to the snapshot file
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 || files[0].Name() != "stocks.snap" {
+		t.Errorf("table save error: unexpected files in snapshot directory %v", files)
+	}
+	validateErrorResponse(t, saveHelper(tbl, " save table stocks to '../stocks.snap' "))
+	// load into new table keeps ids, order, types and indexes
+	loaded := newTable("stocks")
+	validateSqlDelete(t, loadHelper(loaded, " load table stocks from stocks.snap "), 2)
+	validateSqlSelect(t, selectHelper(loaded, " select * from stocks where sector = TECH "), 2, 5)
+	validateSqlSelect(t, selectHelper(loaded, " select * from stocks where bid between 20 and 35 "), 1, 5)
+	res := selectHelper(loaded, " select id, note from stocks where ticker = MSFT ").(*sqlSelectResponse)
+	if res.records[0].getValue(0) != "2" || res.records[0].getValue(1) != "a b" {
+		t.Errorf("table load error: unexpected record %v", res.records[0].values)
+	}
+	validateErrorCode(t, insertHelper(loaded, " insert into stocks (ticker) values (IBM) "), errorCodeDuplicateKey)
+	validateErrorResponse(t, insertHelper(loaded, " insert into stocks (ticker, bid) values (ORCL, abc) "))
+	validateSqlInsertResponse(t, insertHelper(loaded, " insert into stocks (ticker) values (ORCL) "))
+	if loaded.getRecord(3) == nil {
+		t.Errorf("table load error: expected new record id 3")
+	}
+	// non empty table requires replace
+	validateErrorCode(t, loadHelper(loaded, " load table stocks from stocks.snap "), errorCodeTableNotEmpty)
+	validateSqlDelete(t, loadHelper(loaded, " load table stocks from stocks.snap replace "), 2)
+	validateSqlSelect(t, selectHelper(loaded, " select * from stocks "), 2, 5)
+	validateErrorResponse(t, loadHelper(loaded, " load table stocks from missing.snap replace "))
+	// record id far beyond number of records is rejected instead of allocated
+	corrupted := newTable("bonds")
+	insertHelper(corrupted, " insert into bonds (ticker) values (T10) ")
+	corrupted.getRecord(0).setValue(0, "1000000000")
+	validateSqlDelete(t, saveHelper(corrupted, " save table bonds to bonds.snap "), 1)
+	loadRes := loadHelper(newTable("bonds"), " load table bonds from bonds.snap ")
+	validateErrorResponse(t, loadRes)
+	if e := loadRes.(*errorResponse); !strings.Contains(e.msg, "corrupted table snapshot") {
+		t.Errorf("table load error: unexpected error %s", e.msg)
+	}
+}
+
+// DESCRIBE
+
+func describeHelper(t *table, sqlDescribe string) response {
+	pc := newTokens()
+	lex(sqlDescribe, pc)
+	req := parse(pc).(*sqlDescribeRequest)
+	return t.sqlDescribe(req)
+}
+
+func TestTableSqlDescribe(t *testing.T) {
+	tbl := newTable("stocks")
+	validateOkResponse(t, createHelper(tbl, "create table stocks (ticker, bid float)"))
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, rangeHelper(tbl, "range stocks bid"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, qty, active, note) values (IBM, 12, 100, true, a) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, qty, ratio, note) values (MSFT, 13, 200, 1.5, 7) ")
+	res := describeHelper(tbl, " describe stocks ")
+	x, ok := res.(*sqlDescribeResponse)
+	if !ok {
+		t.Errorf("table describe error: expected sqlDescribeResponse but got %T", res)
+		return
+	}
+	validateResponseJSON(t, res)
+	expected := [][]string{
+		{"id", "int", "true", "id"},
+		{"ticker", "string", "true", "key"},
+		{"bid", "float", "true", "range"},
+		{"qty", "int", "false", ""},
+		{"active", "bool", "false", ""},
+		{"note", "string", "false", ""},
+		{"ratio", "float", "false", ""},
+	}
+	if len(x.columns) != 4 || len(x.records) != len(expected) {
+		t.Errorf("table describe error: expected %d rows and 4 columns", len(expected))
+		return
+	}
+	for idx, values := range expected {
+		for ordinal, val := range values {
+			if x.records[idx].getValue(ordinal) != val {
+				t.Errorf("table describe error: expected %s but got %s for column %s", val, x.records[idx].getValue(ordinal), values[0])
+			}
+		}
+	}
+}
+
+// TRUNCATE
+
+func truncateHelper(t *table, sqlTruncate string) response {
+	pc := newTokens()
+	lex(sqlTruncate, pc)
+	req := parse(pc).(*sqlTruncateRequest)
+	return t.sqlTruncate(req)
+}
+
+func TestTableSqlTruncate(t *testing.T) {
+	senders := make([]*responseSender, 0)
+	var sender *responseSender
+	tbl := newTable("stocks")
+	// empty table
+	validateSqlDelete(t, truncateHelper(tbl, "truncate table stocks"), 0)
+	validateOkResponse(t, keyHelper(tbl, "key stocks ticker"))
+	validateOkResponse(t, tagHelper(tbl, "tag stocks sector"))
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 12, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (MSFT, 13, TECH) ")
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (JPM, 14, FIN) ")
+	// subscribe to table, tag and id
+	for _, sql := range []string{
+		"subscribe skip * from stocks",
+		"subscribe skip * from stocks where sector = TECH",
+		"subscribe skip * from stocks where id = 2"} {
+		res, sender := subscribeHelper(tbl, sql)
+		validateSqlSubscribeResponse(t, res)
+		senders = append(senders, sender)
+	}
+	validateSqlDelete(t, truncateHelper(tbl, "truncate table stocks"), 3)
+	validateActionDelete(t, senders)
+	for _, sender = range senders {
+		validateNoResponse(t, sender)
+	}
+	validateTableRecordsCount(t, tbl, 0)
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks"), 0, 4)
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks where sector = TECH"), 0, 4)
+	// columns, keys and table subscriptions are preserved
+	insertHelper(tbl, " insert into stocks (ticker, bid, sector) values (IBM, 12, TECH) ")
+	validateErrorResponse(t, insertHelper(tbl, " insert into stocks (ticker) values (IBM) "))
+	validateSqlSelect(t, selectHelper(tbl, "select * from stocks where ticker = IBM"), 1, 4)
+	validateActionInsert(t, senders[:2])
+}
+
 // UNSUBSCRIBE
 
 func unsubscribeHelper(t *table, sqlUnsubscribe string, connectionId uint64) response {
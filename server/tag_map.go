@@ -74,6 +74,16 @@ func (this *tagMap) containsTag(key string) bool {
 	return false
 }
 
+// removeTags removes all tags keeping tagItems with active subscriptions
+func (this *tagMap) removeTags() {
+	for key, item := range this.tags {
+		item.head = nil
+		if item.pubsub.count() == 0 {
+			delete(this.tags, key)
+		}
+	}
+}
+
 // removeTag removes tagItem only if there are no active subscriptions
 func (this *tagMap) removeTag(key string) {
 	item := this.tags[key]